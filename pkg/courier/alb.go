@@ -1,29 +1,54 @@
 package courier
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"golang.org/x/xerrors"
 )
 
+const (
+	// OnDestroyDelete deletes the listener rule on destroy
+	OnDestroyDelete = "delete"
+	// OnDestroyKeep leaves the listener rule as is on destroy
+	OnDestroyKeep = "keep"
+	// OnDestroyFallback gradually shifts all the traffic to the fallback target group before deleting the listener rule
+	OnDestroyFallback = "fallback"
+)
+
 type CourierALB struct {
-	Address          string
-	ListenerARN      string
-	Priority         int
-	ListenerRule     *ListenerRule
-	Region           string
-	Profile          string
-	Destinations     []Destination
-	StepWeight       int
-	StepInterval     time.Duration
-	Metrics          []Metric
+	Address      string
+	ListenerARN  string
+	Priority     int
+	ListenerRule *ListenerRule
+	Region       string
+	Profile      string
+	Destinations []Destination
+	StepWeight   int
+	StepInterval time.Duration
+	Metrics      []Metric
+	Preview      *ALBPreview
+
+	// Mode is either `rollout` or `experiment`. ExperimentDuration and OnComplete are for experiments
+	Mode               string
+	ExperimentDuration time.Duration
+	OnComplete         string
+
+	// OnDestroy is either `delete`, `keep`, or `fallback`. FallbackTargetGroupARN is for `fallback`
+	OnDestroy              string
+	FallbackTargetGroupARN string
+
+	// Telemetry records the rollout metrics labeled with the listener, the rule, and target groups, if set
+	Telemetry        *Telemetry
 	Session          *session.Session
 	AssumeRoleConfig *sdk.AssumeRoleConfig
 }
@@ -36,8 +61,12 @@ func (a *ALB) Delete(d *CourierALB) error {
 
 	sess.Config.Endpoint = &d.Address
 
-	svc := elbv2.New(sess)
+	svc := sdk.NewClients(sess).ELBV2()
+
+	return a.delete(context.Background(), svc, d)
+}
 
+func (a *ALB) delete(ctx context.Context, svc elbv2iface.ELBV2API, d *CourierALB) error {
 	listenerARN := d.ListenerARN
 
 	o, err := svc.DescribeRules(&elbv2.DescribeRulesInput{
@@ -57,18 +86,113 @@ func (a *ALB) Delete(d *CourierALB) error {
 		}
 	}
 
-	if rule != nil {
-		input := &elbv2.DeleteRuleInput{RuleArn: rule.RuleArn}
-		if res, err := svc.DeleteRule(input); err != nil {
-			var appendix string
+	if d.Preview != nil {
+		preview := &Preview{ELBV2: svc, ListenerARN: listenerARN, Priority: d.Preview.Priority}
+
+		if err := preview.Delete(); err != nil {
+			return err
+		}
+	}
+
+	if rule == nil {
+		return nil
+	}
+
+	switch d.OnDestroy {
+	case OnDestroyKeep:
+		log.Printf("Keeping rule %s on destroy", *rule.RuleArn)
+
+		return nil
+	case OnDestroyFallback:
+		if err := shiftToFallback(ctx, svc, d, rule); err != nil {
+			return xerrors.Errorf("shifting traffic to fallback target group %s: %w", d.FallbackTargetGroupARN, err)
+		}
+	}
+
+	input := &elbv2.DeleteRuleInput{RuleArn: rule.RuleArn}
+	if res, err := svc.DeleteRule(input); err != nil {
+		var appendix string
+
+		if res != nil {
+			appendix = fmt.Sprintf("\nOUTPUT:\n%v", *res)
+		}
+
+		log.Printf("Error: deleting rule: %s\nINPUT:\n%v%s", err.Error(), *input, appendix)
+
+		return fmt.Errorf("deleting rule: %w", err)
+	}
+
+	return nil
+}
+
+// shiftToFallback gradually shifts all the traffic of the rule to the fallback target group, with the same steps and analyses as rollouts.
+// The traffic is shifted from one target group at a time, starting from the one currently receiving the most traffic,
+// while the weights of the other target groups are kept.
+func shiftToFallback(ctx context.Context, svc elbv2iface.ELBV2API, d *CourierALB, rule *elbv2.Rule) error {
+	fallback := d.FallbackTargetGroupARN
 
-			if res != nil {
-				appendix = fmt.Sprintf("\nOUTPUT:\n%v", *res)
-			}
+	router := &ALBRuleRouter{ELBV2: svc, RuleARN: *rule.RuleArn}
 
-			log.Printf("Error: deleting rule: %s\nINPUT:\n%v%s", err.Error(), *input, appendix)
+	weights, err := router.GetWeights()
+	if err != nil {
+		return err
+	}
+
+	var froms []string
+
+	for arn, w := range weights {
+		if arn != fallback && w > 0 {
+			froms = append(froms, arn)
+		}
+	}
+
+	sort.Slice(froms, func(i, j int) bool {
+		if weights[froms[i]] != weights[froms[j]] {
+			return weights[froms[i]] > weights[froms[j]]
+		}
+
+		return froms[i] < froms[j]
+	})
+
+	if len(froms) == 0 {
+		log.Printf("Skipping traffic shift to fallback: rule %s forwards only to %s", *rule.RuleArn, fallback)
+
+		return nil
+	}
+
+	tgs, err := svc.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: aws.StringSlice([]string{fallback}),
+	})
+	if err != nil {
+		return xerrors.Errorf("calling elbv2.DescribeTargetGroups: %w", err)
+	}
+
+	if len(tgs.TargetGroups) != 1 {
+		return fmt.Errorf("fallback target group %s not found", fallback)
+	}
+
+	analyzers, err := MetricsToAnalyzers(d.Region, d.Profile, d.AssumeRoleConfig, d.Metrics)
+	if err != nil {
+		return xerrors.Errorf("initializing analyzers: %w", err)
+	}
+
+	for _, from := range froms {
+		r := &Rollout{
+			Router:       router,
+			From:         from,
+			To:           fallback,
+			StepWeight:   d.StepWeight,
+			StepInterval: d.StepInterval,
+			Analyzers:    analyzers,
+			AnalysisData: ListerStatusToTemplateData(ListenerStatus{DesiredTG: tgs.TargetGroups[0]}),
+			Telemetry: d.Telemetry.With(map[string]string{
+				TelemetryLabelListener: d.ListenerARN,
+				TelemetryLabelRule:     *rule.RuleArn,
+			}),
+		}
 
-			return fmt.Errorf("deleting rule: %w", err)
+		if err := r.Run(ctx); err != nil {
+			return xerrors.Errorf("shifting traffic from %s: %w", from, err)
 		}
 	}
 
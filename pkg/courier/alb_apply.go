@@ -7,7 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/google/go-cmp/cmp"
-	"golang.org/x/sync/errgroup"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"golang.org/x/xerrors"
 	"log"
 	"strconv"
@@ -21,7 +21,7 @@ func (a *ALB) Apply(d *CourierALB) error {
 
 	sess.Config.Endpoint = &d.Address
 
-	svc := elbv2.New(sess)
+	svc := sdk.NewClients(sess).ELBV2()
 
 	listenerARN := d.ListenerARN
 
@@ -66,6 +66,12 @@ func (a *ALB) Apply(d *CourierALB) error {
 		rule = o.Rules[0]
 
 		log.Printf("Created new rule: %+v", *rule)
+
+		// The new rule already routes the traffic according to the destinations' weights.
+		// Only experiments need to hold the split while running analyses.
+		if d.Mode != ModeExperiment {
+			return nil
+		}
 	} else {
 		log.Printf("Updating existing rule: %+v", *rule)
 
@@ -130,98 +136,175 @@ func (a *ALB) Apply(d *CourierALB) error {
 		// We can gradually shift traffic because Rule.Conditions are unchanged.
 
 		log.Printf("Updating rule %s with traffic shifting", *rule.RuleArn)
+	}
 
-		ctx := context.Background()
+	ctx := context.Background()
 
-		var nextTGARN, prevTGARN string
+	var nextTGARN, prevTGARN string
 
-		if destinations[0].Weight > destinations[1].Weight {
-			nextTGARN = destinations[0].TargetGroupARN
-			prevTGARN = destinations[1].TargetGroupARN
-		} else {
-			prevTGARN = destinations[0].TargetGroupARN
-			nextTGARN = destinations[1].TargetGroupARN
-		}
+	// The percentage of the traffic routed to the variant during the experiment
+	var experimentWeight int
 
-		tgs, err := svc.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
-			TargetGroupArns: []*string{
-				aws.String(nextTGARN),
-				aws.String(prevTGARN),
-			},
-		})
+	if d.Mode == ModeExperiment {
+		baseline, variant, p, err := experimentSplit([]int{destinations[0].Weight, destinations[1].Weight})
 		if err != nil {
-			log.Printf("elbv2.DescribeTargetGroups failed. TargetGroupArns=%v,%v Error=%v", nextTGARN, prevTGARN, err)
-
-			return xerrors.Errorf("calling elbv2.DescribeTargetGroups: %w", err)
+			return err
 		}
 
-		var desired, current *elbv2.TargetGroup
+		prevTGARN = destinations[baseline].TargetGroupARN
+		nextTGARN = destinations[variant].TargetGroupARN
+		experimentWeight = p
+	} else if destinations[0].Weight > destinations[1].Weight {
+		nextTGARN = destinations[0].TargetGroupARN
+		prevTGARN = destinations[1].TargetGroupARN
+	} else {
+		prevTGARN = destinations[0].TargetGroupARN
+		nextTGARN = destinations[1].TargetGroupARN
+	}
 
-		for i := range tgs.TargetGroups {
-			tg := *tgs.TargetGroups[i]
-			switch *tg.TargetGroupArn {
-			case nextTGARN:
-				desired = &tg
-			case prevTGARN:
-				current = &tg
-			}
-		}
+	tgs, err := svc.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []*string{
+			aws.String(nextTGARN),
+			aws.String(prevTGARN),
+		},
+	})
+	if err != nil {
+		log.Printf("elbv2.DescribeTargetGroups failed. TargetGroupArns=%v,%v Error=%v", nextTGARN, prevTGARN, err)
 
-		if desired == nil {
-			return xerrors.Errorf("next=desired target group %s not found", nextTGARN)
-		}
+		return xerrors.Errorf("calling elbv2.DescribeTargetGroups: %w", err)
+	}
 
-		if current == nil {
-			return xerrors.Errorf("prev=current target group %s not found", prevTGARN)
+	var desired, current *elbv2.TargetGroup
+
+	for i := range tgs.TargetGroups {
+		tg := *tgs.TargetGroups[i]
+		switch *tg.TargetGroupArn {
+		case nextTGARN:
+			desired = &tg
+		case prevTGARN:
+			current = &tg
 		}
+	}
 
-		log.Printf("Starting to update rule %s, so that the traffic is gradually migrated from %s to %s", *rule.RuleArn, *current.TargetGroupArn, *desired.TargetGroupArn)
+	if desired == nil {
+		return xerrors.Errorf("next=desired target group %s not found", nextTGARN)
+	}
 
-		describeListenersResult, err := svc.DescribeListeners(&elbv2.DescribeListenersInput{
-			ListenerArns: aws.StringSlice([]string{lr.ListenerARN}),
-		})
-		if err != nil {
-			log.Printf("elbv2.DescribeListeners failed: ListenerArns=%v Error=%v", lr.ListenerARN, err)
+	if current == nil {
+		return xerrors.Errorf("prev=current target group %s not found", prevTGARN)
+	}
 
-			return xerrors.Errorf("calling elbv2.DescribeListeners: %w", err)
-		}
+	log.Printf("Starting to update rule %s, so that the traffic is gradually migrated from %s to %s", *rule.RuleArn, *current.TargetGroupArn, *desired.TargetGroupArn)
+
+	describeListenersResult, err := svc.DescribeListeners(&elbv2.DescribeListenersInput{
+		ListenerArns: aws.StringSlice([]string{lr.ListenerARN}),
+	})
+	if err != nil {
+		log.Printf("elbv2.DescribeListeners failed: ListenerArns=%v Error=%v", lr.ListenerARN, err)
+
+		return xerrors.Errorf("calling elbv2.DescribeListeners: %w", err)
+	}
 
-		l := ListenerStatus{
-			Listener:       describeListenersResult.Listeners[0],
-			Rule:           rule,
-			ALBAttachments: nil,
-			DesiredTG:      desired,
-			CurrentTG:      current,
-			DeletedTGs:     nil,
-			Metrics:        metrics,
+	l := ListenerStatus{
+		Listener:       describeListenersResult.Listeners[0],
+		Rule:           rule,
+		ALBAttachments: nil,
+		DesiredTG:      desired,
+		CurrentTG:      current,
+		DeletedTGs:     nil,
+		Metrics:        metrics,
+	}
+
+	data := ListerStatusToTemplateData(l)
+
+	analyzers, err := MetricsToAnalyzers(d.Region, d.Profile, d.AssumeRoleConfig, l.Metrics)
+	if err != nil {
+		return xerrors.Errorf("initializing analyzers: %w", err)
+	}
+
+	router := &ALBRuleRouter{ELBV2: svc, RuleARN: *rule.RuleArn}
+
+	var preview *Preview
+
+	if p := d.Preview; p != nil {
+		weights, err := router.GetWeights()
+		if err != nil {
+			return xerrors.Errorf("getting current weights of rule %s: %w", *rule.RuleArn, err)
 		}
 
-		ctx, cancel := context.WithCancel(ctx)
-		e, errctx := errgroup.WithContext(ctx)
+		// Preview only when the desired target group is yet to receive all the traffic
+		if weights[nextTGARN] < 100 {
+			previewAnalyzers, err := MetricsToAnalyzers(d.Region, d.Profile, d.AssumeRoleConfig, p.Metrics)
+			if err != nil {
+				return xerrors.Errorf("initializing preview analyzers: %w", err)
+			}
 
-		e.Go(func() error {
-			defer cancel()
-			return DoGradualTrafficShift(errctx, svc, l, 1, CanaryOpts{
-				CanaryAdvancementInterval: stepInterval,
-				CanaryAdvancementStep:     stepWeight,
-				Region:                    "",
-				ClusterName:               "",
-			})
-		})
+			preview = &Preview{
+				ELBV2:           svc,
+				ListenerARN:     listenerARN,
+				Priority:        p.Priority,
+				Conditions:      getPreviewRuleConditions(lr, p),
+				TargetGroupARN:  nextTGARN,
+				RequireApproval: p.RequireApproval,
+				ApprovalTimeout: p.ApprovalTimeout,
+				Duration:        p.Duration,
+				Analyzers:       previewAnalyzers,
+				AnalysisData:    data,
+			}
 
-		data := ListerStatusToTemplateData(l)
+			log.Printf("Previewing %s with the rule at priority %d before shifting traffic", nextTGARN, p.Priority)
 
-		region, profile := d.Region, d.Profile
+			if err := preview.Run(ctx); err != nil {
+				if err := preview.Delete(); err != nil {
+					log.Printf("Failed to delete preview rule: %v", err)
+				}
 
-		e.Go(func() error {
-			return Analyze(errctx, region, profile, d.AssumeRoleConfig, l.Metrics, data)
-		})
+				return xerrors.Errorf("previewing target group %s: %w", nextTGARN, err)
+			}
+		}
+	}
+
+	r := &Rollout{
+		Router:       router,
+		From:         prevTGARN,
+		To:           nextTGARN,
+		Start:        1,
+		StepWeight:   stepWeight,
+		StepInterval: stepInterval,
+		Analyzers:    analyzers,
+		AnalysisData: data,
+		Telemetry: d.Telemetry.With(map[string]string{
+			TelemetryLabelListener: listenerARN,
+			TelemetryLabelRule:     *rule.RuleArn,
+		}),
+	}
+
+	if d.Mode == ModeExperiment {
+		e := &Experiment{
+			Rollout:    *r,
+			Weight:     experimentWeight,
+			Duration:   d.ExperimentDuration,
+			OnComplete: d.OnComplete,
+		}
 
-		if err := e.Wait(); err != nil {
-			return xerrors.Errorf("shifting traffic over ALB: %w", err)
+		err = e.Run(ctx)
+	} else {
+		err = r.Run(ctx)
+	}
+
+	if preview != nil {
+		if derr := preview.Delete(); derr != nil {
+			if err == nil {
+				return derr
+			}
+
+			log.Printf("Failed to delete preview rule: %v", derr)
 		}
 	}
 
+	if err != nil {
+		return xerrors.Errorf("shifting traffic over ALB: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,150 @@
+package courier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ruleELBV2 is the fake ELBv2 API with a single listener rule
+type ruleELBV2 struct {
+	elbv2iface.ELBV2API
+
+	rule    *elbv2.Rule
+	weights []map[string]int
+	deleted []string
+}
+
+func (m *ruleELBV2) DescribeRules(i *elbv2.DescribeRulesInput) (*elbv2.DescribeRulesOutput, error) {
+	return &elbv2.DescribeRulesOutput{Rules: []*elbv2.Rule{m.rule}}, nil
+}
+
+func (m *ruleELBV2) ModifyRule(i *elbv2.ModifyRuleInput) (*elbv2.ModifyRuleOutput, error) {
+	m.rule.Actions = i.Actions
+
+	ws := map[string]int{}
+
+	for _, tg := range i.Actions[0].ForwardConfig.TargetGroups {
+		ws[*tg.TargetGroupArn] = int(*tg.Weight)
+	}
+
+	m.weights = append(m.weights, ws)
+
+	return &elbv2.ModifyRuleOutput{}, nil
+}
+
+func (m *ruleELBV2) DeleteRule(i *elbv2.DeleteRuleInput) (*elbv2.DeleteRuleOutput, error) {
+	m.deleted = append(m.deleted, *i.RuleArn)
+
+	return &elbv2.DeleteRuleOutput{}, nil
+}
+
+func (m *ruleELBV2) DescribeTargetGroups(i *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	var tgs []*elbv2.TargetGroup
+
+	for _, arn := range i.TargetGroupArns {
+		tgs = append(tgs, &elbv2.TargetGroup{TargetGroupArn: arn})
+	}
+
+	return &elbv2.DescribeTargetGroupsOutput{TargetGroups: tgs}, nil
+}
+
+func TestALB_Delete(t *testing.T) {
+	newSvc := func() *ruleELBV2 {
+		return &ruleELBV2{
+			rule: &elbv2.Rule{
+				RuleArn:  aws.String("rule"),
+				Priority: aws.String("10"),
+				Actions: getRuleActions([]Destination{
+					{TargetGroupARN: "blue", Weight: 0},
+					{TargetGroupARN: "green", Weight: 100},
+				}),
+			},
+		}
+	}
+
+	newCourierALB := func(onDestroy string) *CourierALB {
+		return &CourierALB{
+			ListenerARN:            "listener",
+			Priority:               10,
+			StepWeight:             40,
+			StepInterval:           time.Millisecond,
+			OnDestroy:              onDestroy,
+			FallbackTargetGroupARN: "blue",
+		}
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		svc := newSvc()
+
+		require.NoError(t, (&ALB{}).delete(context.Background(), svc, newCourierALB(OnDestroyDelete)))
+
+		assert.Empty(t, svc.weights)
+		assert.Equal(t, []string{"rule"}, svc.deleted)
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		svc := newSvc()
+
+		require.NoError(t, (&ALB{}).delete(context.Background(), svc, newCourierALB(OnDestroyKeep)))
+
+		assert.Empty(t, svc.weights)
+		assert.Empty(t, svc.deleted)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		svc := newSvc()
+
+		require.NoError(t, (&ALB{}).delete(context.Background(), svc, newCourierALB(OnDestroyFallback)))
+
+		assert.Equal(t, []map[string]int{
+			{"blue": 40, "green": 60},
+			{"blue": 80, "green": 20},
+			{"blue": 100, "green": 0},
+		}, svc.weights)
+		assert.Equal(t, []string{"rule"}, svc.deleted)
+	})
+
+	t.Run("fallback from multiple target groups", func(t *testing.T) {
+		svc := newSvc()
+		svc.rule.Actions = getRuleActions([]Destination{
+			{TargetGroupARN: "blue", Weight: 0},
+			{TargetGroupARN: "green", Weight: 70},
+			{TargetGroupARN: "canary", Weight: 30},
+		})
+
+		require.NoError(t, (&ALB{}).delete(context.Background(), svc, newCourierALB(OnDestroyFallback)))
+
+		assert.Equal(t, []map[string]int{
+			{"blue": 40, "green": 30, "canary": 30},
+			{"blue": 70, "green": 0, "canary": 30},
+			{"blue": 100, "green": 0, "canary": 0},
+		}, svc.weights)
+		assert.Equal(t, []string{"rule"}, svc.deleted)
+	})
+
+	t.Run("fallback rollback on analysis failure", func(t *testing.T) {
+		svc := newSvc()
+
+		max := 0.1
+
+		d := newCourierALB(OnDestroyFallback)
+		d.Metrics = []Metric{{Provider: "http_probe", URL: "http://127.0.0.1:1", Requests: 1, Timeout: time.Second, Query: "success_rate", Min: &max}}
+
+		err := (&ALB{}).delete(context.Background(), svc, d)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fallback target group blue")
+
+		assert.Equal(t, []map[string]int{
+			{"blue": 40, "green": 60},
+			{"blue": 0, "green": 100},
+		}, svc.weights)
+		assert.Empty(t, svc.deleted)
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier/metrics"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"golang.org/x/xerrors"
@@ -17,45 +16,24 @@ func MetricsToAnalyzers(region, profile string, assumeRoleConfig *sdk.AssumeRole
 	var analyzers []*Analyzer
 
 	for _, m := range ms {
-		var provider MetricProvider
-
-		var err error
-
-		switch m.Provider {
-		case "cloudwatch":
-			if m.AWSRegion != "" {
-				region = m.AWSRegion
+		if m.SLO != nil {
+			a, err := newSLOAnalyzer(region, profile, assumeRoleConfig, m)
+			if err != nil {
+				return nil, err
 			}
 
-			if m.AWSProfile != "" {
-				profile = m.AWSProfile
-			}
+			analyzers = append(analyzers, &Analyzer{Name: m.Name, SLO: a})
 
-			s := sdk.AWSSession(region, profile, assumeRoleConfig)
-
-			s.Config.Endpoint = aws.String(m.Address)
-			c := cloudwatch.New(s)
-			provider = metrics.NewCloudWatchProvider(c, metrics.ProviderOpts{
-				Address:  m.Address,
-				Interval: 1 * time.Minute,
-			})
-		case "datadog":
-			provider, err = metrics.NewDatadogProvider(metrics.ProviderOpts{
-				Address:  m.Address,
-				Interval: 1 * time.Minute,
-			}, metrics.DatadogOpts{
-				APIKey:         os.Getenv("DATADOG_API_KEY"),
-				ApplicationKey: os.Getenv("DATADOG_APPLICATION_KEY"),
-			})
-		default:
-			return nil, fmt.Errorf("creating metrics provider: unknown and unsupported provider %q specified", m.Provider)
+			continue
 		}
 
+		provider, err := newMetricProvider(region, profile, assumeRoleConfig, m, 1*time.Minute)
 		if err != nil {
-			return nil, fmt.Errorf("creating metrics provider %q: %v", m.Provider, err)
+			return nil, err
 		}
 
 		analyzers = append(analyzers, &Analyzer{
+			Name:           m.Name,
 			MetricProvider: provider,
 			Query:          m.Query,
 			Min:            m.Min,
@@ -66,18 +44,166 @@ func MetricsToAnalyzers(region, profile string, assumeRoleConfig *sdk.AssumeRole
 	return analyzers, nil
 }
 
+func newSLOAnalyzer(region, profile string, assumeRoleConfig *sdk.AssumeRoleConfig, m Metric) (*SLOAnalyzer, error) {
+	if err := m.SLO.Validate(); err != nil {
+		return nil, fmt.Errorf("validating slo: %w", err)
+	}
+
+	providers := map[time.Duration]MetricProvider{}
+
+	for _, w := range m.SLO.WindowLengths() {
+		windowed := m
+		windowed.Window = w
+
+		// The Datadog provider queries over the 10 metric intervals ending now.
+		// Divide the window so that each query covers the whole window.
+		// The CloudWatch provider sums the datapoints over windowed.Window instead.
+		p, err := newMetricProvider(region, profile, assumeRoleConfig, windowed, w/10)
+		if err != nil {
+			return nil, err
+		}
+
+		providers[w] = p
+	}
+
+	return &SLOAnalyzer{
+		SLO:       *m.SLO,
+		Providers: providers,
+	}, nil
+}
+
+func newMetricProvider(region, profile string, assumeRoleConfig *sdk.AssumeRoleConfig, m Metric, interval time.Duration) (MetricProvider, error) {
+	var provider MetricProvider
+
+	var err error
+
+	switch m.Provider {
+	case "cloudwatch":
+		if m.AWSRegion != "" {
+			region = m.AWSRegion
+		}
+
+		if m.AWSProfile != "" {
+			profile = m.AWSProfile
+		}
+
+		s := sdk.AWSSession(region, profile, assumeRoleConfig)
+
+		s.Config.Endpoint = aws.String(m.Address)
+		c := sdk.NewClients(s).CloudWatch()
+		opts := metrics.ProviderOpts{
+			Address:  m.Address,
+			Interval: interval,
+		}
+
+		// SLO queries count events over the whole window
+		if m.SLO != nil {
+			opts.Window = m.Window
+		}
+
+		provider = metrics.NewCloudWatchProvider(c, opts)
+	case "cloudwatch_logs":
+		if m.AWSRegion != "" {
+			region = m.AWSRegion
+		}
+
+		if m.AWSProfile != "" {
+			profile = m.AWSProfile
+		}
+
+		s := sdk.AWSSession(region, profile, assumeRoleConfig)
+
+		s.Config.Endpoint = aws.String(m.Address)
+		c := sdk.NewClients(s).CloudWatchLogs()
+		provider, err = metrics.NewCloudWatchLogsProvider(c, metrics.CloudWatchLogsOpts{
+			LogGroupNames: m.LogGroupNames,
+			Field:         m.Field,
+			Window:        m.Window,
+		})
+	case "http_probe":
+		provider, err = metrics.NewHTTPProbeProvider(metrics.HTTPProbeOpts{
+			URL:                m.URL,
+			Method:             m.Method,
+			Headers:            m.Headers,
+			Requests:           m.Requests,
+			Timeout:            m.Timeout,
+			SuccessStatusCodes: m.SuccessStatusCodes,
+		})
+	case "datadog":
+		provider, err = metrics.NewDatadogProvider(metrics.ProviderOpts{
+			Address:  m.Address,
+			Interval: interval,
+		}, metrics.DatadogOpts{
+			APIKey:         os.Getenv("DATADOG_API_KEY"),
+			ApplicationKey: os.Getenv("DATADOG_APPLICATION_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("creating metrics provider: unknown and unsupported provider %q specified", m.Provider)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("creating metrics provider %q: %v", m.Provider, err)
+	}
+
+	return provider, nil
+}
+
 type MetricProvider interface {
 	Execute(string) (float64, error)
 }
 
 type Analyzer struct {
 	MetricProvider
+	Name  string
 	Query string
 	Min   *float64
 	Max   *float64
+
+	// SLO makes the analyzer check the burn rates of the error budget, instead of the threshold on the Query.
+	SLO *SLOAnalyzer
 }
 
 func (a *Analyzer) Analyze(data interface{}) error {
+	return a.analyze(data, nil)
+}
+
+// analyze runs the analysis while recording the analyzed values and failures to the telemetry.
+func (a *Analyzer) analyze(data interface{}, t *Telemetry) error {
+	t = t.With(map[string]string{TelemetryLabelAnalysis: a.Name})
+
+	err := a.check(data, t)
+	if err != nil {
+		t.Inc(TelemetryMetricAnalysisFailures, nil)
+	}
+
+	return err
+}
+
+func (a *Analyzer) check(data interface{}, t *Telemetry) error {
+	if a.SLO != nil {
+		return a.SLO.analyze(data, t)
+	}
+
+	v, err := executeQuery(a.MetricProvider, "query", a.Query, data, nil)
+	if err != nil {
+		return err
+	}
+
+	t.Gauge(TelemetryMetricAnalysisValue, v, nil)
+
+	if a.Min != nil && *a.Min > v {
+		return fmt.Errorf("checking value against threshold: %v is below %v", v, *a.Min)
+	}
+
+	if a.Max != nil && *a.Max < v {
+		return fmt.Errorf("checking value against threshold: %v is beyond %v", v, *a.Max)
+	}
+
+	return nil
+}
+
+// executeQuery renders the query template with the data and executes it on the provider, retrying on errors.
+func executeQuery(p MetricProvider, name, queryTemplate string, data interface{}, funcs template.FuncMap) (float64, error) {
 	maxRetries := 3
 
 	var v float64
@@ -87,38 +213,30 @@ func (a *Analyzer) Analyze(data interface{}) error {
 	var query string
 
 	{
-		tmpl, err := template.New("query").Parse(a.Query)
+		tmpl, err := template.New(name).Funcs(funcs).Parse(queryTemplate)
 		if err != nil {
-			return fmt.Errorf("parsing query template: %w", err)
+			return 0, fmt.Errorf("parsing query template: %w", err)
 		}
 
 		var buf bytes.Buffer
 
 		if err := tmpl.Execute(&buf, data); err != nil {
-			return fmt.Errorf("executing query template: %w", err)
+			return 0, fmt.Errorf("executing query template: %w", err)
 		}
 
 		query = buf.String()
 	}
 
 	for i := 0; i < maxRetries; i++ {
-		v, err = a.MetricProvider.Execute(query)
+		v, err = p.Execute(query)
 		if err == nil {
 			break
 		}
 	}
 
 	if err != nil {
-		return xerrors.Errorf("executing query on metric provider %d time(s): %w", maxRetries, err)
+		return 0, xerrors.Errorf("executing query on metric provider %d time(s): %w", maxRetries, err)
 	}
 
-	if a.Min != nil && *a.Min > v {
-		return fmt.Errorf("checking value against threshold: %v is below %v", v, *a.Min)
-	}
-
-	if a.Max != nil && *a.Max < v {
-		return fmt.Errorf("checking value against threshold: %v is beyond %v", v, *a.Max)
-	}
-
-	return nil
+	return v, nil
 }
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
-	"golang.org/x/sync/errgroup"
 	"golang.org/x/xerrors"
 	"log"
 	"time"
@@ -22,7 +22,7 @@ func CreateOrUpdateCourierRoute53Record(d api.Getter, mSchema *MetricSchema) err
 		sess.Config.Endpoint = aws.String(v.(string))
 	}
 
-	svc := route53.New(sess)
+	svc := sdk.NewClients(sess).Route53()
 
 	zoneID := d.Get("zone_id").(string)
 
@@ -74,8 +74,26 @@ func CreateOrUpdateCourierRoute53Record(d api.Getter, mSchema *MetricSchema) err
 		stepWeight = v.(int)
 	}
 
+	mode, experimentDuration, onComplete, err := ReadMode(d, "mode", "duration", "on_complete")
+	if err != nil {
+		return err
+	}
+
+	telemetry, err := ReadTelemetry(d, "telemetry")
+	if err != nil {
+		return err
+	}
+
 	assumeRoleConfig := tfsdk.GetAssumeRoleConfig(d)
 
+	analyzers, err := MetricsToAnalyzers(region, profile, assumeRoleConfig, metrics)
+	if err != nil {
+		return xerrors.Errorf("initializing analyzers: %w", err)
+	}
+
+	type templateData struct {
+	}
+
 	r := &Route53RecordSetRouter{
 		Service:                   svc,
 		RecordName:                recordName,
@@ -83,22 +101,13 @@ func CreateOrUpdateCourierRoute53Record(d api.Getter, mSchema *MetricSchema) err
 		Destinations:              destinations,
 		CanaryAdvancementInterval: stepInterval,
 		CanaryAdvancementStep:     stepWeight,
+		Analyzers:                 analyzers,
+		AnalysisData:              &templateData{},
+		Mode:                      mode,
+		ExperimentDuration:        experimentDuration,
+		OnComplete:                onComplete,
+		Telemetry:                 telemetry,
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
-	e, errctx := errgroup.WithContext(ctx)
-
-	e.Go(func() error {
-		defer cancel()
-		return r.TrafficShift(errctx)
-	})
-
-	type templateData struct {
-	}
-
-	e.Go(func() error {
-		return Analyze(errctx, region, profile, assumeRoleConfig, metrics, &templateData{})
-	})
-
-	return e.Wait()
+	return r.TrafficShift(ctx)
 }
@@ -0,0 +1,148 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"golang.org/x/xerrors"
+)
+
+const (
+	ModeRollout    = "rollout"
+	ModeExperiment = "experiment"
+
+	// OnCompletePromote shifts all the traffic to the variant after the experiment, stepping like a rollout
+	OnCompletePromote = "promote"
+	// OnCompleteRevert routes all the traffic back to the baseline after the experiment
+	OnCompleteRevert = "revert"
+	// OnCompleteLeave keeps the split after the experiment
+	OnCompleteLeave = "leave"
+)
+
+// Experiment holds the fixed split between the baseline `From` and the variant `To` for Duration, while running analyzers.
+//
+// When an analysis fails or the context is canceled during the experiment, all the traffic is routed back to the baseline.
+// Otherwise the split is promoted, reverted, or left as is, according to OnComplete.
+type Experiment struct {
+	// Rollout is used to set the split, hold it, and promote the variant.
+	// Its Start is ignored.
+	Rollout
+
+	// Weight is the weight of the variant during the experiment
+	Weight   int
+	Duration time.Duration
+
+	OnComplete string
+}
+
+func (e *Experiment) Run(ctx context.Context) error {
+	if e.Weight < 0 || e.Weight > 100 {
+		return fmt.Errorf("invalid weight for experiment: got %d, must be within 0 and 100", e.Weight)
+	}
+
+	switch e.OnComplete {
+	case OnCompletePromote, OnCompleteRevert, OnCompleteLeave:
+	default:
+		return fmt.Errorf("invalid on_complete %q: must be either %q, %q, or %q", e.OnComplete, OnCompletePromote, OnCompleteRevert, OnCompleteLeave)
+	}
+
+	if _, err := e.readWeights(); err != nil {
+		return err
+	}
+
+	if e.Weight > e.total {
+		return fmt.Errorf("invalid weight for experiment: got %d, must not exceed %d, as the rest is routed to other destinations %v", e.Weight, e.total, e.others)
+	}
+
+	log.Printf("Starting experiment: %s: Weight %d, %s: Weight %d, for %s.", e.To, e.Weight, e.From, e.total-e.Weight, e.Duration)
+
+	if err := e.setWeight(e.Weight); err != nil {
+		return err
+	}
+
+	if err := e.pause(ctx, e.clock(), e.Duration); err != nil {
+		log.Printf("Reverting experiment between %s and %s: %v", e.From, e.To, err)
+
+		if revertErr := e.setWeight(0); revertErr != nil {
+			return xerrors.Errorf("reverting experiment due to %v: %w", err, revertErr)
+		}
+
+		return err
+	}
+
+	log.Printf("Experiment between %s and %s completed. Running on_complete %q", e.From, e.To, e.OnComplete)
+
+	switch e.OnComplete {
+	case OnCompletePromote:
+		r := e.Rollout
+		r.Start = 0
+
+		return r.Run(ctx)
+	case OnCompleteRevert:
+		return e.setWeight(0)
+	}
+
+	return nil
+}
+
+// ReadMode reads the mode, the duration of the experiment, and the on_complete action from the keys.
+func ReadMode(d api.Getter, modeKey, durationKey, onCompleteKey string) (string, time.Duration, string, error) {
+	mode := ModeRollout
+	if v, ok := d.Get(modeKey).(string); ok && v != "" {
+		mode = v
+	}
+
+	if mode != ModeRollout && mode != ModeExperiment {
+		return "", 0, "", fmt.Errorf("invalid mode %q: must be either %q or %q", mode, ModeRollout, ModeExperiment)
+	}
+
+	if mode != ModeExperiment {
+		return mode, 0, "", nil
+	}
+
+	var duration time.Duration
+
+	if v, ok := d.Get(durationKey).(string); ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return "", 0, "", fmt.Errorf("error parsing %s %v: %w", durationKey, v, err)
+		}
+
+		duration = d
+	}
+
+	if duration <= 0 {
+		return "", 0, "", fmt.Errorf("%s is required for mode %q", durationKey, ModeExperiment)
+	}
+
+	onComplete := OnCompleteLeave
+	if v, ok := d.Get(onCompleteKey).(string); ok && v != "" {
+		onComplete = v
+	}
+
+	return mode, duration, onComplete, nil
+}
+
+// experimentSplit returns the indices of the baseline and the variant among two destinations with the weights,
+// and the percentage of the traffic routed to the variant.
+// The variant is the destination with the lower weight, or the second one when the weights are equal.
+func experimentSplit(weights []int) (int, int, int, error) {
+	if len(weights) != 2 {
+		return 0, 0, 0, fmt.Errorf("unsupported number of destinations for experiment: want 2, got %d", len(weights))
+	}
+
+	total := weights[0] + weights[1]
+	if total <= 0 {
+		return 0, 0, 0, fmt.Errorf("sum of destinations' weights must be positive: %v", weights)
+	}
+
+	baseline, variant := 0, 1
+	if weights[0] < weights[1] {
+		baseline, variant = 1, 0
+	}
+
+	return baseline, variant, weights[variant] * 100 / total, nil
+}
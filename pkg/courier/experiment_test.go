@@ -0,0 +1,114 @@
+package courier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperiment_Run(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newExperiment := func(router TrafficRouter, clock Clock, onComplete string) *Experiment {
+		return &Experiment{
+			Rollout: Rollout{
+				Router:       router,
+				From:         "baseline",
+				To:           "variant",
+				StepWeight:   40,
+				StepInterval: time.Minute,
+				Clock:        clock,
+			},
+			Weight:     20,
+			Duration:   time.Hour,
+			OnComplete: onComplete,
+		}
+	}
+
+	t.Run("leave", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"baseline": 100})
+
+		require.NoError(t, newExperiment(router, clock, OnCompleteLeave).Run(context.Background()))
+
+		assert.Equal(t, []int{20}, weightsOf(router.History(), "variant"))
+		assert.Equal(t, []int{80}, weightsOf(router.History(), "baseline"))
+		assert.Equal(t, t0.Add(time.Hour), clock.Now())
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"baseline": 100})
+
+		require.NoError(t, newExperiment(router, clock, OnCompletePromote).Run(context.Background()))
+
+		h := router.History()
+		assert.Equal(t, []int{20, 60, 100}, weightsOf(h, "variant"))
+		assert.Equal(t, t0.Add(time.Hour), h[1].At)
+		assert.Equal(t, t0.Add(time.Hour+time.Minute), h[2].At)
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"baseline": 100})
+
+		require.NoError(t, newExperiment(router, clock, OnCompleteRevert).Run(context.Background()))
+
+		assert.Equal(t, []int{20, 0}, weightsOf(router.History(), "variant"))
+	})
+
+	t.Run("revert on analysis failure", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"baseline": 100})
+
+		max := 0.1
+
+		e := newExperiment(router, clock, OnCompletePromote)
+		e.AnalysisInterval = 10 * time.Minute
+		e.Analyzers = []*Analyzer{{MetricProvider: metricProviderFunc(func(string) (float64, error) {
+			if clock.Now().Sub(t0) >= 30*time.Minute {
+				return 0.5, nil
+			}
+
+			return 0, nil
+		}), Max: &max}}
+
+		err := e.Run(context.Background())
+		require.Error(t, err)
+
+		h := router.History()
+		assert.Equal(t, []int{20, 0}, weightsOf(h, "variant"))
+		assert.Equal(t, t0.Add(30*time.Minute), h[1].At)
+	})
+
+	t.Run("invalid on_complete", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"baseline": 100})
+
+		require.Error(t, newExperiment(router, clock, "rollback").Run(context.Background()))
+		assert.Empty(t, router.History())
+	})
+}
+
+func TestExperimentSplit(t *testing.T) {
+	baseline, variant, p, err := experimentSplit([]int{80, 20})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 20}, []int{baseline, variant, p})
+
+	baseline, variant, p, err = experimentSplit([]int{1, 3})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0, 25}, []int{baseline, variant, p})
+
+	baseline, variant, p, err = experimentSplit([]int{50, 50})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 50}, []int{baseline, variant, p})
+
+	_, _, _, err = experimentSplit([]int{0, 0})
+	require.Error(t, err)
+
+	_, _, _, err = experimentSplit([]int{100})
+	require.Error(t, err)
+}
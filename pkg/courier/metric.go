@@ -3,6 +3,8 @@ package courier
 import "time"
 
 type Metric struct {
+	// Name identifies the analysis in the telemetry
+	Name       string
 	Provider   string
 	Address    string
 	Query      string
@@ -11,4 +13,20 @@ type Metric struct {
 	Interval   time.Duration
 	AWSRegion  string
 	AWSProfile string
+
+	// LogGroupNames, Field, and Window are for the `cloudwatch_logs` provider
+	LogGroupNames []string
+	Field         string
+	Window        time.Duration
+
+	// URL, Method, Headers, Requests, Timeout, and SuccessStatusCodes are for the `http_probe` provider
+	URL                string
+	Method             string
+	Headers            map[string]string
+	Requests           int
+	Timeout            time.Duration
+	SuccessStatusCodes []int
+
+	// SLO turns the metric into the burn-rate analysis of the SLO, whose queries are run on the Provider
+	SLO *SLO
 }
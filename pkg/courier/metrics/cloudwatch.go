@@ -17,6 +17,9 @@ const (
 type CloudWatch struct {
 	client     cloudWatchClient
 	startDelta time.Duration
+
+	// window is set to sum the datapoints over the window, instead of returning the latest one
+	window time.Duration
 }
 
 // for the testing purpose
@@ -27,12 +30,17 @@ type cloudWatchClient interface {
 type ProviderOpts struct {
 	Address  string
 	Interval time.Duration
+
+	// Window is the length of the window to sum the datapoints over, like the number of events within a window of the SLO.
+	// The CloudWatch provider queries over the window and returns the sum of all the datapoints, instead of the latest one, when set.
+	Window time.Duration
 }
 
 func NewCloudWatchProvider(client cloudwatchiface.CloudWatchAPI, provider ProviderOpts) *CloudWatch {
 	return &CloudWatch{
 		client:     client,
 		startDelta: cloudWatchStartDeltaMultiplierOnMetricInterval * provider.Interval,
+		window:     provider.Window,
 	}
 }
 
@@ -43,6 +51,11 @@ func (p *CloudWatch) Execute(query string) (float64, error) {
 	}
 
 	end := time.Now()
+
+	if p.window > 0 {
+		return p.sum(cq, end.Add(-p.window), end)
+	}
+
 	start := end.Add(-p.startDelta)
 	res, err := p.client.GetMetricData(&cloudwatch.GetMetricDataInput{
 		EndTime:           aws.Time(end),
@@ -67,3 +80,43 @@ func (p *CloudWatch) Execute(query string) (float64, error) {
 
 	return aws.Float64Value(vs[0]), nil
 }
+
+// sum returns the sum of all the datapoints of the first result between start and end, following the pagination.
+func (p *CloudWatch) sum(cq []*cloudwatch.MetricDataQuery, start, end time.Time) (float64, error) {
+	var (
+		sum       float64
+		found     bool
+		nextToken *string
+	)
+
+	for {
+		res, err := p.client.GetMetricData(&cloudwatch.GetMetricDataInput{
+			EndTime:           aws.Time(end),
+			StartTime:         aws.Time(start),
+			MetricDataQueries: cq,
+			NextToken:         nextToken,
+		})
+
+		if err != nil {
+			return 0, fmt.Errorf("error requesting cloudwatch: %s", err.Error())
+		}
+
+		if len(res.MetricDataResults) > 0 {
+			for _, v := range res.MetricDataResults[0].Values {
+				sum += aws.Float64Value(v)
+				found = true
+			}
+		}
+
+		nextToken = res.NextToken
+		if aws.StringValue(nextToken) == "" {
+			break
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("invalid response: no datapoints between %s and %s: %w", start, end, ErrNoValuesFound)
+	}
+
+	return sum, nil
+}
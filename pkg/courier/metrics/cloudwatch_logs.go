@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+const (
+	cloudWatchLogsDefaultWindow       = 5 * time.Minute
+	cloudWatchLogsDefaultPollInterval = 1 * time.Second
+	cloudWatchLogsDefaultQueryTimeout = 1 * time.Minute
+)
+
+// CloudWatchLogs is the metric provider that runs a CloudWatch Logs Insights query over the sliding window
+// ending now, and returns the value of the field in the first result row.
+type CloudWatchLogs struct {
+	client        cloudWatchLogsClient
+	logGroupNames []string
+	field         string
+	window        time.Duration
+	pollInterval  time.Duration
+	timeout       time.Duration
+	sleep         func(time.Duration)
+}
+
+// for the testing purpose
+type cloudWatchLogsClient interface {
+	StartQuery(input *cloudwatchlogs.StartQueryInput) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(input *cloudwatchlogs.GetQueryResultsInput) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	StopQuery(input *cloudwatchlogs.StopQueryInput) (*cloudwatchlogs.StopQueryOutput, error)
+}
+
+type CloudWatchLogsOpts struct {
+	LogGroupNames []string
+	// Field is the name of the field in the first result row to be returned as the metric value.
+	// When empty, the first field whose name doesn't start with `@` is used.
+	Field  string
+	Window time.Duration
+}
+
+func NewCloudWatchLogsProvider(client cloudwatchlogsiface.CloudWatchLogsAPI, opts CloudWatchLogsOpts) (*CloudWatchLogs, error) {
+	if len(opts.LogGroupNames) == 0 {
+		return nil, fmt.Errorf("cloudwatch logs metrics provider: one or more log group names are required")
+	}
+
+	window := opts.Window
+	if window == 0 {
+		window = cloudWatchLogsDefaultWindow
+	}
+
+	return &CloudWatchLogs{
+		client:        client,
+		logGroupNames: opts.LogGroupNames,
+		field:         opts.Field,
+		window:        window,
+		pollInterval:  cloudWatchLogsDefaultPollInterval,
+		timeout:       cloudWatchLogsDefaultQueryTimeout,
+		sleep:         time.Sleep,
+	}, nil
+}
+
+// Execute starts the Logs Insights query, polls until the query completes,
+// and returns the numeric value of the field extracted from the first result row.
+func (p *CloudWatchLogs) Execute(query string) (float64, error) {
+	end := time.Now()
+	start := end.Add(-p.window)
+
+	started, err := p.client.StartQuery(&cloudwatchlogs.StartQueryInput{
+		LogGroupNames: aws.StringSlice(p.logGroupNames),
+		QueryString:   aws.String(query),
+		StartTime:     aws.Int64(start.Unix()),
+		EndTime:       aws.Int64(end.Unix()),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error starting cloudwatch logs query: %w", err)
+	}
+
+	queryID := started.QueryId
+
+	var res *cloudwatchlogs.GetQueryResultsOutput
+
+	for deadline := time.Now().Add(p.timeout); ; {
+		res, err = p.client.GetQueryResults(&cloudwatchlogs.GetQueryResultsInput{QueryId: queryID})
+		if err != nil {
+			return 0, fmt.Errorf("error getting cloudwatch logs query results: %w", err)
+		}
+
+		status := aws.StringValue(res.Status)
+
+		if status == cloudwatchlogs.QueryStatusComplete {
+			break
+		}
+
+		if status != cloudwatchlogs.QueryStatusScheduled && status != cloudwatchlogs.QueryStatusRunning {
+			return 0, fmt.Errorf("cloudwatch logs query %s finished with status %q", aws.StringValue(queryID), status)
+		}
+
+		if time.Now().After(deadline) {
+			if _, err := p.client.StopQuery(&cloudwatchlogs.StopQueryInput{QueryId: queryID}); err != nil {
+				return 0, fmt.Errorf("error stopping cloudwatch logs query %s after timeout: %w", aws.StringValue(queryID), err)
+			}
+
+			return 0, fmt.Errorf("cloudwatch logs query %s did not complete within %s", aws.StringValue(queryID), p.timeout)
+		}
+
+		p.sleep(p.pollInterval)
+	}
+
+	if len(res.Results) < 1 {
+		return 0, fmt.Errorf("invalid response: %s: %w", res.String(), ErrNoValuesFound)
+	}
+
+	for _, f := range res.Results[0] {
+		name := aws.StringValue(f.Field)
+
+		if p.field == "" && strings.HasPrefix(name, "@") || p.field != "" && name != p.field {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(aws.StringValue(f.Value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing value of field %q as float64: %w", name, err)
+		}
+
+		return v, nil
+	}
+
+	return 0, fmt.Errorf("field %q not found in the first result row: %s: %w", p.field, res.String(), ErrNoValuesFound)
+}
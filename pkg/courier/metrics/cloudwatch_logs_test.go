@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cloudWatchLogsClientMock struct {
+	started  *cloudwatchlogs.StartQueryInput
+	results  []*cloudwatchlogs.GetQueryResultsOutput
+	polled   int
+	stopped  bool
+	startErr error
+}
+
+func (c *cloudWatchLogsClientMock) StartQuery(in *cloudwatchlogs.StartQueryInput) (*cloudwatchlogs.StartQueryOutput, error) {
+	c.started = in
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("qid")}, c.startErr
+}
+
+func (c *cloudWatchLogsClientMock) GetQueryResults(in *cloudwatchlogs.GetQueryResultsInput) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	r := c.results[c.polled]
+	if c.polled < len(c.results)-1 {
+		c.polled++
+	}
+	return r, nil
+}
+
+func (c *cloudWatchLogsClientMock) StopQuery(in *cloudwatchlogs.StopQueryInput) (*cloudwatchlogs.StopQueryOutput, error) {
+	c.stopped = true
+	return &cloudwatchlogs.StopQueryOutput{Success: aws.Bool(true)}, nil
+}
+
+func row(kvs ...string) []*cloudwatchlogs.ResultField {
+	var fs []*cloudwatchlogs.ResultField
+	for i := 0; i < len(kvs); i += 2 {
+		fs = append(fs, &cloudwatchlogs.ResultField{Field: aws.String(kvs[i]), Value: aws.String(kvs[i+1])})
+	}
+	return fs
+}
+
+func newCloudWatchLogsForTest(c *cloudWatchLogsClientMock, field string) *CloudWatchLogs {
+	return &CloudWatchLogs{
+		client:        c,
+		logGroupNames: []string{"/aws/containerinsights/mycluster/application"},
+		field:         field,
+		window:        5 * time.Minute,
+		pollInterval:  time.Second,
+		timeout:       time.Minute,
+		sleep:         func(time.Duration) {},
+	}
+}
+
+func TestCloudWatchLogsProvider_RunQuery(t *testing.T) {
+	query := `filter level = "error" and kubernetes.labels.app = "canary" | stats count(*) as errors`
+
+	t.Run("ok", func(t *testing.T) {
+		c := &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{Status: aws.String(cloudwatchlogs.QueryStatusScheduled)},
+				{Status: aws.String(cloudwatchlogs.QueryStatusRunning)},
+				{
+					Status: aws.String(cloudwatchlogs.QueryStatusComplete),
+					Results: [][]*cloudwatchlogs.ResultField{
+						row("@ptr", "xxx", "errors", "12"),
+						row("@ptr", "yyy", "errors", "3"),
+					},
+				},
+			},
+		}
+
+		p := newCloudWatchLogsForTest(c, "errors")
+
+		actual, err := p.Execute(query)
+		require.NoError(t, err)
+		assert.Equal(t, float64(12), actual)
+		assert.Equal(t, 2, c.polled)
+
+		assert.Equal(t, query, aws.StringValue(c.started.QueryString))
+		assert.Equal(t, []string{"/aws/containerinsights/mycluster/application"}, aws.StringValueSlice(c.started.LogGroupNames))
+		assert.Equal(t, int64(300), aws.Int64Value(c.started.EndTime)-aws.Int64Value(c.started.StartTime))
+	})
+
+	t.Run("default field", func(t *testing.T) {
+		c := &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{
+					Status:  aws.String(cloudwatchlogs.QueryStatusComplete),
+					Results: [][]*cloudwatchlogs.ResultField{row("@ptr", "xxx", "errors", "1.5")},
+				},
+			},
+		}
+
+		actual, err := newCloudWatchLogsForTest(c, "").Execute(query)
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, actual)
+	})
+
+	t.Run("no values", func(t *testing.T) {
+		c := &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{Status: aws.String(cloudwatchlogs.QueryStatusComplete)},
+			},
+		}
+
+		_, err := newCloudWatchLogsForTest(c, "errors").Execute(query)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNoValuesFound))
+
+		c = &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{
+					Status:  aws.String(cloudwatchlogs.QueryStatusComplete),
+					Results: [][]*cloudwatchlogs.ResultField{row("warnings", "1")},
+				},
+			},
+		}
+
+		_, err = newCloudWatchLogsForTest(c, "errors").Execute(query)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNoValuesFound))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		c := &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{Status: aws.String(cloudwatchlogs.QueryStatusFailed)},
+			},
+		}
+
+		_, err := newCloudWatchLogsForTest(c, "errors").Execute(query)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `finished with status "Failed"`)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		c := &cloudWatchLogsClientMock{
+			results: []*cloudwatchlogs.GetQueryResultsOutput{
+				{Status: aws.String(cloudwatchlogs.QueryStatusRunning)},
+			},
+		}
+
+		p := newCloudWatchLogsForTest(c, "errors")
+		p.timeout = 0
+
+		_, err := p.Execute(query)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "did not complete")
+		assert.True(t, c.stopped)
+	})
+
+	t.Run("log groups required", func(t *testing.T) {
+		_, err := NewCloudWatchLogsProvider(nil, CloudWatchLogsOpts{})
+		require.Error(t, err)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 type cloudWatchClientMock struct {
@@ -18,6 +19,20 @@ func (c cloudWatchClientMock) GetMetricData(_ *cloudwatch.GetMetricDataInput) (*
 	return c.o, c.err
 }
 
+// pagedCloudWatchClientMock returns the outputs one by one, recording the inputs
+type pagedCloudWatchClientMock struct {
+	outputs []*cloudwatch.GetMetricDataOutput
+	inputs  []*cloudwatch.GetMetricDataInput
+}
+
+func (c *pagedCloudWatchClientMock) GetMetricData(in *cloudwatch.GetMetricDataInput) (*cloudwatch.GetMetricDataOutput, error) {
+	o := c.outputs[len(c.inputs)]
+
+	c.inputs = append(c.inputs, in)
+
+	return o, nil
+}
+
 func TestCloudWatchProvider_RunQuery(t *testing.T) {
 	// ref: https://aws.amazon.com/premiumsupport/knowledge-center/cloudwatch-getmetricdata-api/
 	query := `
@@ -102,4 +117,47 @@ func TestCloudWatchProvider_RunQuery(t *testing.T) {
 		require.Error(t, err)
 		require.True(t, errors.Is(err, ErrNoValuesFound))
 	})
+
+	t.Run("window", func(t *testing.T) {
+		c := &pagedCloudWatchClientMock{
+			outputs: []*cloudwatch.GetMetricDataOutput{
+				{
+					MetricDataResults: []*cloudwatch.MetricDataResult{
+						{Values: []*float64{aws.Float64(10), aws.Float64(20)}},
+					},
+					NextToken: aws.String("next"),
+				},
+				{
+					MetricDataResults: []*cloudwatch.MetricDataResult{
+						{Values: []*float64{aws.Float64(30)}},
+					},
+				},
+			},
+		}
+
+		p := CloudWatch{client: c, window: time.Hour}
+
+		actual, err := p.Execute(query)
+		require.NoError(t, err)
+		assert.Equal(t, float64(60), actual)
+
+		require.Len(t, c.inputs, 2)
+		assert.Equal(t, time.Hour, c.inputs[0].EndTime.Sub(*c.inputs[0].StartTime))
+		assert.Nil(t, c.inputs[0].NextToken)
+		assert.Equal(t, "next", aws.StringValue(c.inputs[1].NextToken))
+	})
+
+	t.Run("window without datapoints", func(t *testing.T) {
+		c := &pagedCloudWatchClientMock{
+			outputs: []*cloudwatch.GetMetricDataOutput{
+				{MetricDataResults: []*cloudwatch.MetricDataResult{{Values: []*float64{}}}},
+			},
+		}
+
+		p := CloudWatch{client: c, window: time.Hour}
+
+		_, err := p.Execute(query)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNoValuesFound))
+	})
 }
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	httpProbeDefaultRequests = 10
+	httpProbeDefaultTimeout  = 5 * time.Second
+
+	HTTPProbeSuccessRate   = "success_rate"
+	HTTPProbeLatencyPrefix = "latency_p"
+)
+
+// HTTPProbe is the metric provider that sends synthetic requests to the URL on each execution.
+//
+// It is useful to analyze canaries that don't receive enough real traffic, like ones receiving only 1% of the traffic of
+// low-traffic services. Set headers that make the load balancer route the requests to the canary.
+//
+// The query is either `success_rate`, that returns the percentage of successful requests, or `latency_pNN`, like `latency_p99`,
+// that returns the NN-th percentile of the response times in milliseconds.
+type HTTPProbe struct {
+	client             *http.Client
+	url                string
+	method             string
+	headers            map[string]string
+	requests           int
+	successStatusCodes map[int]bool
+}
+
+type HTTPProbeOpts struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+
+	// Requests is the number of requests sent on each execution
+	Requests int
+	Timeout  time.Duration
+
+	// SuccessStatusCodes are the status codes of successful responses. Defaults to any 2xx and 3xx.
+	SuccessStatusCodes []int
+}
+
+func NewHTTPProbeProvider(opts HTTPProbeOpts) (*HTTPProbe, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("http probe metrics provider: url is required")
+	}
+
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	requests := opts.Requests
+	if requests <= 0 {
+		requests = httpProbeDefaultRequests
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = httpProbeDefaultTimeout
+	}
+
+	var codes map[int]bool
+
+	if len(opts.SuccessStatusCodes) > 0 {
+		codes = map[int]bool{}
+
+		for _, c := range opts.SuccessStatusCodes {
+			codes[c] = true
+		}
+	}
+
+	return &HTTPProbe{
+		client:             &http.Client{Timeout: timeout},
+		url:                opts.URL,
+		method:             method,
+		headers:            opts.Headers,
+		requests:           requests,
+		successStatusCodes: codes,
+	}, nil
+}
+
+// Execute sends the requests and returns the statistic of the responses specified by the query.
+func (p *HTTPProbe) Execute(query string) (float64, error) {
+	query = strings.TrimSpace(query)
+
+	var percentile float64
+
+	switch {
+	case query == HTTPProbeSuccessRate:
+	case strings.HasPrefix(query, HTTPProbeLatencyPrefix):
+		v, err := strconv.ParseFloat(strings.TrimPrefix(query, HTTPProbeLatencyPrefix), 64)
+		if err != nil || v <= 0 || v > 100 {
+			return 0, fmt.Errorf("http probe metrics provider: invalid percentile in query %q", query)
+		}
+
+		percentile = v
+	default:
+		return 0, fmt.Errorf("http probe metrics provider: unsupported query %q: it must be either %q or %q followed by the percentile, like %q",
+			query, HTTPProbeSuccessRate, HTTPProbeLatencyPrefix, HTTPProbeLatencyPrefix+"99")
+	}
+
+	var succeeded int
+
+	var latencies []time.Duration
+
+	var lastErr error
+
+	for i := 0; i < p.requests; i++ {
+		latency, ok, err := p.probe()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		latencies = append(latencies, latency)
+
+		if ok {
+			succeeded++
+		}
+	}
+
+	if query == HTTPProbeSuccessRate {
+		return float64(succeeded) / float64(p.requests) * 100, nil
+	}
+
+	if len(latencies) == 0 {
+		return 0, fmt.Errorf("no response received from %s: %v: %w", p.url, lastErr, ErrNoValuesFound)
+	}
+
+	return float64(latencyPercentile(latencies, percentile)) / float64(time.Millisecond), nil
+}
+
+func (p *HTTPProbe) probe() (time.Duration, bool, error) {
+	req, err := http.NewRequest(p.method, p.url, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("error http.NewRequest: %w", err)
+	}
+
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	// Set Host explicitly, so that a host header can be used to match the listener rule while sending requests to the load balancer
+	if h := req.Header.Get("Host"); h != "" {
+		req.Host = h
+	}
+
+	start := time.Now()
+
+	r, err := p.client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("request failed: %w", err)
+	}
+
+	defer r.Body.Close()
+
+	if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+		return 0, false, fmt.Errorf("error reading body: %w", err)
+	}
+
+	latency := time.Since(start)
+
+	if p.successStatusCodes != nil {
+		return latency, p.successStatusCodes[r.StatusCode], nil
+	}
+
+	return latency, r.StatusCode >= 200 && r.StatusCode < 400, nil
+}
+
+// latencyPercentile returns the p-th percentile of the latencies by the nearest-rank method
+func latencyPercentile(latencies []time.Duration, p float64) time.Duration {
+	sorted := append([]time.Duration{}, latencies...)
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
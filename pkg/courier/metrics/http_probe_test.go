@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProbeProvider_RunQuery(t *testing.T) {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+
+		if r.Header.Get("X-Canary") != "always" || r.Host != "myapp.example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Every 4th request fails slowly
+		if n%4 == 0 {
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	newProbe := func(t *testing.T, opts HTTPProbeOpts) *HTTPProbe {
+		t.Helper()
+
+		atomic.StoreInt32(&requests, 0)
+
+		opts.URL = ts.URL
+		if opts.Headers == nil {
+			opts.Headers = map[string]string{"X-Canary": "always", "Host": "myapp.example.com"}
+		}
+
+		p, err := NewHTTPProbeProvider(opts)
+		require.NoError(t, err)
+
+		return p
+	}
+
+	t.Run("success rate", func(t *testing.T) {
+		p := newProbe(t, HTTPProbeOpts{Requests: 8})
+
+		actual, err := p.Execute("success_rate")
+		require.NoError(t, err)
+		assert.Equal(t, float64(75), actual)
+		assert.Equal(t, int32(8), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("success status codes", func(t *testing.T) {
+		p := newProbe(t, HTTPProbeOpts{Requests: 4, SuccessStatusCodes: []int{200, 500}})
+
+		actual, err := p.Execute("success_rate")
+		require.NoError(t, err)
+		assert.Equal(t, float64(100), actual)
+	})
+
+	t.Run("routing headers", func(t *testing.T) {
+		p := newProbe(t, HTTPProbeOpts{Requests: 4, Headers: map[string]string{}})
+
+		actual, err := p.Execute("success_rate")
+		require.NoError(t, err)
+		assert.Equal(t, float64(0), actual)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		p := newProbe(t, HTTPProbeOpts{Requests: 4})
+
+		p99, err := p.Execute("latency_p99")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, p99, float64(50))
+
+		p50, err := p.Execute("latency_p50")
+		require.NoError(t, err)
+		assert.Less(t, p50, float64(50))
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		p, err := NewHTTPProbeProvider(HTTPProbeOpts{URL: "http://127.0.0.1:1", Requests: 2, Timeout: time.Second})
+		require.NoError(t, err)
+
+		rate, err := p.Execute("success_rate")
+		require.NoError(t, err)
+		assert.Equal(t, float64(0), rate)
+
+		_, err = p.Execute("latency_p99")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrNoValuesFound))
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		p := newProbe(t, HTTPProbeOpts{})
+
+		for _, q := range []string{"error_rate", "latency_p0", "latency_pxx"} {
+			_, err := p.Execute(q)
+			assert.Error(t, err, q)
+		}
+
+		assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("url required", func(t *testing.T) {
+		_, err := NewHTTPProbeProvider(HTTPProbeOpts{})
+		require.Error(t, err)
+	})
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	ls := []time.Duration{5, 1, 4, 2, 3, 6, 7, 8, 9, 10}
+
+	assert.Equal(t, time.Duration(5), latencyPercentile(ls, 50))
+	assert.Equal(t, time.Duration(9), latencyPercentile(ls, 90))
+	assert.Equal(t, time.Duration(10), latencyPercentile(ls, 99))
+	assert.Equal(t, time.Duration(1), latencyPercentile(ls, 1))
+}
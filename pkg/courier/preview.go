@@ -0,0 +1,292 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// PreviewApprovalTagKey is the key of the tag to be added to the preview listener rule to approve the rollout.
+	PreviewApprovalTagKey = "courier-preview-approved"
+
+	// PreviewRuleTagKey is the key of the tag added to the preview listener rule on creation, so that an unrelated
+	// rule at the preview priority is never modified or deleted.
+	PreviewRuleTagKey = "courier-preview"
+
+	DefaultPreviewDuration        = 5 * time.Minute
+	DefaultPreviewApprovalTimeout = 1 * time.Hour
+)
+
+// ALBPreview is the settings of the preview listener rule that routes requests matching the main rule and
+// the additional header and query string matches to the desired target group, before any weighted shifting.
+type ALBPreview struct {
+	Priority     int
+	Headers      map[string]string
+	QueryStrings map[string]string
+
+	RequireApproval bool
+	ApprovalTimeout time.Duration
+
+	// Duration is how long the preview analysis runs for, when RequireApproval is false.
+	Duration time.Duration
+
+	Metrics []Metric
+}
+
+// Preview manages the preview listener rule and waits until the rollout is allowed to proceed to weighted shifting.
+//
+// When RequireApproval is true, the preview lasts until the preview rule is tagged with `courier-preview-approved=true`
+// or ApprovalTimeout elapses. Otherwise, it lasts for Duration. Analyzers are run every AnalysisInterval during the preview,
+// and the preview fails as soon as any analysis fails.
+type Preview struct {
+	ELBV2 elbv2iface.ELBV2API
+
+	ListenerARN    string
+	Priority       int
+	Conditions     []*elbv2.RuleCondition
+	TargetGroupARN string
+
+	RequireApproval bool
+	ApprovalTimeout time.Duration
+	Duration        time.Duration
+
+	Analyzers        []*Analyzer
+	AnalysisInterval time.Duration
+	AnalysisData     interface{}
+
+	Clock Clock
+
+	ruleARN string
+}
+
+// Run creates or updates the preview rule and waits for the preview to pass.
+func (p *Preview) Run(ctx context.Context) error {
+	if err := p.ensureRule(); err != nil {
+		return err
+	}
+
+	clock := p.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	interval := p.AnalysisInterval
+	if interval == 0 {
+		interval = DefaultAnalyzeInterval
+	}
+
+	duration := p.Duration
+	if duration == 0 {
+		duration = DefaultPreviewDuration
+	}
+
+	if p.RequireApproval {
+		duration = p.ApprovalTimeout
+		if duration == 0 {
+			duration = DefaultPreviewApprovalTimeout
+		}
+
+		log.Printf("Waiting for the preview rule %s to be tagged with %s=true", p.ruleARN, PreviewApprovalTagKey)
+	}
+
+	deadline := clock.Now().Add(duration)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for _, a := range p.Analyzers {
+			if err := a.Analyze(p.AnalysisData); err != nil {
+				return fmt.Errorf("preview analysis: %w", err)
+			}
+		}
+
+		if p.RequireApproval {
+			approved, err := p.approved()
+			if err != nil {
+				return err
+			}
+
+			if approved {
+				log.Printf("Preview rule %s has been approved", p.ruleARN)
+
+				return nil
+			}
+
+			if !clock.Now().Before(deadline) {
+				return fmt.Errorf("preview rule %s was not approved within %s", p.ruleARN, duration)
+			}
+		} else if !clock.Now().Before(deadline) {
+			return nil
+		}
+
+		wait := interval
+		if rest := deadline.Sub(clock.Now()); rest < wait {
+			wait = rest
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// Delete deletes the preview rule, if any.
+func (p *Preview) Delete() error {
+	rule, err := p.findRule()
+	if err != nil {
+		return err
+	}
+
+	if rule == nil {
+		return nil
+	}
+
+	if _, err := p.ELBV2.DeleteRule(&elbv2.DeleteRuleInput{RuleArn: rule.RuleArn}); err != nil {
+		return xerrors.Errorf("deleting preview rule %s: %w", *rule.RuleArn, err)
+	}
+
+	log.Printf("Deleted preview rule %s", *rule.RuleArn)
+
+	return nil
+}
+
+func (p *Preview) ensureRule() error {
+	rule, err := p.findRule()
+	if err != nil {
+		return err
+	}
+
+	actions := getRuleActions([]Destination{{TargetGroupARN: p.TargetGroupARN, Weight: 100}})
+
+	if rule != nil {
+		if _, err := p.ELBV2.ModifyRule(&elbv2.ModifyRuleInput{
+			RuleArn:    rule.RuleArn,
+			Conditions: p.Conditions,
+			Actions:    actions,
+		}); err != nil {
+			return xerrors.Errorf("updating preview rule %s: %w", *rule.RuleArn, err)
+		}
+
+		p.ruleARN = *rule.RuleArn
+
+		return nil
+	}
+
+	o, err := p.ELBV2.CreateRule(&elbv2.CreateRuleInput{
+		ListenerArn: aws.String(p.ListenerARN),
+		Priority:    aws.Int64(int64(p.Priority)),
+		Conditions:  p.Conditions,
+		Actions:     actions,
+		Tags:        []*elbv2.Tag{{Key: aws.String(PreviewRuleTagKey), Value: aws.String("true")}},
+	})
+	if err != nil {
+		return xerrors.Errorf("creating preview rule: %w", err)
+	}
+
+	p.ruleARN = *o.Rules[0].RuleArn
+
+	log.Printf("Created preview rule %s forwarding to %s", p.ruleARN, p.TargetGroupARN)
+
+	return nil
+}
+
+func (p *Preview) findRule() (*elbv2.Rule, error) {
+	o, err := p.ELBV2.DescribeRules(&elbv2.DescribeRulesInput{
+		ListenerArn: aws.String(p.ListenerARN),
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("calling elbv2.DescribeRules: %w", err)
+	}
+
+	priority := strconv.Itoa(p.Priority)
+
+	for _, r := range o.Rules {
+		if r.Priority == nil || *r.Priority != priority {
+			continue
+		}
+
+		owned, err := p.hasTag(*r.RuleArn, PreviewRuleTagKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if !owned {
+			return nil, fmt.Errorf("listener rule %s at the preview priority %d is not a preview rule, as it has no %s=true tag. Change the preview priority or remove the rule", *r.RuleArn, p.Priority, PreviewRuleTagKey)
+		}
+
+		return r, nil
+	}
+
+	return nil, nil
+}
+
+func (p *Preview) approved() (bool, error) {
+	return p.hasTag(p.ruleARN, PreviewApprovalTagKey)
+}
+
+// hasTag returns true when the rule is tagged with key=true.
+func (p *Preview) hasTag(ruleARN, key string) (bool, error) {
+	o, err := p.ELBV2.DescribeTags(&elbv2.DescribeTagsInput{
+		ResourceArns: aws.StringSlice([]string{ruleARN}),
+	})
+	if err != nil {
+		return false, xerrors.Errorf("calling elbv2.DescribeTags: %w", err)
+	}
+
+	for _, d := range o.TagDescriptions {
+		for _, t := range d.Tags {
+			if aws.StringValue(t.Key) == key && aws.StringValue(t.Value) == "true" {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// getPreviewRuleConditions returns the conditions of the main rule with the header and query string matches of the preview.
+func getPreviewRuleConditions(lr *ListenerRule, preview *ALBPreview) []*elbv2.RuleCondition {
+	conditions := getRuleConditions(lr)
+
+	for name, value := range preview.Headers {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field: aws.String("http-header"),
+			HttpHeaderConfig: &elbv2.HttpHeaderConditionConfig{
+				HttpHeaderName: aws.String(name),
+				Values:         aws.StringSlice([]string{value}),
+			},
+		})
+	}
+
+	if len(preview.QueryStrings) > 0 {
+		var vs []*elbv2.QueryStringKeyValuePair
+
+		for k, v := range preview.QueryStrings {
+			vs = append(vs, &elbv2.QueryStringKeyValuePair{
+				Key:   aws.String(k),
+				Value: aws.String(v),
+			})
+		}
+
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field: aws.String("query-string"),
+			QueryStringConfig: &elbv2.QueryStringConditionConfig{
+				Values: vs,
+			},
+		})
+	}
+
+	return conditions
+}
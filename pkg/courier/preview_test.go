@@ -0,0 +1,220 @@
+package courier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type previewELBV2 struct {
+	elbv2iface.ELBV2API
+
+	rules    []*elbv2.Rule
+	created  []*elbv2.CreateRuleInput
+	modified []*elbv2.ModifyRuleInput
+	deleted  []string
+
+	// tags are the tags of rules by ARN
+	tags map[string][]*elbv2.Tag
+
+	// approveAfter is the number of DescribeTags calls after which the preview rule is tagged as approved
+	approveAfter int
+	tagCalls     int
+}
+
+func (m *previewELBV2) DescribeRules(i *elbv2.DescribeRulesInput) (*elbv2.DescribeRulesOutput, error) {
+	return &elbv2.DescribeRulesOutput{Rules: m.rules}, nil
+}
+
+func (m *previewELBV2) CreateRule(i *elbv2.CreateRuleInput) (*elbv2.CreateRuleOutput, error) {
+	m.created = append(m.created, i)
+
+	r := &elbv2.Rule{
+		RuleArn:  aws.String("preview-rule"),
+		Priority: aws.String("5"),
+	}
+
+	m.rules = append(m.rules, r)
+
+	if m.tags == nil {
+		m.tags = map[string][]*elbv2.Tag{}
+	}
+
+	m.tags[*r.RuleArn] = i.Tags
+
+	return &elbv2.CreateRuleOutput{Rules: []*elbv2.Rule{r}}, nil
+}
+
+func (m *previewELBV2) ModifyRule(i *elbv2.ModifyRuleInput) (*elbv2.ModifyRuleOutput, error) {
+	m.modified = append(m.modified, i)
+
+	return &elbv2.ModifyRuleOutput{}, nil
+}
+
+func (m *previewELBV2) DeleteRule(i *elbv2.DeleteRuleInput) (*elbv2.DeleteRuleOutput, error) {
+	m.deleted = append(m.deleted, *i.RuleArn)
+
+	return &elbv2.DeleteRuleOutput{}, nil
+}
+
+func (m *previewELBV2) DescribeTags(i *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	m.tagCalls++
+
+	tags := append([]*elbv2.Tag{}, m.tags[*i.ResourceArns[0]]...)
+
+	if m.tagCalls > m.approveAfter {
+		tags = append(tags, &elbv2.Tag{Key: aws.String(PreviewApprovalTagKey), Value: aws.String("true")})
+	}
+
+	return &elbv2.DescribeTagsOutput{
+		TagDescriptions: []*elbv2.TagDescription{{ResourceArn: i.ResourceArns[0], Tags: tags}},
+	}, nil
+}
+
+func TestPreview(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	lr := &ListenerRule{Hosts: []string{"example.com"}}
+
+	conditions := getPreviewRuleConditions(lr, &ALBPreview{Headers: map[string]string{"X-Canary": "true"}})
+
+	newPreview := func(svc elbv2iface.ELBV2API, clock Clock) *Preview {
+		return &Preview{
+			ELBV2:            svc,
+			ListenerARN:      "listener",
+			Priority:         5,
+			Conditions:       conditions,
+			TargetGroupARN:   "next",
+			AnalysisInterval: 10 * time.Second,
+			Clock:            clock,
+		}
+	}
+
+	t.Run("conditions", func(t *testing.T) {
+		require.Len(t, conditions, 2)
+		assert.Equal(t, "host-header", *conditions[0].Field)
+		assert.Equal(t, "http-header", *conditions[1].Field)
+		assert.Equal(t, "X-Canary", *conditions[1].HttpHeaderConfig.HttpHeaderName)
+		assert.Equal(t, []string{"true"}, aws.StringValueSlice(conditions[1].HttpHeaderConfig.Values))
+	})
+
+	t.Run("analysis for duration", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		svc := &previewELBV2{}
+
+		var analyzedAt []time.Time
+
+		p := newPreview(svc, clock)
+		p.Duration = 25 * time.Second
+		p.Analyzers = []*Analyzer{{MetricProvider: metricProviderFunc(func(string) (float64, error) {
+			analyzedAt = append(analyzedAt, clock.Now())
+			return 0, nil
+		})}}
+
+		require.NoError(t, p.Run(context.Background()))
+
+		require.Len(t, svc.created, 1)
+		assert.Equal(t, int64(5), *svc.created[0].Priority)
+		assert.Equal(t, conditions, svc.created[0].Conditions)
+		assert.Equal(t, []*elbv2.TargetGroupTuple{{TargetGroupArn: aws.String("next"), Weight: aws.Int64(100)}}, svc.created[0].Actions[0].ForwardConfig.TargetGroups)
+		assert.Equal(t, []*elbv2.Tag{{Key: aws.String(PreviewRuleTagKey), Value: aws.String("true")}}, svc.created[0].Tags)
+
+		assert.Equal(t, []time.Time{t0, t0.Add(10 * time.Second), t0.Add(20 * time.Second), t0.Add(25 * time.Second)}, analyzedAt)
+
+		require.NoError(t, p.Delete())
+		assert.Equal(t, []string{"preview-rule"}, svc.deleted)
+	})
+
+	t.Run("analysis failure", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		svc := &previewELBV2{}
+
+		max := 0.1
+
+		p := newPreview(svc, clock)
+		p.Analyzers = []*Analyzer{{MetricProvider: metricProviderFunc(func(string) (float64, error) {
+			return 0.5, nil
+		}), Max: &max}}
+
+		err := p.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "preview analysis")
+	})
+
+	t.Run("approval", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		svc := &previewELBV2{
+			rules: []*elbv2.Rule{{RuleArn: aws.String("existing-preview-rule"), Priority: aws.String("5")}},
+			tags: map[string][]*elbv2.Tag{
+				"existing-preview-rule": {{Key: aws.String(PreviewRuleTagKey), Value: aws.String("true")}},
+			},
+			// The first call is for finding the preview rule
+			approveAfter: 3,
+		}
+
+		p := newPreview(svc, clock)
+		p.RequireApproval = true
+
+		require.NoError(t, p.Run(context.Background()))
+
+		// The existing rule at the preview priority is reused
+		assert.Empty(t, svc.created)
+		require.Len(t, svc.modified, 1)
+		assert.Equal(t, "existing-preview-rule", *svc.modified[0].RuleArn)
+
+		assert.Equal(t, 4, svc.tagCalls)
+		assert.Equal(t, t0.Add(20*time.Second), clock.Now())
+	})
+
+	t.Run("foreign rule at the preview priority", func(t *testing.T) {
+		svc := &previewELBV2{
+			rules:        []*elbv2.Rule{{RuleArn: aws.String("unrelated-rule"), Priority: aws.String("5")}},
+			approveAfter: 1000,
+		}
+
+		p := newPreview(svc, NewFakeClock(t0))
+
+		err := p.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "listener rule unrelated-rule at the preview priority 5 is not a preview rule")
+
+		err = p.Delete()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a preview rule")
+
+		assert.Empty(t, svc.created)
+		assert.Empty(t, svc.modified)
+		assert.Empty(t, svc.deleted)
+	})
+
+	t.Run("approval timeout", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		svc := &previewELBV2{approveAfter: 1000}
+
+		p := newPreview(svc, clock)
+		p.RequireApproval = true
+		p.ApprovalTimeout = time.Minute
+
+		err := p.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "was not approved within 1m0s")
+		assert.Equal(t, t0.Add(time.Minute), clock.Now())
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		p := newPreview(&previewELBV2{}, NewFakeClock(t0))
+
+		err := p.Run(ctx)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
@@ -95,6 +95,24 @@ func ReadListenerRule(m api.Lister, schema *ALBSchema) (*ListenerRule, error) {
 	}, nil
 }
 
+// ignoreUnsetBounds drops the zero bound that leaves no value in the range between min and max.
+// The SDK reads an unset float attribute as zero, so a zero bound like `max` of `min = 99` can only be unset.
+func ignoreUnsetBounds(min, max *float64) (*float64, *float64) {
+	if min == nil || max == nil || *min <= *max {
+		return min, max
+	}
+
+	if *max == 0 {
+		return min, nil
+	}
+
+	if *min == 0 {
+		return nil, max
+	}
+
+	return min, max
+}
+
 func LoadMetrics(metrics []interface{}, schema *MetricSchema) ([]Metric, error) {
 	var result []Metric
 
@@ -103,18 +121,20 @@ func LoadMetrics(metrics []interface{}, schema *MetricSchema) ([]Metric, error)
 
 		var max *float64
 
-		if v, set := m[schema.Max]; set {
+		if v, set := m[schema.Max]; set && v != nil {
 			vv := v.(float64)
 			max = &vv
 		}
 
 		var min *float64
 
-		if v, minSet := m[schema.Min]; minSet {
+		if v, minSet := m[schema.Min]; minSet && v != nil {
 			vv := v.(float64)
 			min = &vv
 		}
 
+		min, max = ignoreUnsetBounds(min, max)
+
 		var interval time.Duration
 
 		if v, set := m[schema.Interval]; set {
@@ -138,6 +158,64 @@ func LoadMetrics(metrics []interface{}, schema *MetricSchema) ([]Metric, error)
 			Interval:   interval,
 		}
 
+		if v, set := m[schema.LogGroupNames]; set && v != nil {
+			for _, n := range v.([]interface{}) {
+				metric.LogGroupNames = append(metric.LogGroupNames, n.(string))
+			}
+		}
+
+		if v, set := m[schema.Field]; set && v != nil {
+			metric.Field = v.(string)
+		}
+
+		if v, set := m[schema.Name]; set && v != nil {
+			metric.Name = v.(string)
+		}
+
+		if v, set := m[schema.Window]; set && v != nil && v.(string) != "" {
+			d, err := time.ParseDuration(v.(string))
+			if err != nil {
+				return nil, fmt.Errorf("parsing metric.window %q: %v", v, err)
+			}
+
+			metric.Window = d
+		}
+
+		if v, set := m[schema.URL]; set && v != nil {
+			metric.URL = v.(string)
+		}
+
+		if v, set := m[schema.Method]; set && v != nil {
+			metric.Method = v.(string)
+		}
+
+		if v, set := m[schema.Headers]; set && v != nil {
+			metric.Headers = map[string]string{}
+
+			for k, hv := range v.(map[string]interface{}) {
+				metric.Headers[k] = hv.(string)
+			}
+		}
+
+		if v, set := m[schema.Requests]; set && v != nil {
+			metric.Requests = v.(int)
+		}
+
+		if v, set := m[schema.Timeout]; set && v != nil && v.(string) != "" {
+			d, err := time.ParseDuration(v.(string))
+			if err != nil {
+				return nil, fmt.Errorf("parsing metric.timeout %q: %v", v, err)
+			}
+
+			metric.Timeout = d
+		}
+
+		if v, set := m[schema.SuccessStatusCodes]; set && v != nil {
+			for _, c := range v.([]interface{}) {
+				metric.SuccessStatusCodes = append(metric.SuccessStatusCodes, c.(int))
+			}
+		}
+
 		if v := m["provider"]; v != nil {
 			metric.Provider = v.(string)
 		}
@@ -147,3 +225,67 @@ func LoadMetrics(metrics []interface{}, schema *MetricSchema) ([]Metric, error)
 
 	return result, nil
 }
+
+func LoadSLOs(slos []interface{}, schema *MetricSchema) ([]Metric, error) {
+	var result []Metric
+
+	for _, r := range slos {
+		m := r.(map[string]interface{})
+
+		slo := SLO{
+			Objective:  m[schema.Objective].(float64),
+			GoodQuery:  m[schema.GoodQuery].(string),
+			TotalQuery: m[schema.TotalQuery].(string),
+		}
+
+		for _, rw := range m[schema.BurnRateWindow].([]interface{}) {
+			w := rw.(map[string]interface{})
+
+			long, err := time.ParseDuration(w[schema.LongWindow].(string))
+			if err != nil {
+				return nil, fmt.Errorf("parsing slo.window.long %q: %v", w[schema.LongWindow], err)
+			}
+
+			short, err := time.ParseDuration(w[schema.ShortWindow].(string))
+			if err != nil {
+				return nil, fmt.Errorf("parsing slo.window.short %q: %v", w[schema.ShortWindow], err)
+			}
+
+			slo.Windows = append(slo.Windows, BurnRateWindow{
+				Long:        long,
+				Short:       short,
+				MaxBurnRate: w[schema.MaxBurnRate].(float64),
+			})
+		}
+
+		if err := slo.Validate(); err != nil {
+			return nil, fmt.Errorf("validating slo: %w", err)
+		}
+
+		metric := Metric{
+			Provider:   m[schema.Provider].(string),
+			Address:    m[schema.Address].(string),
+			AWSRegion:  m[schema.AWSRegion].(string),
+			AWSProfile: m[schema.AWSProfile].(string),
+			SLO:        &slo,
+		}
+
+		if v, set := m[schema.LogGroupNames]; set && v != nil {
+			for _, n := range v.([]interface{}) {
+				metric.LogGroupNames = append(metric.LogGroupNames, n.(string))
+			}
+		}
+
+		if v, set := m[schema.Field]; set && v != nil {
+			metric.Field = v.(string)
+		}
+
+		if v, set := m[schema.Name]; set && v != nil {
+			metric.Name = v.(string)
+		}
+
+		result = append(result, metric)
+	}
+
+	return result, nil
+}
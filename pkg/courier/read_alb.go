@@ -1,8 +1,10 @@
 package courier
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/gensdk"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
 	"golang.org/x/xerrors"
 	"strconv"
@@ -18,6 +20,12 @@ type ALBSchema struct {
 	DestinationWeight         string
 	StepWeight                string
 	StepInterval              string
+	Mode                      string
+	ExperimentDuration        string
+	OnComplete                string
+	OnDestroy                 string
+	Fallback                  string
+	Telemetry                 string
 
 	Hosts        string
 	PathPatterns string
@@ -25,6 +33,15 @@ type ALBSchema struct {
 	SourceIPs    string
 	Headers      string
 	QueryStrings string
+
+	// Preview is the key of the `preview` block. The rest of keys are for attributes within the block
+	Preview                string
+	PreviewPriority        string
+	PreviewHeaders         string
+	PreviewQueryStrings    string
+	PreviewRequireApproval string
+	PreviewApprovalTimeout string
+	PreviewDuration        string
 }
 
 func ReadCourierALB(d api.Lister, schema *ALBSchema, metricSchema *MetricSchema) (*CourierALB, error) {
@@ -127,6 +144,34 @@ func ReadCourierALB(d api.Lister, schema *ALBSchema, metricSchema *MetricSchema)
 
 	conf.StepInterval = stepInterval
 
+	mode, experimentDuration, onComplete, err := ReadMode(d, schema.Mode, schema.ExperimentDuration, schema.OnComplete)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.Mode = mode
+	conf.ExperimentDuration = experimentDuration
+	conf.OnComplete = onComplete
+
+	conf.OnDestroy = OnDestroyDelete
+	if v, ok := d.Get(schema.OnDestroy).(string); ok && v != "" {
+		conf.OnDestroy = v
+	}
+
+	switch conf.OnDestroy {
+	case OnDestroyDelete, OnDestroyKeep:
+	case OnDestroyFallback:
+		if vs, _ := d.Get(schema.Fallback).([]interface{}); len(vs) > 0 && vs[0] != nil {
+			conf.FallbackTargetGroupARN = vs[0].(map[string]interface{})[schema.DestinationTargetGroupARN].(string)
+		}
+
+		if conf.FallbackTargetGroupARN == "" {
+			return nil, fmt.Errorf("%s is required when on_destroy is %q", schema.Fallback, OnDestroyFallback)
+		}
+	default:
+		return nil, fmt.Errorf("invalid on_destroy %q: must be either %q, %q, or %q", conf.OnDestroy, OnDestroyDelete, OnDestroyKeep, OnDestroyFallback)
+	}
+
 	metrics, err := ReadMetrics(d, metricSchema)
 	if err != nil {
 		return nil, err
@@ -134,6 +179,13 @@ func ReadCourierALB(d api.Lister, schema *ALBSchema, metricSchema *MetricSchema)
 
 	conf.Metrics = metrics
 
+	telemetry, err := ReadTelemetry(d, schema.Telemetry)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.Telemetry = telemetry
+
 	lr, err := ReadListenerRule(d, schema)
 	if err != nil {
 		return nil, err
@@ -141,5 +193,77 @@ func ReadCourierALB(d api.Lister, schema *ALBSchema, metricSchema *MetricSchema)
 
 	conf.ListenerRule = lr
 
+	if schema.Preview != "" {
+		if vs, _ := d.Get(schema.Preview).([]interface{}); len(vs) > 0 && vs[0] != nil {
+			preview, err := ReadALBPreview(vs[0].(map[string]interface{}), schema, metricSchema)
+			if err != nil {
+				return nil, xerrors.Errorf("reading preview: %w", err)
+			}
+
+			if preview.Priority >= conf.Priority {
+				return nil, fmt.Errorf("preview priority %d must be less than the rule priority %d, so that the preview rule is evaluated first", preview.Priority, conf.Priority)
+			}
+
+			conf.Preview = preview
+		}
+	}
+
 	return &conf, nil
 }
+
+func ReadALBPreview(m map[string]interface{}, schema *ALBSchema, metricSchema *MetricSchema) (*ALBPreview, error) {
+	preview := ALBPreview{
+		Priority: m[schema.PreviewPriority].(int),
+	}
+
+	if v, ok := m[schema.PreviewHeaders].(map[string]interface{}); ok && len(v) > 0 {
+		preview.Headers = map[string]string{}
+
+		for k, hv := range v {
+			preview.Headers[k] = hv.(string)
+		}
+	}
+
+	if v, ok := m[schema.PreviewQueryStrings].(map[string]interface{}); ok && len(v) > 0 {
+		preview.QueryStrings = map[string]string{}
+
+		for k, qv := range v {
+			preview.QueryStrings[k] = qv.(string)
+		}
+	}
+
+	if len(preview.Headers) == 0 && len(preview.QueryStrings) == 0 {
+		return nil, errors.New("one or more of `headers` or `querystrings` are required to distinguish preview requests from others")
+	}
+
+	if v, ok := m[schema.PreviewRequireApproval].(bool); ok {
+		preview.RequireApproval = v
+	}
+
+	if v, ok := m[schema.PreviewApprovalTimeout].(string); ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing approval_timeout %q: %w", v, err)
+		}
+
+		preview.ApprovalTimeout = d
+	}
+
+	if v, ok := m[schema.PreviewDuration].(string); ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration %q: %w", v, err)
+		}
+
+		preview.Duration = d
+	}
+
+	metrics, err := ReadMetrics(&gensdk.MapReader{M: m}, metricSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Metrics = metrics
+
+	return &preview, nil
+}
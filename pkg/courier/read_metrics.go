@@ -5,13 +5,38 @@ import (
 )
 
 type MetricSchema struct {
-	DatadogMetric      string
-	CloudWatchMetric   string
-	Min, Max, Interval string
-	Address            string
-	Query              string
-	AWSProfile         string
-	AWSRegion          string
+	Name                 string
+	DatadogMetric        string
+	CloudWatchMetric     string
+	CloudWatchLogsMetric string
+	Min, Max, Interval   string
+	Address              string
+	Query                string
+	AWSProfile           string
+	AWSRegion            string
+	LogGroupNames        string
+	Field                string
+	Window               string
+
+	// HTTPProbe is the key of `http_probe` blocks. The rest of keys are for attributes within an `http_probe` block
+	HTTPProbe          string
+	URL                string
+	Method             string
+	Headers            string
+	Requests           string
+	Timeout            string
+	SuccessStatusCodes string
+
+	// SLO is the key of `slo` blocks. The rest of keys are for attributes within an `slo` block
+	SLO            string
+	Provider       string
+	Objective      string
+	GoodQuery      string
+	TotalQuery     string
+	BurnRateWindow string
+	LongWindow     string
+	ShortWindow    string
+	MaxBurnRate    string
 }
 
 func ReadMetrics(d api.Getter, schema *MetricSchema) ([]Metric, error) {
@@ -43,5 +68,46 @@ func ReadMetrics(d api.Getter, schema *MetricSchema) ([]Metric, error) {
 		metrics = append(metrics, ms...)
 	}
 
+	if schema.CloudWatchLogsMetric != "" {
+		if v := d.Get(schema.CloudWatchLogsMetric); v != nil {
+			ms, err := LoadMetrics(v.([]interface{}), schema)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range ms {
+				ms[i].Provider = "cloudwatch_logs"
+			}
+
+			metrics = append(metrics, ms...)
+		}
+	}
+
+	if schema.HTTPProbe != "" {
+		if v := d.Get(schema.HTTPProbe); v != nil {
+			ms, err := LoadMetrics(v.([]interface{}), schema)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range ms {
+				ms[i].Provider = "http_probe"
+			}
+
+			metrics = append(metrics, ms...)
+		}
+	}
+
+	if schema.SLO != "" {
+		if v := d.Get(schema.SLO); v != nil {
+			ms, err := LoadSLOs(v.([]interface{}), schema)
+			if err != nil {
+				return nil, err
+			}
+
+			metrics = append(metrics, ms...)
+		}
+	}
+
 	return metrics, nil
 }
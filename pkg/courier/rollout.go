@@ -0,0 +1,274 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+)
+
+const (
+	DefaultStepWeight   = 5
+	DefaultStepInterval = 30 * time.Second
+)
+
+// Rollout gradually shifts traffic from the destination `From` to the destination `To` via the Router.
+//
+// The rollout advances the weight of `To` by StepWeight and pauses for StepInterval after each step.
+// Analyzers are run every AnalysisInterval during each pause. When an analysis fails or the context
+// is canceled before the rollout completes, all the traffic is routed back to `From`.
+//
+// The rollout resumes from the weight currently set for `To`, so that re-running an interrupted rollout
+// doesn't shift the traffic back and forth.
+//
+// Weights of other destinations, like another target group of the same listener rule, are kept as is. Only the rest of
+// the traffic is shifted, so the rollout completes when `To` receives all the traffic but theirs.
+type Rollout struct {
+	Router TrafficRouter
+
+	From string
+	To   string
+
+	// Start is the weight of `To` set at the first step when no traffic is routed to it yet.
+	// Defaults to StepWeight.
+	Start int
+
+	StepWeight   int
+	StepInterval time.Duration
+
+	Analyzers        []*Analyzer
+	AnalysisInterval time.Duration
+	AnalysisData     interface{}
+
+	// Telemetry records weights, analyses, rollbacks and durations of the rollout, if set.
+	Telemetry *Telemetry
+	// DestinationLabel is the name of the telemetry label for destination IDs. Defaults to `target_group`.
+	DestinationLabel string
+
+	Clock Clock
+
+	// others is the weights of destinations other than From and To, and total is the weight shared by From and To
+	others map[string]int
+	total  int
+}
+
+func (r *Rollout) Run(ctx context.Context) error {
+	start := r.clock().Now()
+
+	err := r.run(ctx)
+
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+
+	r.Telemetry.Gauge(TelemetryMetricRolloutDurationSeconds, r.clock().Now().Sub(start).Seconds(), nil)
+	r.Telemetry.Inc(TelemetryMetricRollouts, map[string]string{TelemetryLabelResult: result})
+	r.Telemetry.Flush()
+
+	return err
+}
+
+func (r *Rollout) run(ctx context.Context) error {
+	clock := r.clock()
+
+	step := r.StepWeight
+	if step <= 0 {
+		step = DefaultStepWeight
+	}
+
+	interval := r.StepInterval
+	if interval == 0 {
+		interval = DefaultStepInterval
+	}
+
+	weights, err := r.readWeights()
+	if err != nil {
+		return err
+	}
+
+	p := weights[r.To]
+
+	if p >= r.total {
+		log.Printf("Skipping traffic shift from %s to %s: all the traffic is already routed to the latter", r.From, r.To)
+
+		return nil
+	}
+
+	if p > 0 {
+		log.Printf("Resuming traffic shift from %s to %s at weight %d", r.From, r.To, p)
+
+		p += step
+	} else if r.Start > 0 {
+		p = r.Start
+	} else {
+		p = step
+	}
+
+	for {
+		if p >= r.total {
+			p = r.total
+		}
+
+		log.Printf("Setting weight to %s: Weight %d, %s: Weight %d.", r.To, p, r.From, r.total-p)
+
+		if err := r.setWeight(p); err != nil {
+			return err
+		}
+
+		if p == r.total {
+			log.Printf("Traffic shift from %s to %s completed.", r.From, r.To)
+
+			return nil
+		}
+
+		if err := r.pause(ctx, clock, interval); err != nil {
+			log.Printf("Rolling back traffic shift from %s to %s: %v", r.From, r.To, err)
+
+			r.Telemetry.Inc(TelemetryMetricRollbacks, nil)
+
+			if rollbackErr := r.setWeight(0); rollbackErr != nil {
+				return xerrors.Errorf("rolling back traffic shift due to %v: %w", err, rollbackErr)
+			}
+
+			return err
+		}
+
+		p += step
+	}
+}
+
+// readWeights returns the current weights, and keeps ones of destinations other than From and To for setWeight.
+func (r *Rollout) readWeights() (map[string]int, error) {
+	weights, err := r.Router.GetWeights()
+	if err != nil {
+		return nil, xerrors.Errorf("getting current weights: %w", err)
+	}
+
+	r.others = map[string]int{}
+	r.total = 100
+
+	for dest, w := range weights {
+		if dest != r.From && dest != r.To {
+			r.others[dest] = w
+			r.total -= w
+		}
+	}
+
+	if r.total <= 0 {
+		return nil, fmt.Errorf("shifting traffic from %s to %s: no traffic to shift, as all the traffic is routed to other destinations %v", r.From, r.To, r.others)
+	}
+
+	if len(r.others) > 0 {
+		log.Printf("Keeping weights of destinations other than %s and %s: %v", r.From, r.To, r.others)
+	}
+
+	return weights, nil
+}
+
+// setWeight sets the weight of To to p, and the rest of the traffic not routed to other destinations to From.
+// readWeights needs to be called beforehand.
+func (r *Rollout) setWeight(p int) error {
+	weights := copyWeights(r.others)
+	weights[r.To] = p
+	weights[r.From] = r.total - p
+
+	if err := r.Router.SetWeights(weights); err != nil {
+		return err
+	}
+
+	label := r.DestinationLabel
+	if label == "" {
+		label = TelemetryLabelTargetGroup
+	}
+
+	for _, dest := range []string{r.To, r.From} {
+		w := weights[dest]
+
+		r.Telemetry.Gauge(TelemetryMetricTrafficWeight, float64(w), map[string]string{label: dest})
+	}
+
+	r.Telemetry.Flush()
+
+	return nil
+}
+
+// pause waits for d while running analyzers every AnalysisInterval, and once more at the end of the pause.
+func (r *Rollout) pause(ctx context.Context, clock Clock, d time.Duration) error {
+	deadline := clock.Now().Add(d)
+
+	analysisInterval := r.AnalysisInterval
+	if analysisInterval == 0 {
+		analysisInterval = DefaultAnalyzeInterval
+	}
+
+	for {
+		wait := deadline.Sub(clock.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		if len(r.Analyzers) > 0 && analysisInterval < wait {
+			wait = analysisInterval
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+
+		for _, a := range r.Analyzers {
+			if err := a.analyze(r.AnalysisData, r.Telemetry); err != nil {
+				return xerrors.Errorf("analyze: %w", err)
+			}
+		}
+
+		r.Telemetry.Flush()
+	}
+}
+
+func (r *Rollout) clock() Clock {
+	if r.Clock != nil {
+		return r.Clock
+	}
+
+	return realClock{}
+}
+
+// RunAnalyzers runs all the analyzers every DefaultAnalyzeInterval until ctx is canceled.
+// This is used for running analyses shared by multiple rollouts that are run concurrently.
+// The analyses aren't recorded as telemetry, as they aren't tied to any single rollout.
+func RunAnalyzers(ctx context.Context, analyzers []*Analyzer, data interface{}) error {
+	g, errctx := errgroup.WithContext(ctx)
+
+	for i := range analyzers {
+		a := analyzers[i]
+
+		g.Go(func() error {
+			ticker := time.NewTicker(DefaultAnalyzeInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-errctx.Done():
+					// Deployment finished. Stop checking as not necessary anymore
+					return nil
+				case <-ticker.C:
+					if err := a.analyze(data, nil); err != nil {
+						return fmt.Errorf("analyze: %w", err)
+					}
+				}
+			}
+		})
+	}
+
+	return g.Wait()
+}
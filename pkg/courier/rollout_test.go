@@ -0,0 +1,275 @@
+package courier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metricProviderFunc func(string) (float64, error)
+
+func (f metricProviderFunc) Execute(q string) (float64, error) {
+	return f(q)
+}
+
+func weightsOf(changes []WeightChange, dest string) []int {
+	var ws []int
+
+	for _, c := range changes {
+		ws = append(ws, c.Weights[dest])
+	}
+
+	return ws
+}
+
+func TestRollout_Run(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("steps and pauses", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   25,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		h := router.History()
+		assert.Equal(t, []int{25, 50, 75, 100}, weightsOf(h, "next"))
+		assert.Equal(t, []int{75, 50, 25, 0}, weightsOf(h, "prev"))
+
+		for i, c := range h {
+			assert.Equal(t, t0.Add(time.Duration(i)*time.Minute), c.At)
+		}
+	})
+
+	t.Run("start", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100})
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			Start:        1,
+			StepWeight:   50,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		assert.Equal(t, []int{1, 51, 100}, weightsOf(router.History(), "next"))
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 60, "next": 40})
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   25,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		assert.Equal(t, []int{65, 90, 100}, weightsOf(router.History(), "next"))
+	})
+
+	t.Run("other destinations", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 70, "next": 0, "exp": 30})
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   40,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		h := router.History()
+		assert.Equal(t, []int{40, 70}, weightsOf(h, "next"))
+		assert.Equal(t, []int{30, 0}, weightsOf(h, "prev"))
+		assert.Equal(t, []int{30, 30}, weightsOf(h, "exp"))
+	})
+
+	t.Run("all traffic routed to other destinations", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 0, "next": 0, "exp": 100})
+
+		r := &Rollout{
+			Router: router,
+			From:   "prev",
+			To:     "next",
+			Clock:  clock,
+		}
+
+		require.Error(t, r.Run(context.Background()))
+
+		assert.Empty(t, router.History())
+	})
+
+	t.Run("already completed", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 0, "next": 100})
+
+		r := &Rollout{
+			Router: router,
+			From:   "prev",
+			To:     "next",
+			Clock:  clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		assert.Empty(t, router.History())
+	})
+
+	t.Run("rollback on analysis failure", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		max := 0.1
+
+		// Simulates the error rate that goes beyond the threshold once the new destination receives half of the traffic
+		errorRate := metricProviderFunc(func(string) (float64, error) {
+			ws, err := router.GetWeights()
+			if err != nil {
+				return 0, err
+			}
+
+			if ws["next"] >= 50 {
+				return 0.5, nil
+			}
+
+			return 0.01, nil
+		})
+
+		r := &Rollout{
+			Router:           router,
+			From:             "prev",
+			To:               "next",
+			StepWeight:       25,
+			StepInterval:     time.Minute,
+			Analyzers:        []*Analyzer{{MetricProvider: errorRate, Query: "error_rate", Max: &max}},
+			AnalysisInterval: 20 * time.Second,
+			Clock:            clock,
+		}
+
+		err := r.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "0.5 is beyond 0.1")
+
+		h := router.History()
+		assert.Equal(t, []int{25, 50, 0}, weightsOf(h, "next"))
+		assert.Equal(t, []int{75, 50, 100}, weightsOf(h, "prev"))
+		// The failure is detected by the first analysis after the second step
+		assert.Equal(t, t0.Add(80*time.Second), h[2].At)
+	})
+
+	t.Run("analysis interval", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		var analyzedAt []time.Time
+
+		p := metricProviderFunc(func(string) (float64, error) {
+			analyzedAt = append(analyzedAt, clock.Now())
+			return 0, nil
+		})
+
+		r := &Rollout{
+			Router:           router,
+			From:             "prev",
+			To:               "next",
+			StepWeight:       50,
+			StepInterval:     time.Minute,
+			Analyzers:        []*Analyzer{{MetricProvider: p, Query: "q"}},
+			AnalysisInterval: 25 * time.Second,
+			Clock:            clock,
+		}
+
+		require.NoError(t, r.Run(context.Background()))
+
+		assert.Equal(t, []time.Time{
+			t0.Add(25 * time.Second),
+			t0.Add(50 * time.Second),
+			t0.Add(60 * time.Second),
+		}, analyzedAt)
+	})
+
+	t.Run("rollback on cancellation", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		router.OnSetWeights = func(ws map[string]int) error {
+			if ws["next"] == 50 {
+				cancel()
+			}
+			return nil
+		}
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   25,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		err := r.Run(ctx)
+		require.True(t, errors.Is(err, context.Canceled))
+
+		assert.Equal(t, []int{25, 50, 0}, weightsOf(router.History(), "next"))
+	})
+
+	t.Run("router failure", func(t *testing.T) {
+		clock := NewFakeClock(t0)
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		routerErr := errors.New("throttled")
+
+		router.OnSetWeights = func(ws map[string]int) error {
+			if ws["next"] == 50 {
+				return routerErr
+			}
+			return nil
+		}
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   25,
+			StepInterval: time.Minute,
+			Clock:        clock,
+		}
+
+		err := r.Run(context.Background())
+		require.True(t, errors.Is(err, routerErr))
+
+		assert.Equal(t, []int{25}, weightsOf(router.History(), "next"))
+	})
+}
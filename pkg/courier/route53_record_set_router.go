@@ -6,7 +6,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/k-kinzal/progressived/pkg/provider"
 	"golang.org/x/xerrors"
-	"log"
 	"time"
 )
 
@@ -17,13 +16,39 @@ type Route53RecordSetRouter struct {
 	Destinations              []DestinationRecordSet
 	CanaryAdvancementInterval time.Duration
 	CanaryAdvancementStep     int
+	Analyzers                 []*Analyzer
+	AnalysisData              interface{}
+
+	// Mode is either `rollout` or `experiment`. ExperimentDuration and OnComplete are for experiments
+	Mode               string
+	ExperimentDuration time.Duration
+	OnComplete         string
+
+	// Telemetry records the rollout metrics labeled with the record name and set identifiers, if set
+	Telemetry *Telemetry
 }
 
 func (r *Route53RecordSetRouter) TrafficShift(ctx context.Context) error {
 	var src, dst DestinationRecordSet
 
+	// The percentage of the traffic routed to the variant during the experiment
+	var experimentWeight int
+
 	switch len(r.Destinations) {
 	case 2:
+		if r.Mode == ModeExperiment {
+			baseline, variant, p, err := experimentSplit([]int{r.Destinations[0].Weight, r.Destinations[1].Weight})
+			if err != nil {
+				return err
+			}
+
+			src = r.Destinations[baseline]
+			dst = r.Destinations[variant]
+			experimentWeight = p
+
+			break
+		}
+
 		if r.Destinations[0].Weight < r.Destinations[1].Weight {
 			src = r.Destinations[0]
 			dst = r.Destinations[1]
@@ -49,64 +74,62 @@ func (r *Route53RecordSetRouter) TrafficShift(ctx context.Context) error {
 		return xerrors.Errorf("initializing courier Route 53: %w", err)
 	}
 
-	// Gradually shift traffic from current tg to desired tg by
-	// updating rule
-	var step int
-
-	if r.CanaryAdvancementStep > 0 {
-		step = r.CanaryAdvancementStep
-	} else {
-		step = 5
+	rollout := &Rollout{
+		Router: &Route53Router{
+			Provider:    rp,
+			Source:      src.SetIdentifier,
+			Destination: dst.SetIdentifier,
+		},
+		From:         src.SetIdentifier,
+		To:           dst.SetIdentifier,
+		StepWeight:   r.CanaryAdvancementStep,
+		StepInterval: r.CanaryAdvancementInterval,
+		Analyzers:    r.Analyzers,
+		AnalysisData: r.AnalysisData,
+		Telemetry: r.Telemetry.With(map[string]string{
+			TelemetryLabelRecord: r.RecordName,
+		}),
+		DestinationLabel: TelemetryLabelSetIdentifier,
 	}
 
-	var advancementInterval time.Duration
+	if r.Mode == ModeExperiment {
+		e := &Experiment{
+			Rollout:    *rollout,
+			Weight:     experimentWeight,
+			Duration:   r.ExperimentDuration,
+			OnComplete: r.OnComplete,
+		}
 
-	if r.CanaryAdvancementInterval != 0 {
-		advancementInterval = r.CanaryAdvancementInterval
-	} else {
-		advancementInterval = 30 * time.Second
+		return e.Run(ctx)
 	}
 
-	ticker := time.NewTicker(advancementInterval)
-	defer ticker.Stop()
-
-	p := step
-
-	for {
-		select {
-		case <-ticker.C:
-			if p >= 100 {
-				p = 100
-			}
-
-			log.Printf("Setting weight to %v", p)
-
-			if err := rp.Update(float64(p)); err != nil {
-				return err
-			}
-
-			if p == 100 {
-				fmt.Printf("Done.")
-				return nil
-			}
-
-			p += step
-		case <-ctx.Done():
-			if p != 100 {
-				log.Printf("Rolling back traffic for record %s", r.RecordName)
+	return rollout.Run(ctx)
+}
 
-				if err := rp.Update(0); err != nil {
-					return err
-				}
+// Route53Router is the TrafficRouter that routes traffic by modifying weights of two weighted Route 53 records.
+type Route53Router struct {
+	Provider    *provider.Route53Provider
+	Source      string
+	Destination string
+}
 
-				break
-			}
+func (r *Route53Router) GetWeights() (map[string]int, error) {
+	p, err := r.Provider.Get()
+	if err != nil {
+		return nil, xerrors.Errorf("getting current weights of Route 53 records: %w", err)
+	}
 
-			return nil
-		}
+	// progressived returns -1 when either of the records is missing or both the weights are zero.
+	if p < 0 {
+		p = 0
 	}
 
-	log.Printf("Rolling back traffic shift for Route 53 record %s", r.RecordName)
+	return map[string]int{
+		r.Source:      100 - int(p),
+		r.Destination: int(p),
+	}, nil
+}
 
-	return nil
+func (r *Route53Router) SetWeights(weights map[string]int) error {
+	return r.Provider.Update(float64(weights[r.Destination]))
 }
@@ -0,0 +1,29 @@
+package courier
+
+import "time"
+
+// TrafficRouter is the abstraction over the thing that splits traffic across destinations,
+// like an ALB listener rule forwarding to weighted target groups, or a set of weighted Route 53 records.
+//
+// Weights are keyed by destination IDs, which are target group ARNs for ALB and set identifiers for Route 53.
+type TrafficRouter interface {
+	GetWeights() (map[string]int, error)
+	SetWeights(map[string]int) error
+}
+
+// Clock is used by the rollout engine to wait between steps and analyses.
+// It is replaced with FakeClock in tests so that rollouts can be simulated deterministically.
+type Clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,105 @@
+package courier
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is the Clock whose time advances only when waited on.
+//
+// After advances the clock by the given duration and returns an already-fired channel,
+// so that a rollout driven by FakeClock completes immediately while observing the same sequence of
+// steps, pauses and analyses as it would in real time.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+
+	return ch
+}
+
+// WeightChange is a record of SimulatedRouter.SetWeights call.
+type WeightChange struct {
+	At      time.Time
+	Weights map[string]int
+}
+
+// SimulatedRouter is the in-memory TrafficRouter for testing rollouts without AWS.
+type SimulatedRouter struct {
+	Clock Clock
+
+	// OnSetWeights is called before weights are updated. Returning an error from it fails SetWeights.
+	OnSetWeights func(map[string]int) error
+
+	mu      sync.Mutex
+	weights map[string]int
+	history []WeightChange
+}
+
+func NewSimulatedRouter(clock Clock, weights map[string]int) *SimulatedRouter {
+	return &SimulatedRouter{
+		Clock:   clock,
+		weights: copyWeights(weights),
+	}
+}
+
+func (r *SimulatedRouter) GetWeights() (map[string]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return copyWeights(r.weights), nil
+}
+
+func (r *SimulatedRouter) SetWeights(weights map[string]int) error {
+	if r.OnSetWeights != nil {
+		if err := r.OnSetWeights(weights); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.weights = copyWeights(weights)
+	r.history = append(r.history, WeightChange{At: r.Clock.Now(), Weights: copyWeights(weights)})
+
+	return nil
+}
+
+// History returns all the weight changes made so far, in order.
+func (r *SimulatedRouter) History() []WeightChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]WeightChange{}, r.history...)
+}
+
+func copyWeights(weights map[string]int) map[string]int {
+	c := make(map[string]int, len(weights))
+
+	for k, v := range weights {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,177 @@
+package courier
+
+import (
+	"fmt"
+	"math"
+	"text/template"
+	"time"
+)
+
+// SLO is the service level objective that the burn-rate analysis checks the canary against.
+//
+// GoodQuery and TotalQuery are run over each window to count good and total events.
+// Within the queries, `{{ window }}` and `{{ windowSeconds }}` are replaced with the length of the window
+// the query is run over, so that the query can aggregate events over the whole window.
+type SLO struct {
+	// Objective is the target percentage of good events, like 99.9
+	Objective float64
+
+	GoodQuery  string
+	TotalQuery string
+
+	Windows []BurnRateWindow
+}
+
+// BurnRateWindow is a pair of windows of the multi-window burn-rate analysis.
+//
+// The analysis fails only when the burn rates over both the Long and the Short windows exceed MaxBurnRate.
+// The long window makes the verdict resistant to short spikes, while the short window makes it recover fast
+// once the error rate goes down.
+type BurnRateWindow struct {
+	Long        time.Duration
+	Short       time.Duration
+	MaxBurnRate float64
+}
+
+// ErrorBudget returns the ratio of events allowed to be bad, like 0.001 for the objective of 99.9%.
+func (s SLO) ErrorBudget() float64 {
+	return 1 - s.Objective/100
+}
+
+func (s SLO) Validate() error {
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return fmt.Errorf("objective must be greater than 0 and less than 100: got %v", s.Objective)
+	}
+
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("one or more windows are required")
+	}
+
+	for _, w := range s.Windows {
+		if w.Long <= 0 || w.Short <= 0 {
+			return fmt.Errorf("window lengths must be positive: got long=%s, short=%s", w.Long, w.Short)
+		}
+
+		if w.Short > w.Long {
+			return fmt.Errorf("short window %s must not be longer than long window %s", w.Short, w.Long)
+		}
+
+		if w.MaxBurnRate <= 0 {
+			return fmt.Errorf("max burn rate must be positive: got %v", w.MaxBurnRate)
+		}
+	}
+
+	return nil
+}
+
+// WindowLengths returns all the distinct window lengths the queries need to be run over.
+func (s SLO) WindowLengths() []time.Duration {
+	var ds []time.Duration
+
+	seen := map[time.Duration]bool{}
+
+	for _, w := range s.Windows {
+		for _, d := range []time.Duration{w.Long, w.Short} {
+			if !seen[d] {
+				seen[d] = true
+				ds = append(ds, d)
+			}
+		}
+	}
+
+	return ds
+}
+
+// SLOAnalyzer analyzes the rate at which the error budget of the SLO is being burnt.
+type SLOAnalyzer struct {
+	SLO
+
+	// Providers are the metric providers keyed by the length of the window they query over.
+	Providers map[time.Duration]MetricProvider
+}
+
+func (a *SLOAnalyzer) Analyze(data interface{}) error {
+	return a.analyze(data, nil)
+}
+
+// analyze checks the burn rates while recording them to the telemetry as analysis values labeled with windows.
+func (a *SLOAnalyzer) analyze(data interface{}, t *Telemetry) error {
+	burnRates := map[time.Duration]float64{}
+
+	burnRate := func(window time.Duration) (float64, error) {
+		if r, ok := burnRates[window]; ok {
+			return r, nil
+		}
+
+		r, err := a.burnRate(window, data)
+		if err != nil {
+			return 0, err
+		}
+
+		burnRates[window] = r
+
+		t.Gauge(TelemetryMetricAnalysisValue, r, map[string]string{TelemetryLabelWindow: window.String()})
+
+		return r, nil
+	}
+
+	for _, w := range a.Windows {
+		long, err := burnRate(w.Long)
+		if err != nil {
+			return err
+		}
+
+		if long <= w.MaxBurnRate {
+			continue
+		}
+
+		short, err := burnRate(w.Short)
+		if err != nil {
+			return err
+		}
+
+		if short <= w.MaxBurnRate {
+			continue
+		}
+
+		return fmt.Errorf("checking burn rate against threshold: error budget is burning %.1fx over %s and %.1fx over %s, beyond %vx",
+			long, w.Long, short, w.Short, w.MaxBurnRate)
+	}
+
+	return nil
+}
+
+func (a *SLOAnalyzer) burnRate(window time.Duration, data interface{}) (float64, error) {
+	p, ok := a.Providers[window]
+	if !ok {
+		return 0, fmt.Errorf("no metric provider found for window %s", window)
+	}
+
+	funcs := template.FuncMap{
+		"window": func() string {
+			return window.String()
+		},
+		"windowSeconds": func() int64 {
+			return int64(window.Seconds())
+		},
+	}
+
+	good, err := executeQuery(p, "good_query", a.GoodQuery, data, funcs)
+	if err != nil {
+		return 0, fmt.Errorf("good events over %s: %w", window, err)
+	}
+
+	total, err := executeQuery(p, "total_query", a.TotalQuery, data, funcs)
+	if err != nil {
+		return 0, fmt.Errorf("total events over %s: %w", window, err)
+	}
+
+	// No events means no error budget is burnt
+	if total <= 0 {
+		return 0, nil
+	}
+
+	errorRate := math.Max(0, 1-good/total)
+
+	return errorRate / a.ErrorBudget(), nil
+}
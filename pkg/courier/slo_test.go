@@ -0,0 +1,176 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventCounts is the fake metric provider that returns good and total event counts over a window
+type eventCounts struct {
+	good, total float64
+}
+
+func sloAnalyzerForTest(counts map[time.Duration]eventCounts, queries *[]string) *Analyzer {
+	providers := map[time.Duration]MetricProvider{}
+
+	for w := range counts {
+		w, c := w, counts[w]
+
+		providers[w] = metricProviderFunc(func(q string) (float64, error) {
+			*queries = append(*queries, q)
+
+			switch q {
+			case fmt.Sprintf("good %d", int64(w.Seconds())):
+				return c.good, nil
+			case fmt.Sprintf("total %d", int64(w.Seconds())):
+				return c.total, nil
+			}
+
+			return 0, fmt.Errorf("unexpected query %q", q)
+		})
+	}
+
+	return &Analyzer{
+		SLO: &SLOAnalyzer{
+			SLO: SLO{
+				Objective:  99.9,
+				GoodQuery:  "good {{ windowSeconds }}",
+				TotalQuery: "total {{ windowSeconds }}",
+				Windows: []BurnRateWindow{
+					{Long: time.Hour, Short: 5 * time.Minute, MaxBurnRate: 14.4},
+					{Long: 6 * time.Hour, Short: 30 * time.Minute, MaxBurnRate: 6},
+				},
+			},
+			Providers: providers,
+		},
+	}
+}
+
+func TestSLOAnalyzer_Analyze(t *testing.T) {
+	t.Run("within budget", func(t *testing.T) {
+		var queries []string
+
+		a := sloAnalyzerForTest(map[time.Duration]eventCounts{
+			time.Hour:        {good: 9995, total: 10000},
+			5 * time.Minute:  {good: 999, total: 1000},
+			6 * time.Hour:    {good: 59990, total: 60000},
+			30 * time.Minute: {good: 4990, total: 5000},
+		}, &queries)
+
+		require.NoError(t, a.Analyze(nil))
+
+		// Short windows are not queried while long windows are within budget
+		assert.Equal(t, []string{"good 3600", "total 3600", "good 21600", "total 21600"}, queries)
+	})
+
+	t.Run("fast burn", func(t *testing.T) {
+		var queries []string
+
+		a := sloAnalyzerForTest(map[time.Duration]eventCounts{
+			time.Hour:        {good: 9800, total: 10000},
+			5 * time.Minute:  {good: 950, total: 1000},
+			6 * time.Hour:    {good: 59990, total: 60000},
+			30 * time.Minute: {good: 4990, total: 5000},
+		}, &queries)
+
+		err := a.Analyze(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error budget is burning 20.0x over 1h0m0s and 50.0x over 5m0s, beyond 14.4x")
+	})
+
+	t.Run("recovered in the short window", func(t *testing.T) {
+		var queries []string
+
+		a := sloAnalyzerForTest(map[time.Duration]eventCounts{
+			time.Hour:        {good: 9800, total: 10000},
+			5 * time.Minute:  {good: 1000, total: 1000},
+			6 * time.Hour:    {good: 59990, total: 60000},
+			30 * time.Minute: {good: 4990, total: 5000},
+		}, &queries)
+
+		require.NoError(t, a.Analyze(nil))
+	})
+
+	t.Run("slow burn", func(t *testing.T) {
+		var queries []string
+
+		a := sloAnalyzerForTest(map[time.Duration]eventCounts{
+			time.Hour:        {good: 9990, total: 10000},
+			5 * time.Minute:  {good: 990, total: 1000},
+			6 * time.Hour:    {good: 59400, total: 60000},
+			30 * time.Minute: {good: 4960, total: 5000},
+		}, &queries)
+
+		err := a.Analyze(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "over 6h0m0s")
+	})
+
+	t.Run("no traffic", func(t *testing.T) {
+		var queries []string
+
+		a := sloAnalyzerForTest(map[time.Duration]eventCounts{
+			time.Hour:        {},
+			5 * time.Minute:  {},
+			6 * time.Hour:    {},
+			30 * time.Minute: {},
+		}, &queries)
+
+		require.NoError(t, a.Analyze(nil))
+	})
+
+	t.Run("rollout rollback", func(t *testing.T) {
+		clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		router := NewSimulatedRouter(clock, map[string]int{"prev": 100, "next": 0})
+
+		var queries []string
+
+		r := &Rollout{
+			Router:       router,
+			From:         "prev",
+			To:           "next",
+			StepWeight:   50,
+			StepInterval: time.Minute,
+			Analyzers: []*Analyzer{sloAnalyzerForTest(map[time.Duration]eventCounts{
+				time.Hour:        {good: 9000, total: 10000},
+				5 * time.Minute:  {good: 900, total: 1000},
+				6 * time.Hour:    {good: 59990, total: 60000},
+				30 * time.Minute: {good: 4990, total: 5000},
+			}, &queries)},
+			Clock: clock,
+		}
+
+		err := r.Run(context.Background())
+		require.Error(t, err)
+
+		assert.Equal(t, []int{50, 0}, weightsOf(router.History(), "next"))
+	})
+}
+
+func TestSLO_Validate(t *testing.T) {
+	valid := SLO{
+		Objective: 99.9,
+		Windows:   []BurnRateWindow{{Long: time.Hour, Short: 5 * time.Minute, MaxBurnRate: 14.4}},
+	}
+
+	require.NoError(t, valid.Validate())
+
+	for name, modify := range map[string]func(*SLO){
+		"objective":     func(s *SLO) { s.Objective = 100 },
+		"no windows":    func(s *SLO) { s.Windows = nil },
+		"short window":  func(s *SLO) { s.Windows[0].Short = 2 * time.Hour },
+		"max burn rate": func(s *SLO) { s.Windows[0].MaxBurnRate = 0 },
+	} {
+		s := valid
+		s.Windows = append([]BurnRateWindow{}, valid.Windows...)
+
+		modify(&s)
+
+		assert.Error(t, s.Validate(), name)
+	}
+}
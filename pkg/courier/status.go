@@ -0,0 +1,342 @@
+package courier
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"golang.org/x/xerrors"
+)
+
+// HealthSummary counts targets or health checkers by their health.
+type HealthSummary struct {
+	Healthy   int
+	Unhealthy int
+	// Other is the number of targets that are neither healthy nor unhealthy, like `initial`, `draining`, and `unused`
+	Other int
+}
+
+// ALBRuleStatus is the current state of an ALB listener rule managed by courier.
+type ALBRuleStatus struct {
+	RuleARN      string
+	ListenerARN  string
+	Priority     int
+	IsDefault    bool
+	Conditions   ListenerRule
+	TargetGroups []TargetGroupStatus
+}
+
+type TargetGroupStatus struct {
+	ARN    string
+	Weight int
+	Health HealthSummary
+}
+
+// DescribeALBRuleStatus describes the listener rule either by ruleARN, or by listenerARN and priority.
+func DescribeALBRuleStatus(svc elbv2iface.ELBV2API, ruleARN, listenerARN string, priority int) (*ALBRuleStatus, error) {
+	var rule *elbv2.Rule
+
+	if ruleARN != "" {
+		o, err := svc.DescribeRules(&elbv2.DescribeRulesInput{
+			RuleArns: aws.StringSlice([]string{ruleARN}),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("calling elbv2.DescribeRules: %w", err)
+		}
+
+		if len(o.Rules) != 1 {
+			return nil, fmt.Errorf("unexpected number of rules returned for %s: want 1, got %d", ruleARN, len(o.Rules))
+		}
+
+		rule = o.Rules[0]
+	} else {
+		if listenerARN == "" {
+			return nil, fmt.Errorf("either rule arn or listener arn is required")
+		}
+
+		var marker *string
+
+		for rule == nil {
+			o, err := svc.DescribeRules(&elbv2.DescribeRulesInput{
+				ListenerArn: aws.String(listenerARN),
+				Marker:      marker,
+			})
+			if err != nil {
+				return nil, xerrors.Errorf("calling elbv2.DescribeRules: %w", err)
+			}
+
+			for _, r := range o.Rules {
+				if aws.StringValue(r.Priority) == strconv.Itoa(priority) {
+					rule = r
+
+					break
+				}
+			}
+
+			if o.NextMarker == nil || *o.NextMarker == "" {
+				break
+			}
+
+			marker = o.NextMarker
+		}
+
+		if rule == nil {
+			return nil, fmt.Errorf("no rule found at priority %d in listener %s", priority, listenerARN)
+		}
+	}
+
+	status := &ALBRuleStatus{
+		RuleARN:     aws.StringValue(rule.RuleArn),
+		ListenerARN: listenerARN,
+		IsDefault:   aws.BoolValue(rule.IsDefault),
+		Conditions:  ruleConditionsToListenerRule(rule.Conditions),
+	}
+
+	if !status.IsDefault {
+		p, err := strconv.Atoi(aws.StringValue(rule.Priority))
+		if err != nil {
+			return nil, fmt.Errorf("parsing priority %q of rule %s: %w", aws.StringValue(rule.Priority), status.RuleARN, err)
+		}
+
+		status.Priority = p
+	}
+
+	if status.ListenerARN == "" {
+		status.ListenerARN = listenerARNOfRule(status.RuleARN)
+	}
+
+	for _, a := range rule.Actions {
+		if a.ForwardConfig != nil {
+			for _, tg := range a.ForwardConfig.TargetGroups {
+				status.TargetGroups = append(status.TargetGroups, TargetGroupStatus{
+					ARN:    aws.StringValue(tg.TargetGroupArn),
+					Weight: int(aws.Int64Value(tg.Weight)),
+				})
+			}
+		} else if a.TargetGroupArn != nil {
+			status.TargetGroups = append(status.TargetGroups, TargetGroupStatus{
+				ARN:    aws.StringValue(a.TargetGroupArn),
+				Weight: 100,
+			})
+		}
+	}
+
+	for i := range status.TargetGroups {
+		tg := &status.TargetGroups[i]
+
+		o, err := svc.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(tg.ARN),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("calling elbv2.DescribeTargetHealth for %s: %w", tg.ARN, err)
+		}
+
+		for _, h := range o.TargetHealthDescriptions {
+			if h.TargetHealth == nil {
+				continue
+			}
+
+			switch aws.StringValue(h.TargetHealth.State) {
+			case elbv2.TargetHealthStateEnumHealthy:
+				tg.Health.Healthy++
+			case elbv2.TargetHealthStateEnumUnhealthy:
+				tg.Health.Unhealthy++
+			default:
+				tg.Health.Other++
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// listenerARNOfRule derives the listener ARN from the rule ARN, as DescribeRules doesn't return it.
+// For example, the rule `arn:aws:elasticloadbalancing:us-east-2:123456789012:listener-rule/app/my-alb/abc/def/ghi` belongs to
+// the listener `arn:aws:elasticloadbalancing:us-east-2:123456789012:listener/app/my-alb/abc/def`.
+func listenerARNOfRule(ruleARN string) string {
+	if !strings.Contains(ruleARN, ":listener-rule/") {
+		return ""
+	}
+
+	arn := strings.Replace(ruleARN, ":listener-rule/", ":listener/", 1)
+
+	i := strings.LastIndex(arn, "/")
+
+	return arn[:i]
+}
+
+func ruleConditionsToListenerRule(conditions []*elbv2.RuleCondition) ListenerRule {
+	var lr ListenerRule
+
+	for _, c := range conditions {
+		switch aws.StringValue(c.Field) {
+		case "host-header":
+			if c.HostHeaderConfig != nil {
+				lr.Hosts = append(lr.Hosts, aws.StringValueSlice(c.HostHeaderConfig.Values)...)
+			}
+		case "path-pattern":
+			if c.PathPatternConfig != nil {
+				lr.PathPatterns = append(lr.PathPatterns, aws.StringValueSlice(c.PathPatternConfig.Values)...)
+			}
+		case "http-request-method":
+			if c.HttpRequestMethodConfig != nil {
+				lr.Methods = append(lr.Methods, aws.StringValueSlice(c.HttpRequestMethodConfig.Values)...)
+			}
+		case "source-ip":
+			if c.SourceIpConfig != nil {
+				lr.SourceIPs = append(lr.SourceIPs, aws.StringValueSlice(c.SourceIpConfig.Values)...)
+			}
+		case "http-header":
+			if c.HttpHeaderConfig != nil {
+				if lr.Headers == nil {
+					lr.Headers = map[string][]string{}
+				}
+
+				name := aws.StringValue(c.HttpHeaderConfig.HttpHeaderName)
+
+				lr.Headers[name] = append(lr.Headers[name], aws.StringValueSlice(c.HttpHeaderConfig.Values)...)
+			}
+		case "query-string":
+			if c.QueryStringConfig != nil {
+				if lr.QueryStrings == nil {
+					lr.QueryStrings = map[string]string{}
+				}
+
+				for _, kv := range c.QueryStringConfig.Values {
+					lr.QueryStrings[aws.StringValue(kv.Key)] = aws.StringValue(kv.Value)
+				}
+			}
+		}
+	}
+
+	return lr
+}
+
+// Route53RecordStatus is the current state of weighted Route 53 records sharing the name and the type.
+type Route53RecordStatus struct {
+	Name       string
+	Type       string
+	RecordSets []RecordSetStatus
+}
+
+type RecordSetStatus struct {
+	SetIdentifier string
+	Weight        int
+	// Values are the alias target's DNS name, or the values of the non-alias record
+	Values        []string
+	HealthCheckID string
+	// Health counts Route 53 health checkers by their latest observation. It is zero for records without health checks
+	Health HealthSummary
+}
+
+// DescribeRoute53RecordStatus describes all the weighted records of the name within the hosted zone.
+// When recordType is empty, records of any type are described, which is usually fine as weighted records
+// managed by courier are all of the same type.
+func DescribeRoute53RecordStatus(svc route53iface.Route53API, zoneID, name, recordType string) (*Route53RecordStatus, error) {
+	fqdn := strings.TrimSuffix(name, ".") + "."
+
+	status := &Route53RecordStatus{
+		Name: fqdn,
+		Type: recordType,
+	}
+
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(fqdn),
+	}
+
+	if recordType != "" {
+		input.StartRecordType = aws.String(recordType)
+	}
+
+	err := svc.ListResourceRecordSetsPages(input, func(o *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		for _, rs := range o.ResourceRecordSets {
+			// Records are sorted by name, so no more records of the name follow
+			if !strings.EqualFold(aws.StringValue(rs.Name), fqdn) {
+				return false
+			}
+
+			if recordType != "" && aws.StringValue(rs.Type) != recordType {
+				continue
+			}
+
+			if rs.SetIdentifier == nil || rs.Weight == nil {
+				continue
+			}
+
+			s := RecordSetStatus{
+				SetIdentifier: aws.StringValue(rs.SetIdentifier),
+				Weight:        int(aws.Int64Value(rs.Weight)),
+				HealthCheckID: aws.StringValue(rs.HealthCheckId),
+			}
+
+			if rs.AliasTarget != nil {
+				s.Values = []string{aws.StringValue(rs.AliasTarget.DNSName)}
+			}
+
+			for _, r := range rs.ResourceRecords {
+				s.Values = append(s.Values, aws.StringValue(r.Value))
+			}
+
+			if status.Type == "" {
+				status.Type = aws.StringValue(rs.Type)
+			}
+
+			status.RecordSets = append(status.RecordSets, s)
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("calling route53.ListResourceRecordSets: %w", err)
+	}
+
+	if len(status.RecordSets) == 0 {
+		return nil, fmt.Errorf("no weighted records found for %s in hosted zone %s", fqdn, zoneID)
+	}
+
+	for i := range status.RecordSets {
+		s := &status.RecordSets[i]
+
+		if s.HealthCheckID == "" {
+			continue
+		}
+
+		o, err := svc.GetHealthCheckStatus(&route53.GetHealthCheckStatusInput{
+			HealthCheckId: aws.String(s.HealthCheckID),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("calling route53.GetHealthCheckStatus for %s: %w", s.HealthCheckID, err)
+		}
+
+		for _, obs := range o.HealthCheckObservations {
+			if obs.StatusReport == nil {
+				s.Health.Other++
+
+				continue
+			}
+
+			// Status looks like "Success: HTTP Status Code 200, OK" or "Failure: Connection timed out."
+			switch st := aws.StringValue(obs.StatusReport.Status); {
+			case strings.HasPrefix(st, "Success"):
+				s.Health.Healthy++
+			case strings.HasPrefix(st, "Failure"):
+				s.Health.Unhealthy++
+			default:
+				s.Health.Other++
+			}
+		}
+	}
+
+	sort.SliceStable(status.RecordSets, func(i, j int) bool {
+		return status.RecordSets[i].SetIdentifier < status.RecordSets[j].SetIdentifier
+	})
+
+	return status, nil
+}
@@ -0,0 +1,184 @@
+package courier
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statusELBV2 struct {
+	elbv2iface.ELBV2API
+
+	rules  []*elbv2.Rule
+	health map[string][]string
+}
+
+func (m *statusELBV2) DescribeRules(i *elbv2.DescribeRulesInput) (*elbv2.DescribeRulesOutput, error) {
+	if len(i.RuleArns) == 0 {
+		return &elbv2.DescribeRulesOutput{Rules: m.rules}, nil
+	}
+
+	var rules []*elbv2.Rule
+
+	for _, r := range m.rules {
+		if *r.RuleArn == *i.RuleArns[0] {
+			rules = append(rules, r)
+		}
+	}
+
+	return &elbv2.DescribeRulesOutput{Rules: rules}, nil
+}
+
+func (m *statusELBV2) DescribeTargetHealth(i *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	var ds []*elbv2.TargetHealthDescription
+
+	for _, s := range m.health[*i.TargetGroupArn] {
+		ds = append(ds, &elbv2.TargetHealthDescription{TargetHealth: &elbv2.TargetHealth{State: aws.String(s)}})
+	}
+
+	return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: ds}, nil
+}
+
+func TestDescribeALBRuleStatus(t *testing.T) {
+	ruleARN := "arn:aws:elasticloadbalancing:us-east-2:123456789012:listener-rule/app/my-alb/abc/def/ghi"
+
+	svc := &statusELBV2{
+		rules: []*elbv2.Rule{
+			{
+				RuleArn:   aws.String("default"),
+				Priority:  aws.String("default"),
+				IsDefault: aws.Bool(true),
+			},
+			{
+				RuleArn:  aws.String(ruleARN),
+				Priority: aws.String("10"),
+				Conditions: []*elbv2.RuleCondition{
+					{Field: aws.String("host-header"), HostHeaderConfig: &elbv2.HostHeaderConditionConfig{Values: aws.StringSlice([]string{"example.com"})}},
+					{Field: aws.String("http-header"), HttpHeaderConfig: &elbv2.HttpHeaderConditionConfig{HttpHeaderName: aws.String("X-Canary"), Values: aws.StringSlice([]string{"true"})}},
+				},
+				Actions: getRuleActions([]Destination{
+					{TargetGroupARN: "blue", Weight: 80},
+					{TargetGroupARN: "green", Weight: 20},
+				}),
+			},
+		},
+		health: map[string][]string{
+			"blue":  {"healthy", "healthy", "unhealthy"},
+			"green": {"healthy", "draining"},
+		},
+	}
+
+	want := &ALBRuleStatus{
+		RuleARN:     ruleARN,
+		ListenerARN: "arn:aws:elasticloadbalancing:us-east-2:123456789012:listener/app/my-alb/abc/def",
+		Priority:    10,
+		Conditions: ListenerRule{
+			Hosts:   []string{"example.com"},
+			Headers: map[string][]string{"X-Canary": {"true"}},
+		},
+		TargetGroups: []TargetGroupStatus{
+			{ARN: "blue", Weight: 80, Health: HealthSummary{Healthy: 2, Unhealthy: 1}},
+			{ARN: "green", Weight: 20, Health: HealthSummary{Healthy: 1, Other: 1}},
+		},
+	}
+
+	t.Run("by rule arn", func(t *testing.T) {
+		status, err := DescribeALBRuleStatus(svc, ruleARN, "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, want, status)
+	})
+
+	t.Run("by listener and priority", func(t *testing.T) {
+		status, err := DescribeALBRuleStatus(svc, "", "listener", 10)
+		require.NoError(t, err)
+
+		w := *want
+		w.ListenerARN = "listener"
+
+		assert.Equal(t, &w, status)
+	})
+
+	t.Run("missing priority", func(t *testing.T) {
+		_, err := DescribeALBRuleStatus(svc, "", "listener", 20)
+		require.Error(t, err)
+	})
+}
+
+type statusRoute53 struct {
+	route53iface.Route53API
+
+	recordSets []*route53.ResourceRecordSet
+	checks     map[string][]string
+}
+
+func (m *statusRoute53) ListResourceRecordSetsPages(i *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool) error {
+	var rss []*route53.ResourceRecordSet
+
+	for _, rs := range m.recordSets {
+		if *rs.Name >= *i.StartRecordName {
+			rss = append(rss, rs)
+		}
+	}
+
+	fn(&route53.ListResourceRecordSetsOutput{ResourceRecordSets: rss}, true)
+
+	return nil
+}
+
+func (m *statusRoute53) GetHealthCheckStatus(i *route53.GetHealthCheckStatusInput) (*route53.GetHealthCheckStatusOutput, error) {
+	var obs []*route53.HealthCheckObservation
+
+	for _, s := range m.checks[*i.HealthCheckId] {
+		obs = append(obs, &route53.HealthCheckObservation{StatusReport: &route53.StatusReport{Status: aws.String(s)}})
+	}
+
+	return &route53.GetHealthCheckStatusOutput{HealthCheckObservations: obs}, nil
+}
+
+func TestDescribeRoute53RecordStatus(t *testing.T) {
+	svc := &statusRoute53{
+		recordSets: []*route53.ResourceRecordSet{
+			{Name: aws.String("example.com."), Type: aws.String("NS")},
+			{
+				Name:          aws.String("www.example.com."),
+				Type:          aws.String("A"),
+				SetIdentifier: aws.String("green"),
+				Weight:        aws.Int64(25),
+				AliasTarget:   &route53.AliasTarget{DNSName: aws.String("green.elb.amazonaws.com.")},
+				HealthCheckId: aws.String("hc-green"),
+			},
+			{
+				Name:            aws.String("www.example.com."),
+				Type:            aws.String("A"),
+				SetIdentifier:   aws.String("blue"),
+				Weight:          aws.Int64(75),
+				ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+			},
+			{Name: aws.String("xyz.example.com."), Type: aws.String("A"), SetIdentifier: aws.String("other"), Weight: aws.Int64(1)},
+		},
+		checks: map[string][]string{
+			"hc-green": {"Success: HTTP Status Code 200, OK", "Failure: Connection timed out.", "Success: HTTP Status Code 200, OK"},
+		},
+	}
+
+	status, err := DescribeRoute53RecordStatus(svc, "zone", "www.example.com", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, &Route53RecordStatus{
+		Name: "www.example.com.",
+		Type: "A",
+		RecordSets: []RecordSetStatus{
+			{SetIdentifier: "blue", Weight: 75, Values: []string{"10.0.0.1"}},
+			{SetIdentifier: "green", Weight: 25, Values: []string{"green.elb.amazonaws.com."}, HealthCheckID: "hc-green", Health: HealthSummary{Healthy: 2, Unhealthy: 1}},
+		},
+	}, status)
+
+	_, err = DescribeRoute53RecordStatus(svc, "zone", "missing.example.com", "")
+	require.Error(t, err)
+}
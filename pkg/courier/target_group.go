@@ -2,48 +2,78 @@ package courier
 
 import (
 	"fmt"
+	"sort"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"golang.org/x/xerrors"
 )
 
-func SetDesiredTGTrafficPercentage(svc elbv2iface.ELBV2API, l ListenerStatus, p int) error {
-	if p > 100 {
-		return fmt.Errorf("BUG: invalid value for p: got %d, must be less than 100", p)
+// ALBRuleRouter is the TrafficRouter that routes traffic by modifying target group weights of an ALB listener rule.
+type ALBRuleRouter struct {
+	ELBV2   elbv2iface.ELBV2API
+	RuleARN string
+}
+
+func (r *ALBRuleRouter) GetWeights() (map[string]int, error) {
+	o, err := r.ELBV2.DescribeRules(&elbv2.DescribeRulesInput{
+		RuleArns: aws.StringSlice([]string{r.RuleARN}),
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("calling elbv2.DescribeRules: %w", err)
 	}
 
-	if l.DesiredTG == nil {
-		return fmt.Errorf("BUG: DesiredTG is nil: %+v", l)
+	if len(o.Rules) != 1 {
+		return nil, fmt.Errorf("unexpected number of rules returned for %s: want 1, got %d", r.RuleARN, len(o.Rules))
 	}
 
-	if l.CurrentTG == nil {
-		return fmt.Errorf("BUG: CurrentTG is nil: %+v", l)
+	weights := map[string]int{}
+
+	for _, a := range o.Rules[0].Actions {
+		if a.ForwardConfig == nil {
+			continue
+		}
+
+		for _, tg := range a.ForwardConfig.TargetGroups {
+			weights[aws.StringValue(tg.TargetGroupArn)] = int(aws.Int64Value(tg.Weight))
+		}
 	}
 
-	if l.Rule == nil {
-		return fmt.Errorf("BUG: Rule is nil: %+v", l)
+	return weights, nil
+}
+
+func (r *ALBRuleRouter) SetWeights(weights map[string]int) error {
+	var arns []string
+
+	for arn := range weights {
+		arns = append(arns, arn)
+	}
+
+	// Sort for stable ordering of target groups in the request
+	sort.Strings(arns)
+
+	var tgs []*elbv2.TargetGroupTuple
+
+	for _, arn := range arns {
+		tgs = append(tgs, &elbv2.TargetGroupTuple{
+			TargetGroupArn: aws.String(arn),
+			Weight:         aws.Int64(int64(weights[arn])),
+		})
 	}
 
-	_, err := svc.ModifyRule(&elbv2.ModifyRuleInput{
+	_, err := r.ELBV2.ModifyRule(&elbv2.ModifyRuleInput{
 		Actions: []*elbv2.Action{
 			{
 				ForwardConfig: &elbv2.ForwardActionConfig{
 					TargetGroupStickinessConfig: nil,
-					TargetGroups: []*elbv2.TargetGroupTuple{
-						{
-							TargetGroupArn: l.DesiredTG.TargetGroupArn,
-							Weight:         aws.Int64(int64(p)),
-						}, {
-							TargetGroupArn: l.CurrentTG.TargetGroupArn,
-							Weight:         aws.Int64(int64(100 - p)),
-						},
-					},
+					TargetGroups:                tgs,
 				},
 				Order: aws.Int64(1),
 				Type:  aws.String("forward"),
 			},
 		},
-		RuleArn: l.Rule.RuleArn,
+		RuleArn: aws.String(r.RuleARN),
 	})
 	if err != nil {
 		return err
@@ -51,3 +81,28 @@ func SetDesiredTGTrafficPercentage(svc elbv2iface.ELBV2API, l ListenerStatus, p
 
 	return nil
 }
+
+func SetDesiredTGTrafficPercentage(svc elbv2iface.ELBV2API, l ListenerStatus, p int) error {
+	if p > 100 {
+		return fmt.Errorf("BUG: invalid value for p: got %d, must be less than 100", p)
+	}
+
+	if l.DesiredTG == nil {
+		return fmt.Errorf("BUG: DesiredTG is nil: %+v", l)
+	}
+
+	if l.CurrentTG == nil {
+		return fmt.Errorf("BUG: CurrentTG is nil: %+v", l)
+	}
+
+	if l.Rule == nil {
+		return fmt.Errorf("BUG: Rule is nil: %+v", l)
+	}
+
+	r := &ALBRuleRouter{ELBV2: svc, RuleARN: *l.Rule.RuleArn}
+
+	return r.SetWeights(map[string]int{
+		*l.DesiredTG.TargetGroupArn: p,
+		*l.CurrentTG.TargetGroupArn: 100 - p,
+	})
+}
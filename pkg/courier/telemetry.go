@@ -0,0 +1,490 @@
+package courier
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+)
+
+const (
+	TelemetryMetricTrafficWeight          = "courier_traffic_weight"
+	TelemetryMetricAnalysisValue          = "courier_analysis_value"
+	TelemetryMetricAnalysisFailures       = "courier_analysis_failures_total"
+	TelemetryMetricRollbacks              = "courier_rollbacks_total"
+	TelemetryMetricRollouts               = "courier_rollouts_total"
+	TelemetryMetricRolloutDurationSeconds = "courier_rollout_duration_seconds"
+
+	TelemetryLabelListener      = "listener"
+	TelemetryLabelRule          = "rule"
+	TelemetryLabelRecord        = "record"
+	TelemetryLabelTargetGroup   = "target_group"
+	TelemetryLabelSetIdentifier = "set_identifier"
+	TelemetryLabelAnalysis      = "analysis"
+	TelemetryLabelWindow        = "window"
+	TelemetryLabelResult        = "result"
+
+	DefaultTelemetryJob = "eksctl_courier"
+)
+
+type TelemetryMetricType string
+
+const (
+	TelemetryGauge   TelemetryMetricType = "gauge"
+	TelemetryCounter TelemetryMetricType = "counter"
+)
+
+// TelemetryMetric is a data point of a rollout metric pushed to a TelemetrySink.
+type TelemetryMetric struct {
+	Name   string
+	Type   TelemetryMetricType
+	Value  float64
+	Labels map[string]string
+	Time   time.Time
+	// Start is when the counter started counting from zero. Unset for gauges.
+	Start time.Time
+}
+
+// TelemetrySink pushes rollout metrics to an external monitoring system.
+type TelemetrySink interface {
+	Push([]TelemetryMetric) error
+}
+
+// Telemetry records gauges and counters of rollouts and pushes them to the Sink on Flush.
+//
+// Counters count from zero in each Telemetry, which is created per Terraform run.
+// Recorded metrics are buffered until the next Flush, keeping only the latest value of each series,
+// so that a rollout step results in a batch push rather than a request per data point.
+//
+// All the methods are no-op on the nil Telemetry, so that the rollout engine can record metrics unconditionally.
+// Failures in pushing metrics are logged but never fail rollouts.
+type Telemetry struct {
+	Sink TelemetrySink
+
+	// Labels are added to all the metrics recorded via this Telemetry
+	Labels map[string]string
+
+	store *telemetryStore
+}
+
+// telemetryStore is the counters and the metrics pending for the next Flush, shared by Telemetry derived via With.
+type telemetryStore struct {
+	mu       sync.Mutex
+	start    time.Time
+	counters map[string]float64
+	pending  []TelemetryMetric
+	// index is the index of each series in pending
+	index map[string]int
+}
+
+func newTelemetryStore() *telemetryStore {
+	return &telemetryStore{
+		start:    time.Now(),
+		counters: map[string]float64{},
+		index:    map[string]int{},
+	}
+}
+
+func NewTelemetry(sink TelemetrySink, labels map[string]string) *Telemetry {
+	return &Telemetry{
+		Sink:   sink,
+		Labels: labels,
+		store:  newTelemetryStore(),
+	}
+}
+
+// With returns the Telemetry that adds the labels to all the metrics, sharing the sink, counters, and pending metrics with t.
+func (t *Telemetry) With(labels map[string]string) *Telemetry {
+	if t == nil {
+		return nil
+	}
+
+	merged := map[string]string{}
+
+	for k, v := range t.Labels {
+		merged[k] = v
+	}
+
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	return &Telemetry{
+		Sink:   t.Sink,
+		Labels: merged,
+		store:  t.getStore(),
+	}
+}
+
+// Gauge sets the gauge to the value.
+func (t *Telemetry) Gauge(name string, value float64, labels map[string]string) {
+	if t == nil {
+		return
+	}
+
+	ls := t.labels(labels)
+
+	s := t.getStore()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.record(TelemetryMetric{Name: name, Type: TelemetryGauge, Value: value, Labels: ls, Time: time.Now()})
+}
+
+// Inc increments the counter by one.
+func (t *Telemetry) Inc(name string, labels map[string]string) {
+	if t == nil {
+		return
+	}
+
+	ls := t.labels(labels)
+
+	s := t.getStore()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := telemetrySeriesKey(name, ls)
+	s.counters[key]++
+
+	s.record(TelemetryMetric{Name: name, Type: TelemetryCounter, Value: s.counters[key], Labels: ls, Time: time.Now(), Start: s.start})
+}
+
+// Flush pushes all the metrics recorded since the last Flush to the Sink.
+func (t *Telemetry) Flush() {
+	if t == nil {
+		return
+	}
+
+	s := t.getStore()
+
+	s.mu.Lock()
+	ms := s.pending
+	s.pending = nil
+	s.index = map[string]int{}
+	s.mu.Unlock()
+
+	if t.Sink == nil || len(ms) == 0 {
+		return
+	}
+
+	if err := t.Sink.Push(ms); err != nil {
+		log.Printf("Failed to push %d telemetry metrics: %v", len(ms), err)
+	}
+}
+
+func (t *Telemetry) getStore() *telemetryStore {
+	if t.store == nil {
+		t.store = newTelemetryStore()
+	}
+
+	return t.store
+}
+
+func (t *Telemetry) labels(labels map[string]string) map[string]string {
+	ls := map[string]string{}
+
+	for k, v := range t.Labels {
+		ls[k] = v
+	}
+
+	for k, v := range labels {
+		ls[k] = v
+	}
+
+	return ls
+}
+
+// record adds the metric to the pending metrics, replacing the pending value of the same series.
+// The caller needs to hold s.mu.
+func (s *telemetryStore) record(m TelemetryMetric) {
+	key := telemetrySeriesKey(m.Name, m.Labels)
+
+	if i, ok := s.index[key]; ok {
+		s.pending[i] = m
+
+		return
+	}
+
+	s.index[key] = len(s.pending)
+	s.pending = append(s.pending, m)
+}
+
+func telemetrySeriesKey(name string, labels map[string]string) string {
+	return name + "{" + formatPrometheusLabels(labels) + "}"
+}
+
+// PushgatewaySink pushes metrics to the Prometheus Pushgateway.
+//
+// Each metric is pushed to the group identified by the job and all its labels, with a request per group.
+// Pushgateway replaces all the metrics of the same name in the group on every push, so grouping by all the labels
+// prevents concurrent rollouts of different rules, records, and target groups from overwriting each other's metrics.
+//
+// Counters are pushed as gauges of the counts in the latest Terraform run, as groups are kept in Pushgateway
+// across runs while counters start from zero in each run.
+type PushgatewaySink struct {
+	URL     string
+	Job     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (s *PushgatewaySink) Push(ms []TelemetryMetric) error {
+	job := s.Job
+	if job == "" {
+		job = DefaultTelemetryJob
+	}
+
+	var (
+		paths  []string
+		bodies = map[string]*bytes.Buffer{}
+	)
+
+	for _, m := range ms {
+		path := "/metrics/job@base64/" + base64.RawURLEncoding.EncodeToString([]byte(job))
+
+		var keys []string
+
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			// Pushgateway requires the padding-less base64 encoding of an empty value to be "="
+			v := base64.RawURLEncoding.EncodeToString([]byte(m.Labels[k]))
+			if v == "" {
+				v = "="
+			}
+
+			path += "/" + k + "@base64/" + v
+		}
+
+		body, ok := bodies[path]
+		if !ok {
+			body = &bytes.Buffer{}
+			bodies[path] = body
+			paths = append(paths, path)
+		}
+
+		fmt.Fprintf(body, "# TYPE %s %s\n%s %s\n", m.Name, TelemetryGauge, m.Name, strconv.FormatFloat(m.Value, 'g', -1, 64))
+	}
+
+	for _, path := range paths {
+		if err := postTelemetry(s.Client, strings.TrimSuffix(s.URL, "/")+path, "text/plain; version=0.0.4", s.Headers, bodies[path].Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OTLPSink pushes metrics to the OpenTelemetry collector via OTLP/HTTP with the JSON encoding, with a request per push.
+type OTLPSink struct {
+	// URL is the URL of the metrics endpoint, like http://localhost:4318/v1/metrics
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+}
+
+func (s *OTLPSink) Push(ms []TelemetryMetric) error {
+	var metrics []otlpMetric
+
+	for _, m := range ms {
+		var keys []string
+
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		dp := otlpDataPoint{
+			TimeUnixNano: strconv.FormatInt(m.Time.UnixNano(), 10),
+			AsDouble:     m.Value,
+		}
+
+		for _, k := range keys {
+			a := otlpAttribute{Key: k}
+			a.Value.StringValue = m.Labels[k]
+
+			dp.Attributes = append(dp.Attributes, a)
+		}
+
+		om := otlpMetric{Name: m.Name}
+
+		switch m.Type {
+		case TelemetryCounter:
+			// Counters start from zero in each Terraform run. The start time tells the backend about the reset.
+			dp.StartTimeUnixNano = strconv.FormatInt(m.Start.UnixNano(), 10)
+
+			om.Sum = &otlpSum{
+				DataPoints: []otlpDataPoint{dp},
+				// AGGREGATION_TEMPORALITY_CUMULATIVE
+				AggregationTemporality: 2,
+				IsMonotonic:            true,
+			}
+		default:
+			om.Gauge = &otlpGauge{DataPoints: []otlpDataPoint{dp}}
+		}
+
+		metrics = append(metrics, om)
+	}
+
+	serviceName := otlpAttribute{Key: "service.name"}
+	serviceName.Value.StringValue = "terraform-provider-eksctl"
+
+	req := map[string]interface{}{
+		"resourceMetrics": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttribute{serviceName},
+				},
+				"scopeMetrics": []interface{}{
+					map[string]interface{}{
+						"scope":   map[string]interface{}{"name": "courier"},
+						"metrics": metrics,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshaling otlp request: %w", err)
+	}
+
+	return postTelemetry(s.Client, s.URL, "application/json", s.Headers, body)
+}
+
+func postTelemetry(client *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error http.NewRequest: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(res.Body)
+
+		return fmt.Errorf("error response from %s: %s: %s", url, res.Status, string(b))
+	}
+
+	return nil
+}
+
+func formatPrometheusLabels(labels map[string]string) string {
+	var keys []string
+
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var pairs []string
+
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, strconv.Quote(labels[k])))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// ReadTelemetry reads the telemetry block at the key.
+// It returns nil when the block is absent, so that the rollout doesn't push any metrics.
+func ReadTelemetry(d api.Getter, key string) (*Telemetry, error) {
+	vs, _ := d.Get(key).([]interface{})
+	if len(vs) == 0 || vs[0] == nil {
+		return nil, nil
+	}
+
+	m := vs[0].(map[string]interface{})
+
+	pushgatewayURL, _ := m["pushgateway_url"].(string)
+	otlpEndpoint, _ := m["otlp_endpoint"].(string)
+
+	headers := map[string]string{}
+
+	if hs, ok := m["headers"].(map[string]interface{}); ok {
+		for k, v := range hs {
+			headers[k] = v.(string)
+		}
+	}
+
+	var sink TelemetrySink
+
+	switch {
+	case pushgatewayURL != "" && otlpEndpoint != "":
+		return nil, fmt.Errorf("%s: only one of pushgateway_url and otlp_endpoint can be set", key)
+	case pushgatewayURL != "":
+		job, _ := m["job"].(string)
+
+		sink = &PushgatewaySink{URL: pushgatewayURL, Job: job, Headers: headers}
+	case otlpEndpoint != "":
+		sink = &OTLPSink{URL: otlpEndpoint, Headers: headers}
+	default:
+		return nil, fmt.Errorf("%s: either pushgateway_url or otlp_endpoint is required", key)
+	}
+
+	return NewTelemetry(sink, nil), nil
+}
@@ -0,0 +1,217 @@
+package courier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedRequest struct {
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// newTelemetryReceiver starts the local HTTP server that records all the requests pushed to it
+func newTelemetryReceiver(t *testing.T) (*httptest.Server, func() []receivedRequest) {
+	var (
+		mu  sync.Mutex
+		rrs []receivedRequest
+	)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		rrs = append(rrs, receivedRequest{Path: r.URL.Path, Header: r.Header, Body: string(b)})
+		mu.Unlock()
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]receivedRequest{}, rrs...)
+	}
+}
+
+func b64(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestPushgatewaySink(t *testing.T) {
+	s, received := newTelemetryReceiver(t)
+
+	tel := NewTelemetry(&PushgatewaySink{URL: s.URL, Headers: map[string]string{"Authorization": "Bearer token"}}, map[string]string{
+		TelemetryLabelRule: "arn:rule/1",
+	})
+
+	tel.Gauge(TelemetryMetricTrafficWeight, 40, map[string]string{TelemetryLabelTargetGroup: "blue"})
+	tel.Gauge(TelemetryMetricRolloutDurationSeconds, 10, nil)
+	tel.Inc(TelemetryMetricRollbacks, nil)
+	tel.Inc(TelemetryMetricRollbacks, nil)
+
+	// Nothing is pushed until Flush
+	require.Empty(t, received())
+
+	tel.Flush()
+
+	rrs := received()
+	require.Len(t, rrs, 2)
+
+	assert.Equal(t, "/metrics/job@base64/"+b64(DefaultTelemetryJob)+"/rule@base64/"+b64("arn:rule/1")+"/target_group@base64/"+b64("blue"), rrs[0].Path)
+	assert.Equal(t, "# TYPE courier_traffic_weight gauge\ncourier_traffic_weight 40\n", rrs[0].Body)
+	assert.Equal(t, "Bearer token", rrs[0].Header.Get("Authorization"))
+
+	// Metrics of the same labels are pushed at once, and the counter is pushed as the gauge of the latest count
+	assert.Equal(t, "/metrics/job@base64/"+b64(DefaultTelemetryJob)+"/rule@base64/"+b64("arn:rule/1"), rrs[1].Path)
+	assert.Equal(t, "# TYPE courier_rollout_duration_seconds gauge\ncourier_rollout_duration_seconds 10\n"+
+		"# TYPE courier_rollbacks_total gauge\ncourier_rollbacks_total 2\n", rrs[1].Body)
+
+	// Nothing is pending after Flush
+	tel.Flush()
+
+	require.Len(t, received(), 2)
+}
+
+func TestOTLPSink(t *testing.T) {
+	s, received := newTelemetryReceiver(t)
+
+	tel := NewTelemetry(&OTLPSink{URL: s.URL + "/v1/metrics"}, map[string]string{TelemetryLabelRecord: "example.com"})
+
+	tel.Gauge(TelemetryMetricTrafficWeight, 60, map[string]string{TelemetryLabelSetIdentifier: "green"})
+	tel.Inc(TelemetryMetricRollouts, map[string]string{TelemetryLabelResult: "succeeded"})
+	tel.Flush()
+
+	rrs := received()
+	require.Len(t, rrs, 1)
+
+	assert.Equal(t, "/v1/metrics", rrs[0].Path)
+	assert.Equal(t, "application/json", rrs[0].Header.Get("Content-Type"))
+
+	var req struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []otlpMetric `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(rrs[0].Body), &req))
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+
+	gauge := metrics[0]
+	assert.Equal(t, TelemetryMetricTrafficWeight, gauge.Name)
+	require.NotNil(t, gauge.Gauge)
+	assert.Equal(t, 60.0, gauge.Gauge.DataPoints[0].AsDouble)
+	assert.Empty(t, gauge.Gauge.DataPoints[0].StartTimeUnixNano)
+
+	var attrs []string
+	for _, a := range gauge.Gauge.DataPoints[0].Attributes {
+		attrs = append(attrs, a.Key+"="+a.Value.StringValue)
+	}
+
+	assert.Equal(t, []string{"record=example.com", "set_identifier=green"}, attrs)
+
+	sum := metrics[1]
+	assert.Equal(t, TelemetryMetricRollouts, sum.Name)
+	require.NotNil(t, sum.Sum)
+	assert.True(t, sum.Sum.IsMonotonic)
+	assert.Equal(t, 2, sum.Sum.AggregationTemporality)
+	assert.Equal(t, 1.0, sum.Sum.DataPoints[0].AsDouble)
+	// The counter starts from zero in each run
+	assert.Equal(t, strconv.FormatInt(tel.store.start.UnixNano(), 10), sum.Sum.DataPoints[0].StartTimeUnixNano)
+}
+
+func TestRollout_Telemetry(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, received := newTelemetryReceiver(t)
+
+	clock := NewFakeClock(t0)
+	router := NewSimulatedRouter(clock, map[string]int{"blue": 100})
+
+	max := 0.1
+
+	r := &Rollout{
+		Router:           router,
+		From:             "blue",
+		To:               "green",
+		StepWeight:       50,
+		StepInterval:     time.Minute,
+		AnalysisInterval: time.Minute,
+		Analyzers: []*Analyzer{{Name: "errors", MetricProvider: metricProviderFunc(func(string) (float64, error) {
+			if clock.Now().Sub(t0) >= time.Minute {
+				return 0.5, nil
+			}
+
+			return 0, nil
+		}), Max: &max}},
+		Telemetry: NewTelemetry(&PushgatewaySink{URL: s.URL}, map[string]string{TelemetryLabelRule: "rule"}),
+		Clock:     clock,
+	}
+
+	require.Error(t, r.Run(context.Background()))
+
+	counts := map[string]int{}
+	values := map[string]string{}
+
+	for _, rr := range received() {
+		for _, line := range strings.Split(strings.TrimSpace(rr.Body), "\n") {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+
+			key := fields[0] + rr.Path
+			counts[key]++
+			values[key] = fields[1]
+		}
+	}
+
+	prefix := "/metrics/job@base64/" + b64(DefaultTelemetryJob)
+
+	weight := func(tg string) string {
+		return TelemetryMetricTrafficWeight + prefix + "/rule@base64/" + b64("rule") + "/target_group@base64/" + b64(tg)
+	}
+
+	// 50, then rolled back to 0
+	assert.Equal(t, 2, counts[weight("green")])
+	assert.Equal(t, "0", values[weight("green")])
+	assert.Equal(t, "100", values[weight("blue")])
+
+	analysis := prefix + "/analysis@base64/" + b64("errors") + "/rule@base64/" + b64("rule")
+
+	assert.Equal(t, "0.5", values[TelemetryMetricAnalysisValue+analysis])
+	assert.Equal(t, "1", values[TelemetryMetricAnalysisFailures+analysis])
+	assert.Equal(t, "1", values[TelemetryMetricRollbacks+prefix+"/rule@base64/"+b64("rule")])
+	assert.Equal(t, "1", values[TelemetryMetricRollouts+prefix+"/result@base64/"+b64("failed")+"/rule@base64/"+b64("rule")])
+	assert.Equal(t, "60", values[TelemetryMetricRolloutDurationSeconds+prefix+"/rule@base64/"+b64("rule")])
+}
+
+func TestTelemetry_Nil(t *testing.T) {
+	var tel *Telemetry
+
+	assert.Nil(t, tel.With(map[string]string{TelemetryLabelRule: "rule"}))
+
+	tel.Gauge(TelemetryMetricTrafficWeight, 1, nil)
+	tel.Inc(TelemetryMetricRollbacks, nil)
+	tel.Flush()
+}
@@ -1,18 +1,75 @@
 package provider
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
 )
 
+const (
+	KeyLogDir      = "log_dir"
+	KeyLogMaxAge   = "log_max_age"
+	KeyLogMaxFiles = "log_max_files"
+
+	KeyAWSRateLimits = "aws_rate_limits"
+
+	// EnvAWSRateLimits is the environment variable to override rate limits of AWS API calls, like `elasticloadbalancing=5,route53=2`.
+	// aws_rate_limits takes precedence over it.
+	EnvAWSRateLimits = "EKSCTL_PROVIDER_AWS_RATE_LIMITS"
+)
+
 type ProviderInstance struct {
 	AWSSession *session.Session
 }
 
 func providerConfigure() func(*schema.ResourceData) (interface{}, error) {
 	return func(d *schema.ResourceData) (interface{}, error) {
-		s := tfsdk.AWSSessionFromResourceData(&tfsdk.Resource{d})
+		s := tfsdk.AWSSessionFromResourceData(&tfsdk.Resource{ResourceData: d})
+
+		if v, ok := d.Get(KeyLogDir).(string); ok && v != "" {
+			sdk.SetLogDir(v)
+		}
+
+		maxAge := sdk.DefaultLogMaxAge
+
+		if v, ok := d.Get(KeyLogMaxAge).(string); ok && v != "" {
+			a, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s %q: %w", KeyLogMaxAge, v, err)
+			}
+
+			maxAge = a
+		}
+
+		sdk.SetLogRetention(maxAge, d.Get(KeyLogMaxFiles).(int))
+
+		if v := os.Getenv(EnvAWSRateLimits); v != "" {
+			limits, err := sdk.ParseRateLimits(v)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", EnvAWSRateLimits, err)
+			}
+
+			if err := sdk.SetRateLimits(limits); err != nil {
+				return nil, fmt.Errorf("%s: %w", EnvAWSRateLimits, err)
+			}
+		}
+
+		if vs, ok := d.Get(KeyAWSRateLimits).(map[string]interface{}); ok && len(vs) > 0 {
+			limits := map[string]float64{}
+
+			for k, v := range vs {
+				limits[k] = v.(float64)
+			}
+
+			if err := sdk.SetRateLimits(limits); err != nil {
+				return nil, fmt.Errorf("%s: %w", KeyAWSRateLimits, err)
+			}
+		}
 
 		return &ProviderInstance{
 			AWSSession: s,
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/xml/xmlutil"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccCourierALBStatus_read(t *testing.T) {
+	dataSourceName := "data.eksctl_courier_alb_status.the_rule"
+
+	albServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		op := string(body)
+
+		var (
+			params interface{}
+			result string
+		)
+
+		switch op {
+		case "Action=DescribeRules&ListenerArn=listener_arn&Version=2015-12-01":
+			params = &elbv2.DescribeRulesOutput{
+				Rules: []*elbv2.Rule{
+					{
+						RuleArn:  aws.String("rule_arn"),
+						Priority: aws.String("10"),
+						Conditions: []*elbv2.RuleCondition{
+							{
+								Field:            aws.String("host-header"),
+								HostHeaderConfig: &elbv2.HostHeaderConditionConfig{Values: aws.StringSlice([]string{"example.com"})},
+							},
+						},
+						Actions: []*elbv2.Action{
+							{
+								Type: aws.String("forward"),
+								ForwardConfig: &elbv2.ForwardActionConfig{
+									TargetGroups: []*elbv2.TargetGroupTuple{
+										{TargetGroupArn: aws.String("prev_arn"), Weight: aws.Int64(70)},
+										{TargetGroupArn: aws.String("next_arn"), Weight: aws.Int64(30)},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			result = "DescribeRulesResult"
+		case "Action=DescribeTargetHealth&TargetGroupArn=prev_arn&Version=2015-12-01",
+			"Action=DescribeTargetHealth&TargetGroupArn=next_arn&Version=2015-12-01":
+			state := "healthy"
+			if strings.Contains(op, "next_arn") {
+				state = "unhealthy"
+			}
+
+			params = &elbv2.DescribeTargetHealthOutput{
+				TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+					{TargetHealth: &elbv2.TargetHealth{State: aws.String(state)}},
+				},
+			}
+			result = "DescribeTargetHealthResult"
+		default:
+			t.Fatalf("Unexpected operation: %s", op)
+		}
+
+		var buf bytes.Buffer
+		if err := xmlutil.BuildXML(params, xml.NewEncoder(&buf)); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		w.Write([]byte("<" + result + ">" + buf.String() + "</" + result + ">"))
+	}))
+	defer albServer.Close()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "eksctl_courier_alb_status" "the_rule" {
+  address = "` + albServer.URL + `"
+
+  listener_arn = "listener_arn"
+  priority = 10
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "rule_arn", "rule_arn"),
+					resource.TestCheckResourceAttr(dataSourceName, "hosts.#", "1"),
+					resource.TestCheckResourceAttr(dataSourceName, "hosts.0", "example.com"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.#", "2"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.0.arn", "prev_arn"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.0.weight", "70"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.0.health.0.healthy", "1"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.1.arn", "next_arn"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.1.weight", "30"),
+					resource.TestCheckResourceAttr(dataSourceName, "target_group.1.health.0.unhealthy", "1"),
+				),
+			},
+		},
+	})
+}
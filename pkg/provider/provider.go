@@ -7,6 +7,7 @@ import (
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/resource/courier"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/resource/iamserviceaccount"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/resource/nodegroup"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
 )
 
@@ -17,6 +18,30 @@ func Provider() terraform.ResourceProvider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			tfsdk.KeyAssumeRole: tfsdk.SchemaAssumeRole(),
+			KeyLogDir: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("EKSCTL_PROVIDER_LOG_DIR", ""),
+				Description: "The directory to write the complete output of every eksctl command run by the provider. Defaults to `terraform-provider-eksctl/logs` under the system temp directory",
+			},
+			KeyLogMaxAge: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("EKSCTL_PROVIDER_LOG_MAX_AGE", sdk.DefaultLogMaxAge.String()),
+				Description: "Log files in log_dir older than this are deleted. \"0\" keeps them regardless of the age",
+			},
+			KeyLogMaxFiles: {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("EKSCTL_PROVIDER_LOG_MAX_FILES", sdk.DefaultLogMaxFiles),
+				Description: "The number of the latest log files kept in log_dir. 0 keeps all of them",
+			},
+			KeyAWSRateLimits: {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeFloat},
+				Description: "Requests per second allowed for each AWS service, keyed by the service name like `elasticloadbalancing` and `route53`. Overrides the defaults and " + EnvAWSRateLimits + ". Classic ELB and ELBV2 share the `elasticloadbalancing` limit",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"eksctl_cluster":                cluster.ResourceCluster(),
@@ -25,6 +50,11 @@ func Provider() terraform.ResourceProvider {
 			"eksctl_courier_alb":            courier.ResourceALB(),
 			"eksctl_courier_route53_record": courier.ResourceRoute53Record(),
 		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"eksctl_cluster":                cluster.DataSourceCluster(),
+			"eksctl_courier_alb_status":     courier.DataSourceALBStatus(),
+			"eksctl_courier_route53_status": courier.DataSourceRoute53Status(),
+		},
 		ConfigureFunc: providerConfigure(),
 	}
 }
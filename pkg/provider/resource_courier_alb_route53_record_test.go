@@ -6,6 +6,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/query/queryutil"
 	"github.com/aws/aws-sdk-go/private/protocol/xml/xmlutil"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/route53"
@@ -16,6 +17,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -109,9 +111,8 @@ func TestAccCourierRoute53Record_create(t *testing.T) {
 `
 
 	cwServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var req cloudwatch.GetMetricDataInput
-		if err := xmlutil.UnmarshalXML(&req, xml.NewDecoder(r.Body), "GetMetricDataInputRequest"); err != nil {
-			t.Fatalf("Unexpected error while unmarshalling XML: %v", err)
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Unexpected error while parsing form: %v", err)
 		}
 
 		var expected []*cloudwatch.MetricDataQuery
@@ -119,7 +120,20 @@ func TestAccCourierRoute53Record_create(t *testing.T) {
 			t.Fatalf("Unexpected error: %v", err)
 		}
 
-		if diff := cmp.Diff(expected, req.MetricDataQueries); diff != "" {
+		// CloudWatch API requests are form-encoded, so we encode the expected queries the same way for comparison
+		expectedForm := url.Values{}
+		if err := queryutil.Parse(expectedForm, &cloudwatch.GetMetricDataInput{MetricDataQueries: expected}, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		actualForm := url.Values{}
+		for k, v := range r.PostForm {
+			if strings.HasPrefix(k, "MetricDataQueries.") {
+				actualForm[k] = v
+			}
+		}
+
+		if diff := cmp.Diff(expectedForm, actualForm); diff != "" {
 			t.Fatalf("Unexpected diff: %s", diff)
 		}
 
@@ -170,7 +184,7 @@ func TestAccCourierRoute53Record_create(t *testing.T) {
 		switch r.RequestURI {
 		case "/2013-04-01/hostedzone/zone_id":
 			// courier_route53_record checks the existence of hosted zone for fail-fast
-		case "/2013-04-01/hostedzone/zone_id/rrset?name=record_name":
+		case "/2013-04-01/hostedzone/zone_id/rrset?name=record_name", "/2013-04-01/hostedzone/zone_id/rrset":
 			params := &route53.ListResourceRecordSetsOutput{
 				// TODO This is a workaround for progressived's bug that treats IsTruncated's meaning as it's oppposite
 				// Set this `aws.Bool(false)` once we fix that.
@@ -198,7 +212,7 @@ func TestAccCourierRoute53Record_create(t *testing.T) {
 			resBody = []byte("<ListResourceRecordSetsResult>")
 			resBody = append(resBody, buf.Bytes()...)
 			resBody = append(resBody, []byte("</ListResourceRecordSetsResult>")...)
-		case "/2013-04-01/hostedzone/zone_id/rrset":
+		case "/2013-04-01/hostedzone/zone_id/rrset/":
 			var req route53.ChangeResourceRecordSetsInput
 			if err := xmlutil.UnmarshalXML(&req, xml.NewDecoder(strings.NewReader(op)), "ChangeResourceRecordSetsRequest"); err != nil {
 				t.Fatalf("Unexpected error while unmarshalling XML: %v", err)
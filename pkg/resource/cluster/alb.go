@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"log"
 	"sort"
 	"strconv"
@@ -31,7 +32,7 @@ func planListenerChanges(cluster *Cluster, oldId, newId string) (ListenerStatuse
 		return nil, nil
 	}
 
-	svc := elbv2.New(AWSSessionFromCluster(cluster))
+	svc := sdk.NewClients(AWSSessionFromCluster(cluster)).ELBV2()
 
 	//oldClusterName := getClusterName(cluster, oldId)
 	//newClusterName := getClusterName(cluster, newId)
@@ -16,7 +16,7 @@ func doAttachAutoScalingGroupsToTargetGroups(ctx *sdk.Context, set *ClusterSet)
 		return nil
 	}
 
-	cfn := cloudformation.New(ctx.Session())
+	cfn := sdk.NewClients(ctx.Session()).CloudFormation()
 
 	var stackSummaries []*cloudformation.StackSummary
 
@@ -49,7 +49,7 @@ func doAttachAutoScalingGroupsToTargetGroups(ctx *sdk.Context, set *ClusterSet)
 
 	log.Printf("Finding stacks whose name is prefixd with %q from %d stack summaries", stackNamePrefix, len(stackSummaries))
 
-	asSvc := autoscaling.New(ctx.Session())
+	asSvc := sdk.NewClients(ctx.Session()).AutoScaling()
 
 	for _, s := range stackSummaries {
 
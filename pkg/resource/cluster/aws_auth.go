@@ -0,0 +1,313 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+)
+
+const (
+	KeyMapAccounts          = "map_accounts"
+	KeyAWSAuthMapAccounts   = "aws_auth_map_accounts"
+	KeyAWSAuthAuthoritative = "aws_auth_authoritative"
+)
+
+// changeGetter is implemented by both schema.ResourceData and schema.ResourceDiff
+type changeGetter interface {
+	api.Getter
+
+	GetChange(string) (interface{}, interface{})
+}
+
+type changeReadWrite interface {
+	api.ReadWrite
+
+	GetChange(string) (interface{}, interface{})
+}
+
+func iamIdentityMappingResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"iamarn": {
+				Required: true,
+				Type:     schema.TypeString,
+			},
+			"username": {
+				Required: true,
+				Type:     schema.TypeString,
+			},
+			"groups": {
+				Required: true,
+				Type:     schema.TypeList,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func newIAMIdentityMappingSet(mappings []interface{}) *schema.Set {
+	return schema.NewSet(schema.HashResource(iamIdentityMappingResource()), mappings)
+}
+
+func newAccountSet(accounts []string) *schema.Set {
+	var vs []interface{}
+
+	for _, a := range accounts {
+		vs = append(vs, a)
+	}
+
+	return schema.NewSet(schema.HashString, vs)
+}
+
+// isNodeIAMIdentityMapping returns true for mappings of node instance roles and Fargate pod execution roles.
+// They are managed by eksctl along with nodegroups and fargateprofiles, so the authoritative mode never deletes them.
+func isNodeIAMIdentityMapping(m map[string]interface{}) bool {
+	username, _ := m["username"].(string)
+
+	return strings.HasPrefix(username, "system:node:")
+}
+
+func withoutNodeIAMIdentityMappings(s *schema.Set) *schema.Set {
+	var vs []interface{}
+
+	for _, v := range s.List() {
+		if !isNodeIAMIdentityMapping(v.(map[string]interface{})) {
+			vs = append(vs, v)
+		}
+	}
+
+	return newIAMIdentityMappingSet(vs)
+}
+
+// awsAuth is the content of the aws-auth configmap reported by `eksctl get iamidentitymapping`.
+type awsAuth struct {
+	// Mappings are mapRoles and mapUsers entries, whose role or user ARN is stored in `iamarn`
+	Mappings []map[string]interface{}
+
+	// Accounts are mapAccounts entries
+	Accounts []string
+}
+
+func (a *awsAuth) mappingSet() *schema.Set {
+	var vs []interface{}
+
+	for _, m := range a.Mappings {
+		vs = append(vs, m)
+	}
+
+	return newIAMIdentityMappingSet(vs)
+}
+
+// awsAuthChanges is the set of iamidentitymappings to delete and create for updating aws-auth.
+type awsAuthChanges struct {
+	MappingsToCreate, MappingsToDelete *schema.Set
+	AccountsToCreate, AccountsToDelete *schema.Set
+}
+
+func diffAWSAuth(oldMappings, newMappings, oldAccounts, newAccounts *schema.Set) awsAuthChanges {
+	return awsAuthChanges{
+		MappingsToCreate: newMappings.Difference(oldMappings),
+		MappingsToDelete: oldMappings.Difference(newMappings),
+		AccountsToCreate: newAccounts.Difference(oldAccounts),
+		AccountsToDelete: oldAccounts.Difference(newAccounts),
+	}
+}
+
+// plannedAWSAuthChanges returns the changes to aws-auth that the next apply makes.
+// The changes are computed from the previous and the new iam_identity_mapping and map_accounts by default.
+// In the authoritative mode, the previous values are ones read from the live aws-auth instead,
+// so that entries added out of band are deleted.
+func plannedAWSAuthChanges(d changeGetter) awsAuthChanges {
+	oldMappings, newMappings := d.GetChange(KeyIAMIdentityMapping)
+	oldAccounts, newAccounts := d.GetChange(KeyMapAccounts)
+
+	if isAWSAuthAuthoritative(d) {
+		oldMappings, _ = d.GetChange(KeyAWSAuthConfigMap)
+		oldMappings = withoutNodeIAMIdentityMappings(oldMappings.(*schema.Set))
+		oldAccounts, _ = d.GetChange(KeyAWSAuthMapAccounts)
+	}
+
+	return diffAWSAuth(oldMappings.(*schema.Set), newMappings.(*schema.Set), oldAccounts.(*schema.Set), newAccounts.(*schema.Set))
+}
+
+func isAWSAuthAuthoritative(d api.Getter) bool {
+	v, _ := d.Get(KeyAWSAuthAuthoritative).(bool)
+
+	return v
+}
+
+// planAWSAuth sets the desired aws-auth to aws_auth_configmap and aws_auth_map_accounts in the authoritative mode,
+// so that the plan shows a diff when the live aws-auth read on refresh differs from the desired one.
+func planAWSAuth(d *schema.ResourceDiff) error {
+	if d.Id() == "" || !isAWSAuthAuthoritative(d) {
+		return nil
+	}
+
+	if !d.NewValueKnown(KeyIAMIdentityMapping) || !d.NewValueKnown(KeyMapAccounts) {
+		return setNewComputed(d, KeyAWSAuthConfigMap, KeyAWSAuthMapAccounts)
+	}
+
+	changes := plannedAWSAuthChanges(d)
+
+	if changes.MappingsToCreate.Len() > 0 || changes.MappingsToDelete.Len() > 0 {
+		live := d.Get(KeyAWSAuthConfigMap).(*schema.Set)
+		desired := d.Get(KeyIAMIdentityMapping).(*schema.Set).List()
+
+		for _, v := range live.List() {
+			if isNodeIAMIdentityMapping(v.(map[string]interface{})) {
+				desired = append(desired, v)
+			}
+		}
+
+		if err := d.SetNew(KeyAWSAuthConfigMap, desired); err != nil {
+			return fmt.Errorf("setting %s: %w", KeyAWSAuthConfigMap, err)
+		}
+	}
+
+	if changes.AccountsToCreate.Len() > 0 || changes.AccountsToDelete.Len() > 0 {
+		if err := d.SetNew(KeyAWSAuthMapAccounts, d.Get(KeyMapAccounts).(*schema.Set).List()); err != nil {
+			return fmt.Errorf("setting %s: %w", KeyAWSAuthMapAccounts, err)
+		}
+	}
+
+	return nil
+}
+
+// applyAWSAuth updates aws-auth according to iam_identity_mapping and map_accounts, and then sets
+// the resulting aws-auth to aws_auth_configmap and aws_auth_map_accounts.
+// In the authoritative mode, the live aws-auth is read again so that entries added after the refresh are deleted as well.
+func applyAWSAuth(ctx *sdk.Context, d changeReadWrite, cluster *Cluster) error {
+	changes := plannedAWSAuthChanges(d)
+
+	if isAWSAuthAuthoritative(d) {
+		live, err := runGetIAMIdentityMapping(ctx, d, cluster)
+		if err != nil {
+			return fmt.Errorf("can not get iamidentitymapping from eks cluster: %w", err)
+		}
+
+		changes = diffAWSAuth(
+			withoutNodeIAMIdentityMappings(live.mappingSet()),
+			d.Get(KeyIAMIdentityMapping).(*schema.Set),
+			newAccountSet(live.Accounts),
+			d.Get(KeyMapAccounts).(*schema.Set),
+		)
+	}
+
+	// Mappings are created first and then stale ones are deleted, so that updating the mapping of an ARN, like
+	// the one Terraform runs as, never leaves aws-auth without a mapping for the ARN
+	if err := runCreateIAMIdentityMapping(ctx, d, changes.MappingsToCreate, cluster); err != nil {
+		return fmt.Errorf("CreateIAMIdentityMapping Error: %v", err)
+	}
+
+	if err := runCreateAccountIAMIdentityMapping(ctx, d, changes.AccountsToCreate, cluster); err != nil {
+		return fmt.Errorf("CreateIAMIdentityMapping Error: %v", err)
+	}
+
+	if changes.MappingsToDelete.Len() > 0 {
+		live, err := runGetIAMIdentityMapping(ctx, d, cluster)
+		if err != nil {
+			return fmt.Errorf("can not get iamidentitymapping from eks cluster: %w", err)
+		}
+
+		arns := iamIdentityMappingDeletions(live.Mappings, changes.MappingsToDelete, d.Get(KeyIAMIdentityMapping).(*schema.Set))
+
+		if err := runDeleteIAMIdentityMapping(ctx, d, arns, cluster); err != nil {
+			return fmt.Errorf("DeleteIAMIdentityMapping Error: %v", err)
+		}
+	}
+
+	if err := runDeleteAccountIAMIdentityMapping(ctx, d, changes.AccountsToDelete, cluster); err != nil {
+		return fmt.Errorf("DeleteIAMIdentityMapping Error: %v", err)
+	}
+
+	live, err := runGetIAMIdentityMapping(ctx, d, cluster)
+	if err != nil {
+		return fmt.Errorf("can not get iamidentitymapping from eks cluster: %w", err)
+	}
+
+	return setAWSAuth(d, live)
+}
+
+// iamIdentityMappingDeletions returns the ARNs to run `eksctl delete iamidentitymapping --arn` with for deleting
+// the mappings in toDelete from the live aws-auth, in the order to run.
+// The command deletes the first mapping of the ARN in aws-auth, so a mapping is deleted only when all the mappings of
+// the same ARN before it are deleted as well. Otherwise the command could delete a declared mapping of the same ARN,
+// like the one just created, and the mapping is left with a warning.
+func iamIdentityMappingDeletions(live []map[string]interface{}, toDelete, declared *schema.Set) []string {
+	var arns []string
+
+	// kept is the set of ARNs whose first remaining mapping is kept
+	kept := map[string]bool{}
+
+	for _, m := range live {
+		arn := m["iamarn"].(string)
+
+		if !toDelete.Contains(m) || declared.Contains(m) {
+			kept[arn] = true
+
+			continue
+		}
+
+		if kept[arn] {
+			log.Printf("[WARN] skipped deleting iamidentitymapping %v, as deleting by the ARN would delete another mapping of the ARN", m)
+
+			continue
+		}
+
+		arns = append(arns, arn)
+	}
+
+	return arns
+}
+
+func setAWSAuth(d api.ReadWrite, a *awsAuth) error {
+	if err := d.Set(KeyAWSAuthConfigMap, a.Mappings); err != nil {
+		return fmt.Errorf("set aws-auth-configmap from iamidentitymapping : %w", err)
+	}
+
+	if err := d.Set(KeyAWSAuthMapAccounts, a.Accounts); err != nil {
+		return fmt.Errorf("setting %s: %w", KeyAWSAuthMapAccounts, err)
+	}
+
+	return nil
+}
+
+func runCreateAccountIAMIdentityMapping(ctx *sdk.Context, d api.Getter, s *schema.Set, cluster *Cluster) error {
+	return runAccountIAMIdentityMapping(ctx, d, "create", s, cluster)
+}
+
+func runDeleteAccountIAMIdentityMapping(ctx *sdk.Context, d api.Getter, s *schema.Set, cluster *Cluster) error {
+	return runAccountIAMIdentityMapping(ctx, d, "delete", s, cluster)
+}
+
+func runAccountIAMIdentityMapping(ctx *sdk.Context, d api.Getter, verb string, s *schema.Set, cluster *Cluster) error {
+	var accounts []string
+
+	for _, v := range s.List() {
+		accounts = append(accounts, v.(string))
+	}
+
+	sort.Strings(accounts)
+
+	for _, account := range accounts {
+		cmd, err := newEksctlCommandFromResourceWithRegionAndProfile(d, verb, "iamidentitymapping", "--cluster", cluster.Name, "--account", account)
+		if err != nil {
+			return fmt.Errorf("creating %s iamidentitymapping command: %w", verb, err)
+		}
+
+		if _, err := ctx.Run(cmd); err != nil {
+			return fmt.Errorf("running %s iamidentitymapping command for account %s: %w", verb, account, err)
+		}
+
+		log.Printf("[INFO] %sd iamidentitymapping for account %s", verb, account)
+	}
+
+	return nil
+}
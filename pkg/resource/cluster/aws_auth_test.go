@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChangeGetter struct {
+	old, new map[string]interface{}
+}
+
+func (g fakeChangeGetter) Get(k string) interface{} {
+	return g.new[k]
+}
+
+func (g fakeChangeGetter) GetChange(k string) (interface{}, interface{}) {
+	return g.old[k], g.new[k]
+}
+
+func mapping(arn, username string, groups ...string) map[string]interface{} {
+	var gs []interface{}
+
+	for _, g := range groups {
+		gs = append(gs, g)
+	}
+
+	return map[string]interface{}{"iamarn": arn, "username": username, "groups": gs}
+}
+
+func mappingARNs(s *schema.Set) []string {
+	var arns []string
+
+	for _, v := range s.List() {
+		arns = append(arns, v.(map[string]interface{})["iamarn"].(string))
+	}
+
+	return arns
+}
+
+func TestPlannedAWSAuthChanges(t *testing.T) {
+	admin := mapping("arn:aws:iam::123456789012:role/admin", "admin", "system:masters")
+	dev := mapping("arn:aws:iam::123456789012:role/dev", "dev", "dev")
+	outOfBand := mapping("arn:aws:iam::123456789012:role/oob", "oob", "system:masters")
+	node := mapping("arn:aws:iam::123456789012:role/node", "system:node:{{EC2PrivateDNSName}}", "system:bootstrappers", "system:nodes")
+
+	g := fakeChangeGetter{
+		old: map[string]interface{}{
+			KeyIAMIdentityMapping: newIAMIdentityMappingSet([]interface{}{admin}),
+			KeyMapAccounts:        newAccountSet(nil),
+			KeyAWSAuthConfigMap:   newIAMIdentityMappingSet([]interface{}{admin, outOfBand, node}),
+			KeyAWSAuthMapAccounts: newAccountSet([]string{"111111111111"}),
+		},
+		new: map[string]interface{}{
+			KeyIAMIdentityMapping:   newIAMIdentityMappingSet([]interface{}{admin, dev}),
+			KeyMapAccounts:          newAccountSet([]string{"222222222222"}),
+			KeyAWSAuthAuthoritative: false,
+		},
+	}
+
+	c := plannedAWSAuthChanges(g)
+	assert.Equal(t, []string{"arn:aws:iam::123456789012:role/dev"}, mappingARNs(c.MappingsToCreate))
+	assert.Empty(t, mappingARNs(c.MappingsToDelete))
+	assert.Equal(t, []interface{}{"222222222222"}, c.AccountsToCreate.List())
+	assert.Empty(t, c.AccountsToDelete.List())
+
+	// The authoritative mode deletes entries added out of band, except node ones
+	g.new[KeyAWSAuthAuthoritative] = true
+
+	c = plannedAWSAuthChanges(g)
+	assert.Equal(t, []string{"arn:aws:iam::123456789012:role/dev"}, mappingARNs(c.MappingsToCreate))
+	assert.Equal(t, []string{"arn:aws:iam::123456789012:role/oob"}, mappingARNs(c.MappingsToDelete))
+	assert.Equal(t, []interface{}{"222222222222"}, c.AccountsToCreate.List())
+	assert.Equal(t, []interface{}{"111111111111"}, c.AccountsToDelete.List())
+}
+
+func TestIAMIdentityMappingDeletions(t *testing.T) {
+	adminARN := "arn:aws:iam::123456789012:role/admin"
+	oldAdmin := mapping(adminARN, "admin", "system:masters")
+	newAdmin := mapping(adminARN, "admin", "system:masters", "dev")
+	oob := mapping("arn:aws:iam::123456789012:role/oob", "oob", "system:masters")
+	node := mapping("arn:aws:iam::123456789012:role/node", "system:node:{{EC2PrivateDNSName}}", "system:bootstrappers", "system:nodes")
+
+	declared := newIAMIdentityMappingSet([]interface{}{newAdmin})
+	toDelete := newIAMIdentityMappingSet([]interface{}{oldAdmin, oob})
+
+	// The updated mapping was created after the old one, so deleting by the ARN deletes the old one
+	assert.Equal(t,
+		[]string{adminARN, "arn:aws:iam::123456789012:role/oob"},
+		iamIdentityMappingDeletions([]map[string]interface{}{oldAdmin, node, oob, newAdmin}, toDelete, declared),
+	)
+
+	// The old mapping is already missing, so deleting by the ARN would delete the declared one
+	assert.Equal(t,
+		[]string{"arn:aws:iam::123456789012:role/oob"},
+		iamIdentityMappingDeletions([]map[string]interface{}{newAdmin, oob}, toDelete, declared),
+	)
+
+	// The declared mapping precedes the old one
+	assert.Empty(t, iamIdentityMappingDeletions([]map[string]interface{}{newAdmin, oldAdmin}, toDelete, declared))
+}
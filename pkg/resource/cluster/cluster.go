@@ -36,6 +36,8 @@ const KeyMetrics = "metrics"
 const KeyDrainNodeGroups = "drain_node_groups"
 const KeyIAMIdentityMapping = "iam_identity_mapping"
 const KeyAWSAuthConfigMap = "aws_auth_configmap"
+const KeyClusterConfig = "cluster_config"
+const KeyUpdateConcurrency = "update_concurrency"
 const (
 	KeyTargetGroupARNs  = "target_group_arns"
 	KeyOIDCProviderURL  = "oidc_provider_url"
@@ -46,8 +48,6 @@ const (
 const DefaultAPIVersion = "eksctl.io/v1alpha5"
 const DefaultVersion = "1.16"
 
-var ValidDeleteK8sResourceKinds = []string{"deployment", "deploy", "pod", "service", "svc", "statefulset", "job"}
-
 type CheckPodsReadiness struct {
 	namespace  string
 	labels     map[string]string
@@ -76,6 +76,8 @@ type Cluster struct {
 
 	CheckPodsReadinessConfigs []CheckPodsReadiness
 
+	ReadinessChecks []ReadinessCheck
+
 	DeleteKubernetesResourcesBeforeDestroy []DeleteKubernetesResource
 
 	PublicSubnetIDs  []string
@@ -84,6 +86,12 @@ type Cluster struct {
 	TargetGroupARNs  []string
 	Metrics          []courier.Metric
 	AssumeRoleConfig *sdk.AssumeRoleConfig
+
+	NodeGroups        []NodeGroupConfig
+	ManagedNodeGroups []NodeGroupConfig
+
+	// UpdateConcurrency is the maximum number of update tasks run concurrently
+	UpdateConcurrency int
 }
 
 func (c Cluster) IAMWithOIDCEnabled() (bool, error) {
@@ -112,10 +120,16 @@ func (c Cluster) GitOpsEnabled() (bool, error) {
 	return false, nil
 }
 
+// DeleteKubernetesResource selects objects to delete before destroying the cluster, by name or selectors.
 type DeleteKubernetesResource struct {
-	Namespace string
-	Name      string
-	Kind      string
+	Kind          string
+	Namespace     string
+	AllNamespaces bool
+	Name          string
+	LabelSelector string
+	FieldSelector string
+
+	Timeout time.Duration
 }
 
 type EksctlClusterConfig struct {
@@ -164,6 +178,38 @@ type NodeGroup struct {
 	Rest            map[string]interface{} `yaml:",inline"`
 }
 
+// generateSeedClusterConfig returns the cluster.yaml composed of the metadata and spec, which is empty
+// when neither `spec` nor nodegroup blocks are given.
+func generateSeedClusterConfig(a *Cluster, clusterName ClusterName, tagsJson string, spec map[string]interface{}) ([]byte, error) {
+	var specStr string
+
+	if len(spec) > 0 {
+		var buf bytes.Buffer
+
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(spec); err != nil {
+			return nil, err
+		}
+
+		specStr = buf.String()
+	}
+
+	return []byte(fmt.Sprintf(`
+apiVersion: %s
+kind: ClusterConfig
+
+metadata:
+  name: %q
+  region: %q
+  version: %q
+  tags: %s
+
+%s
+`, a.APIVersion, clusterName, a.Region, a.Version, tagsJson, specStr)), nil
+}
+
 type Manager struct {
 	DisableClusterNameSuffix bool
 }
@@ -180,6 +226,10 @@ func (m *Manager) PrepareClusterSet(d api.UniqueResourceGetter, optNewId ...stri
 		return nil, fmt.Errorf("parsing used-provided cluster.yaml: %w: INPUT:\n%s", err, a.Spec)
 	}
 
+	if err := mergeNodeGroups(spec, a.NodeGroups, a.ManagedNodeGroups); err != nil {
+		return nil, err
+	}
+
 	if a.VPCID != "" {
 		var set bool
 
@@ -202,20 +252,6 @@ func (m *Manager) PrepareClusterSet(d api.UniqueResourceGetter, optNewId ...stri
 		}
 	}
 
-	var specStr string
-	{
-		var buf bytes.Buffer
-
-		enc := yaml.NewEncoder(&buf)
-		enc.SetIndent(2)
-
-		if err := enc.Encode(spec); err != nil {
-			return nil, err
-		}
-
-		specStr = buf.String()
-	}
-
 	var id string
 	var newId string
 
@@ -249,18 +285,10 @@ func (m *Manager) PrepareClusterSet(d api.UniqueResourceGetter, optNewId ...stri
 		}
 	}
 
-	seedClusterConfig := []byte(fmt.Sprintf(`
-apiVersion: %s
-kind: ClusterConfig
-
-metadata:
-  name: %q
-  region: %q
-  version: %q
-  tags: %s
-
-%s
-`, a.APIVersion, clusterName, a.Region, a.Version, tagsJson, specStr))
+	seedClusterConfig, err := generateSeedClusterConfig(a, clusterName, tagsJson, spec)
+	if err != nil {
+		return nil, err
+	}
 
 	c := clusterConfigNew()
 
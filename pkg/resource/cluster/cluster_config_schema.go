@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaFS contains the JSON schemas of eksctl's ClusterConfig, named after api_version like `eksctl.io_v1alpha5.json`.
+//
+//go:embed schema/*.json
+var schemaFS embed.FS
+
+// jsonSchema is the subset of JSON Schema used by the embedded ClusterConfig schemas.
+//
+// Unlike JSON Schema, an object with `properties` and without `additionalProperties` disallows unknown fields.
+// Use `"additionalProperties": {}` to allow any fields.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 interface{}            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	Required             []string               `json:"required"`
+	Definitions          map[string]*jsonSchema `json:"definitions"`
+}
+
+// ValidationError is an error in the cluster config found by the schema, located by the path like `nodeGroups[1].instanceType`.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+func loadClusterConfigSchema(apiVersion string) (*jsonSchema, error) {
+	bs, err := schemaFS.ReadFile("schema/" + strings.ReplaceAll(apiVersion, "/", "_") + ".json")
+	if err != nil {
+		return nil, nil
+	}
+
+	var s jsonSchema
+
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return nil, fmt.Errorf("bug: parsing embedded schema for %s: %w", apiVersion, err)
+	}
+
+	return &s, nil
+}
+
+// ValidateClusterConfig validates the eksctl cluster config YAML against the schema for the api version.
+// It returns warnings for unknown fields, enum values, and mistyped values of optional fields, as the schema is
+// a hand-written subset of eksctl's that may lag behind eksctl, and errors for missing and mistyped required fields.
+//
+// Nothing is validated when the provider has no schema for the api version.
+func ValidateClusterConfig(apiVersion string, config []byte) ([]string, []error) {
+	s, err := loadClusterConfigSchema(apiVersion)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	if s == nil {
+		return []string{fmt.Sprintf("skipped validating the cluster config: no schema for api_version %q", apiVersion)}, nil
+	}
+
+	var doc interface{}
+
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, []error{fmt.Errorf("parsing cluster config: %w", err)}
+	}
+
+	v := &schemaValidator{root: s}
+
+	v.validate("", doc, s, true)
+
+	return v.warnings, v.errs
+}
+
+type schemaValidator struct {
+	root *jsonSchema
+
+	warnings []string
+	errs     []error
+}
+
+func (v *schemaValidator) errorf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) warnf(path, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, (&ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}).Error())
+}
+
+func (v *schemaValidator) resolve(s *jsonSchema) (*jsonSchema, error) {
+	for s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+
+		def, ok := v.root.Definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("bug: undefined schema ref %q", s.Ref)
+		}
+
+		s = def
+	}
+
+	return s, nil
+}
+
+// validate validates the value at the path. required is true for the root and fields required by their objects,
+// whose mistyped values are errors. Mistyped values of other fields are warnings.
+func (v *schemaValidator) validate(path string, value interface{}, s *jsonSchema, required bool) {
+	s, err := v.resolve(s)
+	if err != nil {
+		v.errorf(path, "%v", err)
+		return
+	}
+
+	// A null is what eksctl sees for an empty field like `nodeGroups:`, which it treats as the zero value.
+	if value == nil {
+		return
+	}
+
+	if types := schemaTypes(s); len(types) > 0 {
+		t := jsonTypeOf(value)
+
+		var ok bool
+
+		for _, want := range types {
+			if want == t || (want == "number" && t == "integer") {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			if required {
+				v.errorf(path, "expected %s", strings.Join(types, " or "))
+			} else {
+				v.warnf(path, "expected %s", strings.Join(types, " or "))
+			}
+
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		var ok bool
+
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			var vs []string
+
+			for _, e := range s.Enum {
+				vs = append(vs, fmt.Sprintf("%q", fmt.Sprint(e)))
+			}
+
+			v.warnf(path, "expected one of %s: got %q", strings.Join(vs, ", "), fmt.Sprint(value))
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(path, typed, s)
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range typed {
+				v.validate(fmt.Sprintf("%s[%d]", path, i), item, s.Items, required)
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(path string, obj map[string]interface{}, s *jsonSchema) {
+	for _, r := range s.Required {
+		if !hasField(obj, r) {
+			v.errorf(joinPath(path, r), "required")
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+
+	for k := range obj {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := joinPath(path, k)
+
+		required := isRequired(s, k)
+
+		if ps, ok := lookupProperty(s.Properties, k); ok {
+			v.validate(p, obj[k], ps, required)
+		} else if s.AdditionalProperties != nil {
+			v.validate(p, obj[k], s.AdditionalProperties, required)
+		} else if s.Properties != nil {
+			v.warnf(p, "unknown field")
+		}
+	}
+}
+
+// lookupProperty finds the schema of the field, falling back to a case-insensitive match.
+// eksctl decodes the config with encoding/json, which matches field names case-insensitively.
+func lookupProperty(props map[string]*jsonSchema, key string) (*jsonSchema, bool) {
+	if s, ok := props[key]; ok {
+		return s, true
+	}
+
+	for k, s := range props {
+		if strings.EqualFold(k, key) {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func isRequired(s *jsonSchema, key string) bool {
+	for _, r := range s.Required {
+		if strings.EqualFold(r, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasField(obj map[string]interface{}, key string) bool {
+	for k := range obj {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func schemaTypes(s *jsonSchema) []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+
+		for _, v := range t {
+			types = append(types, v.(string))
+		}
+
+		return types
+	}
+
+	return nil
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if typed == math.Trunc(typed) {
+			return "integer"
+		}
+
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClusterConfig(t *testing.T) {
+	warnings, errs := ValidateClusterConfig(DefaultAPIVersion, []byte(`
+apiVersion: eksctl.io/v1alpha5
+kind: ClusterConfig
+metadata:
+  name: mycluster
+  region: us-east-2
+vpc:
+  id: ""
+  subnets:
+    public:
+      us-east-2a:
+        id: subnet-1
+iam:
+  withOIDC: true
+nodeGroups:
+- name: ng1
+  instanceType: m5.large
+  targetGroupARNS: []
+- name: ng2
+  instanceType: 1
+  desiredCapacity: "1"
+  volumeType: gp9
+  foo: bar
+- name: 3
+managedNodeGroups:
+- instanceType: m5.large
+  taints:
+  - key: dedicated
+    effect: NoSchedule
+`))
+
+	var msgs []string
+
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	assert.Equal(t, []string{
+		`managedNodeGroups[0].name: required`,
+		`nodeGroups[2].name: expected string`,
+	}, msgs)
+
+	// Mistyped values of optional fields are warnings, as the schema may lag behind eksctl
+	assert.Equal(t, []string{
+		`nodeGroups[1].desiredCapacity: expected integer`,
+		`nodeGroups[1].foo: unknown field`,
+		`nodeGroups[1].instanceType: expected string`,
+		`nodeGroups[1].volumeType: expected one of "gp2", "gp3", "io1", "io2", "sc1", "st1": got "gp9"`,
+	}, warnings)
+}
+
+func TestValidateClusterConfig_UnknownAPIVersion(t *testing.T) {
+	warnings, errs := ValidateClusterConfig("eksctl.io/v1alpha99", []byte(`foo: bar`))
+
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{`skipped validating the cluster config: no schema for api_version "eksctl.io/v1alpha99"`}, warnings)
+}
+
+func TestValidateClusterConfig_NewerValues(t *testing.T) {
+	warnings, errs := ValidateClusterConfig(DefaultAPIVersion, []byte(`
+apiVersion: eksctl.io/v1alpha5
+kind: ClusterConfig
+metadata:
+  name: mycluster
+  region: us-east-2
+managedNodeGroups:
+- name: ng1
+  amiFamily: AmazonLinux2023
+  volumeType: io2
+- name: ng2
+  amiFamily: Ubuntu2204
+`))
+
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateClusterConfig_UndefinedRef(t *testing.T) {
+	v := &schemaValidator{root: &jsonSchema{}}
+
+	v.validate("nodeGroups", []interface{}{}, &jsonSchema{Ref: "#/definitions/nodeGroups"}, false)
+
+	assert.Empty(t, v.warnings)
+	assert.Equal(t, []error{&ValidationError{Path: "nodeGroups", Message: `bug: undefined schema ref "#/definitions/nodeGroups"`}}, v.errs)
+}
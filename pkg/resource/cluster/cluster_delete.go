@@ -26,7 +26,7 @@ func (m *Manager) deleteCluster(d *schema.ResourceData) error {
 
 	ctx := mustNewContext(cluster)
 
-	if err := doDeleteKubernetesResourcesBeforeDestroy(ctx, cluster, d.Id()); err != nil {
+	if err := doDeleteKubernetesResourcesBeforeDestroy(cluster, string(set.ClusterName)); err != nil {
 		return err
 	}
 
@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const KeyDrift = "drift"
+
+// detectDrift returns the differences between nodegroups, iamserviceaccounts and fargateprofiles declared in the
+// cluster config and ones that exist in the cluster, like ones deleted or added out of band.
+// Fields of the live state that couldn't be read are not compared.
+func detectDrift(config *clusterConfigSummary, live liveClusterState) []string {
+	var drift []string
+
+	add := func(format string, args ...interface{}) {
+		drift = append(drift, fmt.Sprintf(format, args...))
+	}
+
+	if live.NodeGroups != nil {
+		for _, n := range missingNames(config.nodeGroupNames(), live.NodeGroups) {
+			add("nodegroup %s is declared but missing in the cluster", n)
+		}
+
+		for _, n := range missingNames(live.NodeGroups, config.nodeGroupNames()) {
+			add("nodegroup %s exists in the cluster but is not declared", n)
+		}
+	}
+
+	// iamserviceaccounts are managed only when iam.withOIDC is enabled
+	if live.ServiceAccounts != nil && config.IAM.WithOIDC {
+		for _, n := range missingNames(config.serviceAccountNames(), live.ServiceAccounts) {
+			add("iamserviceaccount %s is declared but missing in the cluster", n)
+		}
+
+		for _, n := range missingNames(live.ServiceAccounts, config.serviceAccountNames()) {
+			add("iamserviceaccount %s exists in the cluster but is not declared", n)
+		}
+	}
+
+	if live.FargateProfiles != nil {
+		for _, n := range missingNames(config.fargateProfileNames(), live.FargateProfiles) {
+			add("fargateprofile %s is declared but missing in the cluster", n)
+		}
+
+		// Undeclared fargateprofiles are not reported as drift, as update doesn't delete them
+		// and reporting them would result in a permanent diff.
+		for _, n := range missingNames(live.FargateProfiles, config.fargateProfileNames()) {
+			log.Printf("[WARN] fargateprofile %s exists in the cluster %s but is not declared", n, config.Metadata.Name)
+		}
+	}
+
+	return drift
+}
+
+// planDriftReconciliation clears the drift detected on read, so that the plan shows a diff on drift
+// and the apply runs an update that reconciles the cluster with the cluster config.
+func planDriftReconciliation(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	v, _ := d.Get(KeyDrift).([]interface{})
+	if len(v) == 0 {
+		return nil
+	}
+
+	log.Printf("[INFO] planning an update to reconcile drift: %v", v)
+
+	return d.SetNew(KeyDrift, []interface{}{})
+}
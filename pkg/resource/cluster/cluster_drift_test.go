@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDrift(t *testing.T) {
+	config, err := parseClusterConfigSummary(`
+metadata:
+  name: foo
+nodeGroups:
+- name: ng1
+managedNodeGroups:
+- name: mng1
+iam:
+  withOIDC: true
+  serviceAccounts:
+  - metadata:
+      name: sa1
+      namespace: kube-system
+fargateProfiles:
+- name: fp1
+`)
+	require.NoError(t, err)
+
+	assert.Empty(t, detectDrift(config, liveClusterState{
+		NodeGroups:      []string{"ng1", "mng1"},
+		ServiceAccounts: []string{"kube-system/sa1"},
+		FargateProfiles: []string{"fp1", "undeclared"},
+	}))
+
+	assert.Equal(t, []string{
+		"nodegroup ng1 is declared but missing in the cluster",
+		"nodegroup ng2 exists in the cluster but is not declared",
+		"iamserviceaccount kube-system/sa1 is declared but missing in the cluster",
+		"fargateprofile fp1 is declared but missing in the cluster",
+	}, detectDrift(config, liveClusterState{
+		NodeGroups:      []string{"mng1", "ng2"},
+		ServiceAccounts: []string{},
+		FargateProfiles: []string{},
+	}))
+
+	// Live state that couldn't be read is not compared
+	assert.Empty(t, detectDrift(config, liveClusterState{}))
+}
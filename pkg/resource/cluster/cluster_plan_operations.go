@@ -0,0 +1,416 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"gopkg.in/yaml.v3"
+)
+
+const KeyPlannedOperations = "planned_operations"
+
+// clusterConfigSummary is the part of the eksctl cluster config that determines which operations an update runs.
+type clusterConfigSummary struct {
+	Metadata struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	} `yaml:"metadata"`
+	NodeGroups []struct {
+		Name string `yaml:"name"`
+	} `yaml:"nodeGroups"`
+	ManagedNodeGroups []struct {
+		Name string `yaml:"name"`
+	} `yaml:"managedNodeGroups"`
+	IAM struct {
+		WithOIDC        bool `yaml:"withOIDC"`
+		ServiceAccounts []struct {
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		} `yaml:"serviceAccounts"`
+	} `yaml:"iam"`
+	FargateProfiles []struct {
+		Name string `yaml:"name"`
+	} `yaml:"fargateProfiles"`
+	Git map[string]interface{} `yaml:"git"`
+}
+
+func parseClusterConfigSummary(config string) (*clusterConfigSummary, error) {
+	var s clusterConfigSummary
+
+	if err := yaml.Unmarshal([]byte(config), &s); err != nil {
+		return nil, fmt.Errorf("parsing cluster config: %w", err)
+	}
+
+	return &s, nil
+}
+
+func (s *clusterConfigSummary) nodeGroupNames() []string {
+	var names []string
+
+	for _, ng := range s.NodeGroups {
+		names = append(names, ng.Name)
+	}
+
+	for _, ng := range s.ManagedNodeGroups {
+		names = append(names, ng.Name)
+	}
+
+	return names
+}
+
+func (s *clusterConfigSummary) serviceAccountNames() []string {
+	var names []string
+
+	for _, sa := range s.IAM.ServiceAccounts {
+		names = append(names, serviceAccountName(sa.Metadata.Namespace, sa.Metadata.Name))
+	}
+
+	return names
+}
+
+func (s *clusterConfigSummary) fargateProfileNames() []string {
+	var names []string
+
+	for _, p := range s.FargateProfiles {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+func serviceAccountName(namespace, name string) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return namespace + "/" + name
+}
+
+// liveClusterState is the set of objects that eksctl reports to exist in the cluster.
+// Each field is nil when it couldn't be read, in which case the previous cluster config is used instead.
+type liveClusterState struct {
+	NodeGroups      []string
+	ServiceAccounts []string
+	FargateProfiles []string
+}
+
+// operationsPlan is the input for computing the operations an update of the cluster runs.
+type operationsPlan struct {
+	Old, New *clusterConfigSummary
+	Live     liveClusterState
+
+	// Drains maps the names of nodegroups to drain to true, and ones to undo the drain to false.
+	Drains map[string]bool
+
+	IAMIdentityMappingsToCreate []string
+	IAMIdentityMappingsToDelete []string
+
+	ManifestsChanged bool
+
+	// ManifestObjectsToPrune is the objects to be deleted as they are removed from manifests
+	ManifestObjectsToPrune []string
+}
+
+// operations returns human-readable descriptions of the operations. They are listed in the order of the update tasks
+// declared by updateCluster, which may run independent tasks concurrently, so the actual order can differ.
+// Operations that are known to be no-ops are omitted.
+func (p operationsPlan) operations() []string {
+	var ops []string
+
+	add := func(format string, args ...interface{}) {
+		ops = append(ops, fmt.Sprintf(format, args...))
+	}
+
+	if p.Old.Metadata.Version != p.New.Metadata.Version {
+		add("upgrade cluster %s from %s to %s", p.New.Metadata.Name, p.Old.Metadata.Version, p.New.Metadata.Version)
+		add("update kube-proxy")
+		add("update aws-node")
+		add("update coredns")
+	}
+
+	liveNodeGroups := p.Live.NodeGroups
+	if liveNodeGroups == nil {
+		liveNodeGroups = p.Old.nodeGroupNames()
+	}
+
+	liveServiceAccounts := p.Live.ServiceAccounts
+	if liveServiceAccounts == nil {
+		liveServiceAccounts = p.Old.serviceAccountNames()
+	}
+
+	liveFargateProfiles := p.Live.FargateProfiles
+	if liveFargateProfiles == nil {
+		liveFargateProfiles = p.Old.fargateProfileNames()
+	}
+
+	for _, n := range missingNames(p.New.nodeGroupNames(), liveNodeGroups) {
+		add("create nodegroup %s", n)
+	}
+
+	if p.New.IAM.WithOIDC && !p.Old.IAM.WithOIDC {
+		add("associate iam oidc provider")
+	}
+
+	if p.New.IAM.WithOIDC {
+		for _, n := range missingNames(p.New.serviceAccountNames(), liveServiceAccounts) {
+			add("create iamserviceaccount %s", n)
+		}
+	}
+
+	for _, n := range missingNames(p.New.fargateProfileNames(), liveFargateProfiles) {
+		add("create fargateprofile %s", n)
+	}
+
+	if len(p.New.Git) > 0 && !reflect.DeepEqual(p.Old.Git, p.New.Git) {
+		add("enable repo")
+	}
+
+	for _, n := range sortedKeys(p.Drains) {
+		if p.Drains[n] {
+			add("drain nodegroup %s", n)
+		} else {
+			add("undo drain nodegroup %s", n)
+		}
+	}
+
+	for _, arn := range p.IAMIdentityMappingsToDelete {
+		add("delete iamidentitymapping %s", arn)
+	}
+
+	for _, arn := range p.IAMIdentityMappingsToCreate {
+		add("create iamidentitymapping %s", arn)
+	}
+
+	for _, n := range missingNames(liveNodeGroups, p.New.nodeGroupNames()) {
+		add("delete nodegroup %s (drain)", n)
+	}
+
+	if p.New.IAM.WithOIDC {
+		for _, n := range missingNames(liveServiceAccounts, p.New.serviceAccountNames()) {
+			add("delete iamserviceaccount %s", n)
+		}
+	}
+
+	if p.ManifestsChanged {
+		add("apply kubernetes manifests")
+	}
+
+	for _, o := range p.ManifestObjectsToPrune {
+		add("delete kubernetes object %s", o)
+	}
+
+	return ops
+}
+
+// missingNames returns names in `names` that are not in `existing`, sorted.
+func missingNames(names, existing []string) []string {
+	m := map[string]bool{}
+
+	for _, n := range existing {
+		m[n] = true
+	}
+
+	var missing []string
+
+	for _, n := range names {
+		if !m[n] {
+			missing = append(missing, n)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// planOperations sets the operations that `terraform apply` is going to run for the cluster to planned_operations.
+func planOperations(d *schema.ResourceDiff, cluster *Cluster, newConfig []byte) error {
+	if d.Id() == "" {
+		return d.SetNew(KeyPlannedOperations, []interface{}{fmt.Sprintf("create cluster %s", cluster.Name)})
+	}
+
+	oldConfig, _ := d.GetChange(KeyClusterConfig)
+
+	newSummary, err := parseClusterConfigSummary(string(newConfig))
+	if err != nil {
+		return err
+	}
+
+	// The old cluster config is missing only when the state is written by an older version of the provider,
+	// where we can tell changes only from the live state.
+	oldSummary := newSummary
+
+	if oldConfig.(string) != "" {
+		oldSummary, err = parseClusterConfigSummary(oldConfig.(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	oldDrift, _ := d.GetChange(KeyDrift)
+
+	p := operationsPlan{
+		Old:    oldSummary,
+		New:    newSummary,
+		Drains: map[string]bool{},
+	}
+
+	if needsLiveClusterState(oldConfig.(string), string(newConfig), oldDrift) {
+		p.Live = readLiveClusterState(mustNewContext(cluster), d, cluster)
+	}
+
+	if d.HasChange(KeyDrainNodeGroups) {
+		o, n := d.GetChange(KeyDrainNodeGroups)
+
+		old := o.(map[string]interface{})
+
+		for k, v := range n.(map[string]interface{}) {
+			if ov, ok := old[k]; !ok || ov != v {
+				p.Drains[k] = v.(bool)
+			}
+		}
+	}
+
+	awsAuth := plannedAWSAuthChanges(d)
+
+	for _, m := range awsAuth.MappingsToCreate.List() {
+		p.IAMIdentityMappingsToCreate = append(p.IAMIdentityMappingsToCreate, m.(map[string]interface{})["iamarn"].(string))
+	}
+
+	for _, m := range awsAuth.MappingsToDelete.List() {
+		p.IAMIdentityMappingsToDelete = append(p.IAMIdentityMappingsToDelete, m.(map[string]interface{})["iamarn"].(string))
+	}
+
+	for _, a := range awsAuth.AccountsToCreate.List() {
+		p.IAMIdentityMappingsToCreate = append(p.IAMIdentityMappingsToCreate, "account "+a.(string))
+	}
+
+	for _, a := range awsAuth.AccountsToDelete.List() {
+		p.IAMIdentityMappingsToDelete = append(p.IAMIdentityMappingsToDelete, "account "+a.(string))
+	}
+
+	p.ManifestsChanged = d.HasChange(KeyManifests)
+
+	if p.ManifestsChanged && d.NewValueKnown(KeyManifests) {
+		objs, err := parseManifests(cluster.Manifests)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range manifestObjectsToPrune(readAppliedManifestObjects(d), objs) {
+			p.ManifestObjectsToPrune = append(p.ManifestObjectsToPrune, o.String())
+		}
+	}
+
+	var ops []interface{}
+
+	for _, op := range p.operations() {
+		ops = append(ops, op)
+	}
+
+	return d.SetNew(KeyPlannedOperations, ops)
+}
+
+// needsLiveClusterState returns true when planning operations needs the live state of the cluster.
+// When the cluster config is unchanged and the refresh found no drift, the live state is known to match
+// the cluster config, so that plans don't run `eksctl get`.
+func needsLiveClusterState(oldConfig, newConfig string, drift interface{}) bool {
+	if oldConfig != newConfig {
+		return true
+	}
+
+	v, _ := drift.([]interface{})
+
+	return len(v) > 0
+}
+
+// readLiveClusterState reads nodegroups, iamserviceaccounts and fargateprofiles via `eksctl get`.
+// Any failure is logged and results in the corresponding field left nil, as plan should not fail due to that.
+func readLiveClusterState(ctx *sdk.Context, d api.Getter, cluster *Cluster) liveClusterState {
+	var s liveClusterState
+
+	var nodeGroups []struct {
+		Name string `json:"Name"`
+	}
+
+	if err := runEksctlGet(ctx, d, cluster, "nodegroup", &nodeGroups); err != nil {
+		log.Printf("[WARN] %v", err)
+	} else {
+		s.NodeGroups = []string{}
+
+		for _, ng := range nodeGroups {
+			s.NodeGroups = append(s.NodeGroups, ng.Name)
+		}
+	}
+
+	var serviceAccounts []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+
+	if err := runEksctlGet(ctx, d, cluster, "iamserviceaccount", &serviceAccounts); err != nil {
+		log.Printf("[WARN] %v", err)
+	} else {
+		s.ServiceAccounts = []string{}
+
+		for _, sa := range serviceAccounts {
+			s.ServiceAccounts = append(s.ServiceAccounts, serviceAccountName(sa.Metadata.Namespace, sa.Metadata.Name))
+		}
+	}
+
+	var fargateProfiles []struct {
+		Name string `json:"name"`
+	}
+
+	if err := runEksctlGet(ctx, d, cluster, "fargateprofile", &fargateProfiles); err != nil {
+		log.Printf("[WARN] %v", err)
+	} else {
+		s.FargateProfiles = []string{}
+
+		for _, p := range fargateProfiles {
+			s.FargateProfiles = append(s.FargateProfiles, p.Name)
+		}
+	}
+
+	return s
+}
+
+func runEksctlGet(ctx *sdk.Context, d api.Getter, cluster *Cluster, kind string, out interface{}) error {
+	cmd, err := newEksctlCommandFromResourceWithRegionAndProfile(d, "get", kind, "--cluster", cluster.Name, "-o", "json")
+	if err != nil {
+		return fmt.Errorf("creating get %s command: %w", kind, err)
+	}
+
+	r, err := ctx.Run(cmd)
+	if err != nil {
+		return fmt.Errorf("running get %s: %w", kind, err)
+	}
+
+	if err := json.Unmarshal([]byte(r.Output), out); err != nil {
+		return fmt.Errorf("parsing get %s output: %w", kind, err)
+	}
+
+	return nil
+}
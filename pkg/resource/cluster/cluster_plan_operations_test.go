@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationsPlan(t *testing.T) {
+	old, err := parseClusterConfigSummary(`
+metadata:
+  name: mycluster
+  version: "1.18"
+nodeGroups:
+- name: ng1
+iam:
+  withOIDC: false
+`)
+	require.NoError(t, err)
+
+	cur, err := parseClusterConfigSummary(`
+metadata:
+  name: mycluster
+  version: "1.19"
+nodeGroups:
+- name: ng2
+managedNodeGroups:
+- name: mng1
+iam:
+  withOIDC: true
+  serviceAccounts:
+  - metadata:
+      name: sa1
+      namespace: kube-system
+  - metadata:
+      name: sa2
+`)
+	require.NoError(t, err)
+
+	p := operationsPlan{
+		Old: old,
+		New: cur,
+		Live: liveClusterState{
+			// mng1 is already created out of band
+			NodeGroups:      []string{"ng1", "mng1"},
+			ServiceAccounts: []string{"kube-system/sa1", "default/sa3"},
+		},
+		Drains:                      map[string]bool{"ng1": true},
+		IAMIdentityMappingsToDelete: []string{"arn:aws:iam::123456789012:role/foo"},
+	}
+
+	assert.Equal(t, []string{
+		"upgrade cluster mycluster from 1.18 to 1.19",
+		"update kube-proxy",
+		"update aws-node",
+		"update coredns",
+		"create nodegroup ng2",
+		"associate iam oidc provider",
+		"create iamserviceaccount default/sa2",
+		"drain nodegroup ng1",
+		"delete iamidentitymapping arn:aws:iam::123456789012:role/foo",
+		"delete nodegroup ng1 (drain)",
+		"delete iamserviceaccount default/sa3",
+	}, p.operations())
+}
+
+func TestOperationsPlan_NoChanges(t *testing.T) {
+	c, err := parseClusterConfigSummary(`
+metadata:
+  name: mycluster
+  version: "1.18"
+nodeGroups:
+- name: ng1
+`)
+	require.NoError(t, err)
+
+	// The live state is unavailable, so the old cluster config is used instead
+	p := operationsPlan{Old: c, New: c}
+
+	assert.Empty(t, p.operations())
+}
+
+func TestNeedsLiveClusterState(t *testing.T) {
+	config := "metadata:\n  name: mycluster\n"
+
+	assert.False(t, needsLiveClusterState(config, config, nil))
+	assert.False(t, needsLiveClusterState(config, config, []interface{}{}))
+	assert.True(t, needsLiveClusterState(config, config, []interface{}{"nodegroup ng1 is declared but missing in the cluster"}))
+	assert.True(t, needsLiveClusterState(config, config+"nodeGroups:\n- name: ng1\n", nil))
+
+	// The state written by an older version of the provider has no cluster config
+	assert.True(t, needsLiveClusterState("", config, nil))
+}
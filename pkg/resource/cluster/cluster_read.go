@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"sort"
 	"strings"
 
@@ -26,23 +25,25 @@ func (m *Manager) readCluster(d api.ReadWrite) (*Cluster, error) {
 		return nil, fmt.Errorf("reading cluster: %w", err)
 	}
 
-	var path string
+	// `kubeconfig_path` persisted in a Terraform remote backend might refer to an inexistent local path, meaning that
+	// the file is created on another machine and the tfstate had been changed there.
+	// Another resource that depends on this eksctl_cluster(_deployment)'s kubeconfig_path might use the kubeconfig while
+	// in `terraform plan`, so we "reproduce" the kubeconfig before `plan`.
+	// It also refreshes the short-lived `token`.
+	// Failures are only logged, so that a transient AWS API error doesn't fail the refresh.
+	if err := doRefreshKubeconfig(d, cluster, string(m.getClusterName(cluster, d.Id()))); err != nil {
+		log.Printf("[WARN] refreshing kubeconfig on read: %v", err)
+	}
 
-	if v := d.Get(KeyKubeconfigPath); v != nil {
-		path = v.(string)
+	// cluster_config is populated on read too, so that upgrading the provider doesn't result in a diff on plan
+	if set, err := m.PrepareClusterSet(d); err != nil {
+		log.Printf("[WARN] generating cluster config on read: %v", err)
+	} else if err := d.Set(KeyClusterConfig, string(set.ClusterConfig)); err != nil {
+		return nil, fmt.Errorf("setting %s: %w", KeyClusterConfig, err)
 	}
 
-	// `kubeconfig_path` persistend in a Terraform remote backend might refer to an inexistent local path, meaning that
-	// the file is created on another machine and the tfstate had been changed there.
-	// Another resource that depends on this eksctl_cluster(_deployment)'s kubeconfig_path might use the kubeconfig while
-	// in `terraform plan`, so I believe we need to "reproduce" the kubeconfig before `plan`.
-	if path != "" {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			log.Printf("running customdiff: no kubeconfig file found at kubeconfig_path=%s: recreating it", path)
-			if err := doWriteKubeconfig(ctx, d, string(m.getClusterName(cluster, d.Id())), cluster.Region); err != nil {
-				return nil, fmt.Errorf("writing missing kubeconfig on plan: %w", err)
-			}
-		}
+	if err := readDrift(ctx, d, cluster); err != nil {
+		return nil, err
 	}
 
 	if err := readIAMIdentityMapping(ctx, d, cluster); err != nil {
@@ -52,6 +53,37 @@ func (m *Manager) readCluster(d api.ReadWrite) (*Cluster, error) {
 	return cluster, nil
 }
 
+// readDrift sets the differences between the cluster config in the state and the live cluster to drift.
+func readDrift(ctx *sdk.Context, d api.ReadWrite, cluster *Cluster) error {
+	config, _ := d.Get(KeyClusterConfig).(string)
+	if config == "" {
+		return nil
+	}
+
+	summary, err := parseClusterConfigSummary(config)
+	if err != nil {
+		return fmt.Errorf("reading drift: %w", err)
+	}
+
+	drift := detectDrift(summary, readLiveClusterState(ctx, d, cluster))
+
+	for _, v := range drift {
+		log.Printf("[WARN] eksctl_cluster %q drifted: %s", cluster.Name, v)
+	}
+
+	var v []interface{}
+
+	for _, s := range drift {
+		v = append(v, s)
+	}
+
+	if err := d.Set(KeyDrift, v); err != nil {
+		return fmt.Errorf("setting %s: %w", KeyDrift, err)
+	}
+
+	return nil
+}
+
 func (m *Manager) readClusterInternal(d api.Resource) (*Cluster, error) {
 	clusterNamePrefix := d.Get("name").(string)
 
@@ -94,6 +126,16 @@ func (m *Manager) planCluster(d *tfsdk.DiffReadWrite) error {
 }
 
 func readIAMIdentityMapping(ctx *sdk.Context, d api.ReadWrite, cluster *Cluster) error {
+	// In the authoritative mode, the live aws-auth is read into the state so that out-of-band changes result in a diff
+	if isAWSAuthAuthoritative(d) {
+		live, err := runGetIAMIdentityMapping(ctx, d, cluster)
+		if err != nil {
+			return fmt.Errorf("can not get iamidentitymapping from eks cluster: %w", err)
+		}
+
+		return setAWSAuth(d, live)
+	}
+
 	iamWithOIDCEnabled, err := cluster.IAMWithOIDCEnabled()
 	if err != nil {
 		return fmt.Errorf("reading iam.withOIDC setting from cluster.yaml: %w", err)
@@ -101,11 +143,13 @@ func readIAMIdentityMapping(ctx *sdk.Context, d api.ReadWrite, cluster *Cluster)
 		return nil
 	}
 
-	iams, err := runGetIAMIdentityMapping(ctx, d, cluster)
+	live, err := runGetIAMIdentityMapping(ctx, d, cluster)
 	if err != nil {
 		return fmt.Errorf("can not get iamidentitymapping from eks cluster: %w", err)
 	}
 
+	iams := live.Mappings
+
 	current := make([]map[string]interface{}, 0)
 
 	for _, v := range d.Get(KeyAWSAuthConfigMap).(*schema.Set).List() {
@@ -119,13 +163,13 @@ func readIAMIdentityMapping(ctx *sdk.Context, d api.ReadWrite, cluster *Cluster)
 	if diff := cmp.Diff(iams, current); diff != "" {
 		log.Printf("aws-auth diff remote (-remote +current):\n%s", diff)
 	} else {
-		log.Printf("have diff between remote source and param")
+		log.Printf("have no diff between remote source and param")
 	}
 
 	return nil
 }
 
-func runGetIAMIdentityMapping(ctx *sdk.Context, d api.Getter, cluster *Cluster) ([]map[string]interface{}, error) {
+func runGetIAMIdentityMapping(ctx *sdk.Context, d api.Getter, cluster *Cluster) (*awsAuth, error) {
 	//get iamidentitymapping
 	args := []string{
 		"get",
@@ -146,16 +190,48 @@ func runGetIAMIdentityMapping(ctx *sdk.Context, d api.Getter, cluster *Cluster)
 	if err != nil {
 		return nil, fmt.Errorf("running get iamidentitymapping : %w", err)
 	}
-	//replace rolearn and userarn to iamarn
-	iamJson1 := strings.Replace(iamJson.Output, "rolearn", "iamarn", -1)
-	iamJson2 := strings.Replace(iamJson1, "userarn", "iamarn", -1)
 
-	var iams []map[string]interface{}
-	if err := json.Unmarshal([]byte(iamJson2), &iams); err != nil {
+	var entries []struct {
+		RoleARN  string   `json:"rolearn"`
+		UserARN  string   `json:"userarn"`
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+		Account  string   `json:"account"`
+	}
+	if err := json.Unmarshal([]byte(iamJson.Output), &entries); err != nil {
 		return nil, fmt.Errorf("parse iamidentitymapping : %w", err)
 	}
 
-	return iams, nil
+	a := &awsAuth{
+		Mappings: []map[string]interface{}{},
+	}
+
+	for _, e := range entries {
+		if e.Account != "" {
+			a.Accounts = append(a.Accounts, e.Account)
+
+			continue
+		}
+
+		//use rolearn or userarn as iamarn
+		iamarn := e.RoleARN
+		if iamarn == "" {
+			iamarn = e.UserARN
+		}
+
+		groups := []interface{}{}
+		for _, g := range e.Groups {
+			groups = append(groups, g)
+		}
+
+		a.Mappings = append(a.Mappings, map[string]interface{}{
+			"iamarn":   iamarn,
+			"username": e.Username,
+			"groups":   groups,
+		})
+	}
+
+	return a, nil
 }
 
 func loadOIDCProviderURLAndARN(d api.ReadWrite, cluster *Cluster) error {
@@ -180,12 +256,16 @@ func loadOIDCProviderURLAndARN(d api.ReadWrite, cluster *Cluster) error {
 
 type ClusterState struct {
 	Name               string             `json:"Name"`
+	Version            string             `json:"Version"`
+	Endpoint           string             `json:"Endpoint"`
 	Identity           Identity           `json:"Identity"`
 	RoleArn            string             `json:"RoleArn"`
 	ResourcesVpcConfig ResourcesVpcConfig `json:"ResourcesVpcConfig"`
 }
 
 type ResourcesVpcConfig struct {
+	VpcId                  string   `json:"VpcId"`
+	SubnetIds              []string `json:"SubnetIds"`
 	ClusterSecurityGroupId string   `json:"ClusterSecurityGroupId"`
 	SecurityGroupIds       []string `json:"SecurityGroupIds"`
 }
@@ -42,3 +42,34 @@ metadata:
 		t.Errorf("unexpected diff: want (-), got (+)\n%s", d)
 	}
 }
+
+func TestGenerateSeedClusterConfig_NoSpec(t *testing.T) {
+	a := &Cluster{APIVersion: "eksctl.io/v1alpha5", Region: "us-east-2", Version: "1.18"}
+
+	for _, spec := range []map[string]interface{}{
+		// Neither `spec` nor nodegroup blocks
+		{},
+		{"nodeGroups": []interface{}{map[string]interface{}{"name": "ng1"}}},
+	} {
+		seed, err := generateSeedClusterConfig(a, "mycluster", "{}", spec)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		c := clusterConfigNew()
+
+		if err := yaml.Unmarshal(seed, &c); err != nil {
+			t.Fatalf("parsing seed cluster config: %v: INPUT:\n%s", err, string(seed))
+		}
+
+		metadata, _ := c.Rest["metadata"].(map[string]interface{})
+
+		if name := metadata["name"]; name != "mycluster" {
+			t.Errorf("unexpected metadata.name: %v", name)
+		}
+
+		if got, want := len(c.NodeGroups), len(spec); got != want {
+			t.Errorf("unexpected number of nodegroups: want %d, got %d", want, got)
+		}
+	}
+}
@@ -4,11 +4,54 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
 )
 
+// updateTaskInputs is the attributes other than cluster_config that update tasks read.
+// A change in any of them invalidates the update checkpoint.
+var updateTaskInputs = []string{
+	KeyBin,
+	KeyEksctlVersion,
+	KeyProfile,
+	tfsdk.KeyAssumeRole,
+	KeyManifests,
+	KeyDrainNodeGroups,
+	KeyIAMIdentityMapping,
+	KeyMapAccounts,
+	KeyAWSAuthAuthoritative,
+	KeyTargetGroupARNs,
+	KeyReadinessCheck,
+	KeyPodsReadinessCheck,
+	KeyKubeconfigPath,
+}
+
+// updateConfigHash returns the hash of the cluster config and all the other inputs of update tasks,
+// so that tasks are skipped on resume only when they would run with the same inputs.
+func updateConfigHash(d api.Getter, clusterConfig []byte) string {
+	inputs := map[string]interface{}{
+		KeyClusterConfig: string(clusterConfig),
+	}
+
+	for _, k := range updateTaskInputs {
+		v := d.Get(k)
+
+		if s, ok := v.(*schema.Set); ok {
+			v = s.List()
+		}
+
+		inputs[k] = v
+	}
+
+	return sdk.Hash(inputs)
+}
+
 func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 	log.Printf("[DEBUG] updating eksctl cluster with id %q", d.Id())
 
@@ -21,6 +64,30 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 
 	ctx := mustNewContext(cluster)
 
+	// Tasks running concurrently must access the resource data via sd
+	sd := &tfsdk.SyncReadWrite{D: d}
+
+	var (
+		outputMu sync.Mutex
+		// output is the output of the eksctl command that finished last, which is set to `output` once all the tasks finish
+		output    string
+		hasOutput bool
+	)
+
+	runAndRecordOutput := func(cmd *exec.Cmd) error {
+		r, err := ctx.Run(cmd)
+		if err != nil {
+			return err
+		}
+
+		outputMu.Lock()
+		output = r.Output
+		hasOutput = true
+		outputMu.Unlock()
+
+		return nil
+	}
+
 	updateBy := func(args []string, harmlessErrors []string) func() error {
 		return func() error {
 			eksctlCmdToLog := fmt.Sprintf("eksctl-%s", strings.Join(args, "-"))
@@ -72,7 +139,7 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 
 			cmd.Stdin = bytes.NewReader(clusterConfig)
 
-			if err := ctx.Update(cmd, d); err != nil {
+			if err := runAndRecordOutput(cmd); err != nil {
 				lines := strings.Split(err.Error(), "\n")
 				lastLine := lines[len(lines)-1]
 				if lastLine == "" && len(lines) > 1 {
@@ -105,7 +172,7 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 
 			cmd.Stdin = bytes.NewReader(clusterConfig)
 
-			if err := ctx.Update(cmd, d); err != nil {
+			if err := runAndRecordOutput(cmd); err != nil {
 				lines := strings.Split(err.Error(), "\n")
 				lastLine := lines[len(lines)-1]
 				if lastLine == "" && len(lines) > 1 {
@@ -136,7 +203,7 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 			}
 			cmd.Stdin = bytes.NewReader(clusterConfig)
 
-			if err := ctx.Update(cmd, d); err != nil {
+			if err := runAndRecordOutput(cmd); err != nil {
 				return fmt.Errorf("%v\n\nCLUSTER CONFIG:\n%s", err, string(clusterConfig))
 			}
 
@@ -144,9 +211,9 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 		}
 	}
 
-	applyKubernetesManifests := func(id string) func() error {
+	applyKubernetesManifests := func() func() error {
 		return func() error {
-			return doApplyKubernetesManifests(ctx, cluster, id)
+			return doApplyKubernetesManifests(sd, cluster, string(set.ClusterName))
 		}
 	}
 
@@ -164,7 +231,7 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 			}
 			cmd.Stdin = bytes.NewReader(clusterConfig)
 
-			if err := ctx.Update(cmd, d); err != nil {
+			if err := runAndRecordOutput(cmd); err != nil {
 				return fmt.Errorf("%v\n\nCLUSTER CONFIG:\n%s", err, string(clusterConfig))
 			}
 
@@ -172,15 +239,21 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 		}
 	}
 
-	checkPodsReadiness := func(id string) func() error {
+	checkReadiness := func() func() error {
+		return func() error {
+			return doCheckReadiness(cluster, string(set.ClusterName))
+		}
+	}
+
+	checkPodsReadiness := func() func() error {
 		return func() error {
-			return doCheckPodsReadiness(ctx, cluster, id)
+			return doCheckPodsReadiness(cluster, string(set.ClusterName))
 		}
 	}
 
 	writeKubeconfig := func() func() error {
 		return func() error {
-			return doWriteKubeconfig(ctx, d, string(set.ClusterName), cluster.Region)
+			return doWriteKubeconfig(sd, cluster, string(set.ClusterName))
 		}
 	}
 
@@ -190,8 +263,6 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 		}
 	}
 
-	id := d.Id()
-
 	clusterName := string(set.ClusterName)
 	harmlessFargateProfileCreationErrors := []string{
 		fmt.Sprintf(`Error: no output "FargatePodExecutionRoleARN" in stack "eksctl-%s-cluster"`, clusterName),
@@ -208,7 +279,7 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 				"--cluster=" + clusterName,
 				"-n",
 			}
-			nodegroups := d.Get(KeyDrainNodeGroups).(map[string]interface{})
+			nodegroups := sd.Get(KeyDrainNodeGroups).(map[string]interface{})
 
 			for k, v := range nodegroups {
 				log.Printf("DRAIN    %v %v ", k, v)
@@ -217,13 +288,13 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 				if v == false {
 					opt = append(opt, "--undo")
 				}
-				cmd, err := newEksctlCommandFromResourceWithRegionAndProfile(d, opt...)
+				cmd, err := newEksctlCommandFromResourceWithRegionAndProfile(sd, opt...)
 
 				if err != nil {
 					return fmt.Errorf("creating eksctl drain command: %w", err)
 				}
 
-				if err := ctx.Update(cmd, d); err != nil {
+				if err := runAndRecordOutput(cmd); err != nil {
 					return fmt.Errorf("Drain Error: %v", err)
 				}
 			}
@@ -248,49 +319,69 @@ func (m *Manager) updateCluster(d *schema.ResourceData) (*ClusterSet, error) {
 
 	updateIAMIdentityMapping := func() func() error {
 		return func() error {
-			d.HasChange(KeyIAMIdentityMapping)
-			a, b := d.GetChange(KeyIAMIdentityMapping)
-
-			if err := runCreateIAMIdentityMapping(ctx, d, b.(*schema.Set).Difference(a.(*schema.Set)), cluster); err != nil {
-				return fmt.Errorf("CreateIAMIdentityMapping Error: %v", err)
-			}
-
-			if err := runDeleteIAMIdentityMapping(ctx, d, a.(*schema.Set).Difference(b.(*schema.Set)), cluster); err != nil {
-				return fmt.Errorf("DeleteIAMIdentityMapping Error: %v", err)
-			}
-
-			return nil
+			return applyAWSAuth(ctx, sd, cluster)
 		}
 	}
 
-	tasks := []func() error{
+	tasks := []updateTask{
 		// See https://eksctl.io/usage/cluster-upgrade/ for the cluster upgrade process
-		updateBy([]string{"upgrade", "cluster","--approve"}, nil),
-		updateBy([]string{"utils", "update-kube-proxy","--approve"}, nil),
-		updateBy([]string{"utils", "update-aws-node","--approve"}, nil),
-		updateBy([]string{"utils", "update-coredns","--approve"}, nil),
-		createNew("nodegroup", nil, nil),
-		whenIAMWithOIDCEnabled(associateIAMOIDCProvider()),
-		whenIAMWithOIDCEnabled(createNew("iamserviceaccount", []string{"--approve"}, nil)),
-		createNew("fargateprofile", nil, harmlessFargateProfileCreationErrors),
-		enableRepo(),
-		drainNodegroup(),
-		updateIAMIdentityMapping(),
-		deleteMissing("nodegroup", []string{"--drain", "--approve"}, nil),
-		whenIAMWithOIDCEnabled(deleteMissing("iamserviceaccount", []string{"--approve"}, nil)),
+		{Name: "upgrade-cluster", Run: updateBy([]string{"upgrade", "cluster", "--approve"}, nil)},
+		{Name: "update-kube-proxy", Run: updateBy([]string{"utils", "update-kube-proxy", "--approve"}, nil), DependsOn: []string{"upgrade-cluster"}},
+		{Name: "update-aws-node", Run: updateBy([]string{"utils", "update-aws-node", "--approve"}, nil), DependsOn: []string{"upgrade-cluster"}},
+		{Name: "update-coredns", Run: updateBy([]string{"utils", "update-coredns", "--approve"}, nil), DependsOn: []string{"upgrade-cluster"}},
+		{Name: "create-nodegroups", Run: createNew("nodegroup", nil, nil), DependsOn: []string{"update-kube-proxy", "update-aws-node", "update-coredns"}},
+		{Name: "associate-iam-oidc-provider", Run: whenIAMWithOIDCEnabled(associateIAMOIDCProvider()), DependsOn: []string{"upgrade-cluster"}},
+		{Name: "create-iamserviceaccounts", Run: whenIAMWithOIDCEnabled(createNew("iamserviceaccount", []string{"--approve"}, nil)), DependsOn: []string{"associate-iam-oidc-provider"}},
+		{Name: "create-fargateprofiles", Run: createNew("fargateprofile", nil, harmlessFargateProfileCreationErrors), DependsOn: []string{"upgrade-cluster"}},
+		{Name: "enable-repo", Run: enableRepo(), DependsOn: []string{"create-nodegroups"}},
+		// New nodegroups need to be ready before draining and deleting old ones, so that pods can be rescheduled
+		{Name: "drain-nodegroups", Run: drainNodegroup(), DependsOn: []string{"create-nodegroups"}},
+		// Creating and deleting nodegroups and updating iamidentitymappings all read-modify-write the aws-auth ConfigMap,
+		// so they run one by one
+		{Name: "update-iamidentitymappings", Run: updateIAMIdentityMapping(), DependsOn: []string{"create-nodegroups", "drain-nodegroups"}},
+		{Name: "delete-missing-nodegroups", Run: deleteMissing("nodegroup", []string{"--drain", "--approve"}, nil), DependsOn: []string{"drain-nodegroups", "update-iamidentitymappings"}},
+		{Name: "delete-missing-iamserviceaccounts", Run: whenIAMWithOIDCEnabled(deleteMissing("iamserviceaccount", []string{"--approve"}, nil)), DependsOn: []string{"create-iamserviceaccounts"}},
 		// eksctl delete fargate profile doens't has --only-missing command
 		//deleteMissing("fargateprofile", nil, []string{"Error: invalid Fargate profile: empty name"}),
-		applyKubernetesManifests(id),
-		attachNodeGroupsToTargetGroups(),
-		checkPodsReadiness(id),
-		writeKubeconfig(),
+		{Name: "apply-kubernetes-manifests", Run: applyKubernetesManifests(), DependsOn: []string{"create-nodegroups", "create-iamserviceaccounts", "create-fargateprofiles"}},
+		{Name: "attach-nodegroups-to-target-groups", Run: attachNodeGroupsToTargetGroups(), DependsOn: []string{"create-nodegroups"}},
+		{Name: "check-pods-readiness", Run: checkPodsReadiness(), DependsOn: []string{"enable-repo", "delete-missing-nodegroups", "apply-kubernetes-manifests", "attach-nodegroups-to-target-groups"}},
+		{Name: "check-readiness", Run: checkReadiness(), DependsOn: []string{"enable-repo", "delete-missing-nodegroups", "apply-kubernetes-manifests", "attach-nodegroups-to-target-groups"}},
+		// Rerun on resume to set the token and the kubeconfig of the current run
+		{Name: "write-kubeconfig", Run: writeKubeconfig(), DependsOn: []string{"upgrade-cluster"}, AlwaysRun: true},
 	}
 
-	for _, t := range tasks {
-		if err := t(); err != nil {
-			return nil, err
+	configHash := updateConfigHash(d, clusterConfig)
+
+	cp, err := runUpdatePipeline(tasks, configHash, readUpdateCheckpoint(d), cluster.UpdateConcurrency)
+
+	// All the tasks have finished, so d is no longer accessed concurrently
+	if hasOutput {
+		if err := d.Set(sdk.KeyOutput, output); err != nil {
+			log.Printf("[WARN] setting %s: %v", sdk.KeyOutput, err)
 		}
 	}
 
+	if err != nil {
+		// Persist only the checkpoint so that the next plan still shows the diff, which triggers the update that
+		// resumes from the failed task.
+		d.Partial(true)
+
+		if err := d.Set(KeyUpdateCheckpoint, cp.toState()); err != nil {
+			log.Printf("[WARN] setting %s: %v", KeyUpdateCheckpoint, err)
+		}
+
+		d.SetPartial(KeyUpdateCheckpoint)
+
+		// Objects applied before the failure need to be tracked, so that they are pruned once removed from manifests
+		d.SetPartial(KeyAppliedManifestObjects)
+
+		return nil, err
+	}
+
+	if err := d.Set(KeyUpdateCheckpoint, nil); err != nil {
+		return nil, fmt.Errorf("setting %s: %w", KeyUpdateCheckpoint, err)
+	}
+
 	return set, nil
 }
@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
+)
+
+const (
+	KeySubnetIDs              = "subnet_ids"
+	KeyClusterSecurityGroupID = "cluster_security_group_id"
+	KeyNodeGroups             = "node_groups"
+)
+
+// DataSourceCluster reads an existing cluster that is not managed by the Terraform configuration, like
+// one created by another stack or by eksctl.
+func DataSourceCluster() *schema.Resource {
+	return &schema.Resource{
+		Read: func(d *schema.ResourceData, meta interface{}) (finalErr error) {
+			defer func() {
+				if err := recover(); err != nil {
+					finalErr = fmt.Errorf("unhandled error: %v\n%s", err, debug.Stack())
+				}
+			}()
+
+			if err := readClusterDataSource(d); err != nil {
+				return fmt.Errorf("reading eksctl_cluster data source: %w", err)
+			}
+
+			return nil
+		},
+		Schema: map[string]*schema.Schema{
+			KeyName: {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			KeyRegion: {
+				Type:        schema.TypeString,
+				Required:    true,
+				DefaultFunc: schema.EnvDefaultFunc("AWS_DEFAULT_REGION", nil),
+			},
+			KeyProfile: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			tfsdk.KeyAssumeRole: tfsdk.SchemaAssumeRole(),
+			KeyBin: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "eksctl",
+			},
+			KeyEksctlVersion: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			KeyKubeconfigPath: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The path to write the kubeconfig for the cluster to. The current context of an existing kubeconfig at the path is kept. Defaults to `<region>/<name>.kubeconfig` under `terraform-provider-eksctl/kubeconfigs` in the system temp directory",
+			},
+			KeyVersion: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyVPCID: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeySubnetIDs: {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			KeySecurityGroupIDs: {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			KeyClusterSecurityGroupID: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyOIDCProviderURL: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyOIDCProviderARN: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyEndpoint: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyCertificateAuthorityData: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyToken: {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			KeyKubeconfigRaw: {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			KeyNodeGroups: {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"instance_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"min_size": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"max_size": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"desired_capacity": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"node_instance_role_arn": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// nodeGroupSummary is a nodegroup reported by `eksctl get nodegroup -o json`.
+type nodeGroupSummary struct {
+	Name                string `json:"Name"`
+	Status              string `json:"Status"`
+	InstanceType        string `json:"InstanceType"`
+	MinSize             int    `json:"MinSize"`
+	MaxSize             int    `json:"MaxSize"`
+	DesiredCapacity     int    `json:"DesiredCapacity"`
+	NodeInstanceRoleARN string `json:"NodeInstanceRoleARN"`
+
+	// NodeGroupType is either `managed` or `unmanaged`. Older eksctl versions don't report it.
+	NodeGroupType string `json:"NodeGroupType"`
+}
+
+func readClusterDataSource(d *schema.ResourceData) error {
+	cluster := &Cluster{
+		EksctlBin:        d.Get(KeyBin).(string),
+		EksctlVersion:    d.Get(KeyEksctlVersion).(string),
+		Name:             d.Get(KeyName).(string),
+		Region:           d.Get(KeyRegion).(string),
+		Profile:          d.Get(KeyProfile).(string),
+		AssumeRoleConfig: tfsdk.GetAssumeRoleConfig(d),
+	}
+
+	state, err := runGetCluster(d, cluster)
+	if err != nil {
+		return err
+	}
+
+	var nodeGroups []nodeGroupSummary
+
+	if err := runEksctlGet(mustNewContext(cluster), d, cluster, "nodegroup", &nodeGroups); err != nil {
+		return err
+	}
+
+	var oidcProviderARN string
+
+	if state.Identity.Oidc.Issuer != "" {
+		oidcProviderARN = state.GetOIDCProviderARN()
+	}
+
+	for k, v := range map[string]interface{}{
+		KeyVersion:                state.Version,
+		KeyVPCID:                  state.ResourcesVpcConfig.VpcId,
+		KeySubnetIDs:              state.ResourcesVpcConfig.SubnetIds,
+		KeySecurityGroupIDs:       state.GetSecurityGroupIDs(),
+		KeyClusterSecurityGroupID: state.ResourcesVpcConfig.ClusterSecurityGroupId,
+		KeyOIDCProviderURL:        state.Identity.Oidc.Issuer,
+		KeyOIDCProviderARN:        oidcProviderARN,
+		KeyNodeGroups:             flattenNodeGroupSummaries(nodeGroups),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return fmt.Errorf("setting %s: %w", k, err)
+		}
+	}
+
+	if err := doReadKubeconfig(d, cluster, cluster.Name); err != nil {
+		return err
+	}
+
+	d.SetId(cluster.Region + "/" + cluster.Name)
+
+	return nil
+}
+
+func flattenNodeGroupSummaries(nodeGroups []nodeGroupSummary) []interface{} {
+	var vs []interface{}
+
+	for _, ng := range nodeGroups {
+		vs = append(vs, map[string]interface{}{
+			"name":                   ng.Name,
+			"status":                 ng.Status,
+			"instance_type":          ng.InstanceType,
+			"min_size":               ng.MinSize,
+			"max_size":               ng.MaxSize,
+			"desired_capacity":       ng.DesiredCapacity,
+			"node_instance_role_arn": ng.NodeInstanceRoleARN,
+		})
+	}
+
+	return vs
+}
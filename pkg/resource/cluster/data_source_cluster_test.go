@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterState_dataSource(t *testing.T) {
+	// A trimmed output of `eksctl get cluster -o json`
+	out := `[
+  {
+    "Arn": "arn:aws:eks:us-east-2:123456789012:cluster/prod",
+    "Name": "prod",
+    "Version": "1.19",
+    "Endpoint": "https://ABCDEF.gr7.us-east-2.eks.amazonaws.com",
+    "Identity": {"Oidc": {"Issuer": "https://oidc.eks.us-east-2.amazonaws.com/id/ABCDEF"}},
+    "RoleArn": "arn:aws:iam::123456789012:role/eksctl-prod-cluster-ServiceRole-O7YWRVENASZV",
+    "ResourcesVpcConfig": {
+      "ClusterSecurityGroupId": "sg-cluster",
+      "SecurityGroupIds": ["sg-1"],
+      "SubnetIds": ["subnet-1", "subnet-2"],
+      "VpcId": "vpc-1"
+    }
+  }
+]`
+
+	var states []*ClusterState
+
+	require.NoError(t, json.Unmarshal([]byte(out), &states))
+	require.Len(t, states, 1)
+
+	s := states[0]
+
+	assert.Equal(t, "1.19", s.Version)
+	assert.Equal(t, "https://ABCDEF.gr7.us-east-2.eks.amazonaws.com", s.Endpoint)
+	assert.Equal(t, "vpc-1", s.ResourcesVpcConfig.VpcId)
+	assert.Equal(t, []string{"subnet-1", "subnet-2"}, s.ResourcesVpcConfig.SubnetIds)
+	assert.Equal(t, "sg-cluster", s.ResourcesVpcConfig.ClusterSecurityGroupId)
+	assert.Equal(t, []string{"sg-1"}, s.GetSecurityGroupIDs())
+	assert.Equal(t, "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-2.amazonaws.com/id/ABCDEF", s.GetOIDCProviderARN())
+}
+
+func TestFlattenNodeGroupSummaries(t *testing.T) {
+	// A trimmed output of `eksctl get nodegroup -o json`
+	out := `[
+  {
+    "StackName": "eksctl-prod-nodegroup-ng1",
+    "Cluster": "prod",
+    "Name": "ng1",
+    "Status": "CREATE_COMPLETE",
+    "MaxSize": 3,
+    "MinSize": 1,
+    "DesiredCapacity": 2,
+    "InstanceType": "m5.large",
+    "NodeInstanceRoleARN": "arn:aws:iam::123456789012:role/eksctl-prod-nodegroup-ng1-NodeInstanceRole"
+  }
+]`
+
+	var nodeGroups []nodeGroupSummary
+
+	require.NoError(t, json.Unmarshal([]byte(out), &nodeGroups))
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name":                   "ng1",
+			"status":                 "CREATE_COMPLETE",
+			"instance_type":          "m5.large",
+			"min_size":               1,
+			"max_size":               3,
+			"desired_capacity":       2,
+			"node_instance_role_arn": "arn:aws:iam::123456789012:role/eksctl-prod-nodegroup-ng1-NodeInstanceRole",
+		},
+	}, flattenNodeGroupSummaries(nodeGroups))
+}
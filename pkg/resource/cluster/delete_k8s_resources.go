@@ -1,62 +1,508 @@
 package cluster
 
 import (
-	"io/ioutil"
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"os"
-	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
-	"golang.org/x/xerrors"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 )
 
-func doDeleteKubernetesResourcesBeforeDestroy(ctx *sdk.Context, cluster *Cluster, id string) error {
-	if len(cluster.DeleteKubernetesResourcesBeforeDestroy) == 0 {
+const (
+	deletionPollInterval = 5 * time.Second
+
+	// ingressGroupAnnotation makes AWS Load Balancer Controller share an ALB among ingresses in the group
+	ingressGroupAnnotation = "alb.ingress.kubernetes.io/group.name"
+)
+
+func kubernetesResourceDeletionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:       schema.TypeList,
+		Optional:   true,
+		ConfigMode: schema.SchemaConfigModeBlock,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"kind": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "The kind of objects to delete, specified like kubectl does, e.g. `Deployment`, `svc`, `pvc` and `ingresses.networking.k8s.io`",
+				},
+				"namespace": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  metav1.NamespaceDefault,
+				},
+				"all_namespaces": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"label_selector": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"field_selector": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"timeout_sec": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      600,
+					ValidateFunc: validation.IntAtLeast(1),
+					Description:  "Seconds to wait for the objects, and load balancers of the Services and Ingresses among them, to be deleted",
+				},
+			},
+		},
+	}
+}
+
+func ReadDeleteKubernetesResources(d api.Getter) ([]DeleteKubernetesResource, error) {
+	var deletions []DeleteKubernetesResource
+
+	vs, _ := d.Get(KeyKubernetesResourceDeletionBeforeDestroy).([]interface{})
+
+	for i, v := range vs {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		r := DeleteKubernetesResource{
+			Kind:          m["kind"].(string),
+			Namespace:     m["namespace"].(string),
+			AllNamespaces: m["all_namespaces"].(bool),
+			Name:          m["name"].(string),
+			LabelSelector: m["label_selector"].(string),
+			FieldSelector: m["field_selector"].(string),
+			Timeout:       time.Duration(m["timeout_sec"].(int)) * time.Second,
+		}
+
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", KeyKubernetesResourceDeletionBeforeDestroy, i, err)
+		}
+
+		deletions = append(deletions, r)
+	}
+
+	return deletions, nil
+}
+
+func (r DeleteKubernetesResource) validate() error {
+	if r.Name != "" {
+		if r.AllNamespaces || r.LabelSelector != "" || r.FieldSelector != "" {
+			return errors.New("name can not be used with all_namespaces, label_selector and field_selector")
+		}
+
 		return nil
 	}
 
-	kubeconfig, err := ioutil.TempFile("", "terraform-provider-eksctl-kubeconfig-")
+	if r.LabelSelector == "" && r.FieldSelector == "" {
+		return errors.New("one of name, label_selector and field_selector is required")
+	}
+
+	return nil
+}
+
+func (r DeleteKubernetesResource) String() string {
+	ns := "namespace " + r.Namespace
+	if r.AllNamespaces {
+		ns = "all namespaces"
+	}
+
+	if r.Name != "" {
+		return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+	}
+
+	var selectors []string
+
+	if r.LabelSelector != "" {
+		selectors = append(selectors, fmt.Sprintf("labels %q", r.LabelSelector))
+	}
+
+	if r.FieldSelector != "" {
+		selectors = append(selectors, fmt.Sprintf("fields %q", r.FieldSelector))
+	}
+
+	return fmt.Sprintf("%s in %s with %s", r.Kind, ns, strings.Join(selectors, " and "))
+}
+
+// delete deletes the objects and returns the deleted objects, or nil when the kind doesn't exist in the cluster.
+func (r DeleteKubernetesResource) delete(ctx context.Context, kube *kubeClient) ([]unstructured.Unstructured, error) {
+	mapping, err := kube.mappingFor(r.Kind)
+	if meta.IsNoMatchError(err) {
+		log.Printf("[INFO] skipped deleting %s, as the kind doesn't exist in the cluster", r)
+
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var client dynamic.ResourceInterface = kube.client.Resource(mapping.Resource)
+
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && !r.AllNamespaces {
+		client = kube.client.Resource(mapping.Resource).Namespace(r.Namespace)
+	}
+
+	var objs []unstructured.Unstructured
+
+	if r.Name != "" {
+		obj, err := client.Get(ctx, r.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Printf("[INFO] skipped deleting %s, as it seems already deleted", r)
+
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, *obj)
+	} else {
+		list, err := client.List(ctx, metav1.ListOptions{LabelSelector: r.LabelSelector, FieldSelector: r.FieldSelector})
+		if err != nil {
+			return nil, err
+		}
+
+		objs = list.Items
+	}
+
+	policy := metav1.DeletePropagationBackground
+
+	for _, obj := range objs {
+		uid := obj.GetUID()
+
+		err := kube.client.Resource(mapping.Resource).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{
+			PropagationPolicy: &policy,
+			Preconditions:     &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("deleting %s: %w", objectName(obj), err)
+		}
+
+		log.Printf("[INFO] deleted %s", objectName(obj))
+	}
+
+	return objs, nil
+}
+
+func objectName(obj unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// remainingObjects returns the deleted objects that still exist, along with their finalizers if any.
+func remainingObjects(ctx context.Context, kube *kubeClient, objs []unstructured.Unstructured) ([]string, error) {
+	var remaining []string
+
+	for _, obj := range objs {
+		mapping, err := kube.mapper.RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err := kube.client.Resource(mapping.Resource).Namespace(obj.GetNamespace()).Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// The object has been recreated, e.g. by a controller
+		if cur.GetUID() != obj.GetUID() {
+			continue
+		}
+
+		if fs := cur.GetFinalizers(); len(fs) > 0 {
+			remaining = append(remaining, fmt.Sprintf("%s with finalizers %s", objectName(obj), strings.Join(fs, ", ")))
+		} else {
+			remaining = append(remaining, objectName(obj))
+		}
+	}
+
+	return remaining, nil
+}
+
+// loadBalancerOwner identifies the load balancer created for a Service or an Ingress by the tags
+// that the load balancer has.
+type loadBalancerOwner struct {
+	Object string
+
+	// Tags must all be set to the load balancer. An empty value matches any value.
+	Tags map[string]string
+}
+
+// loadBalancerOwners returns the possible owners of load balancers among the objects, covering load balancers
+// created by AWS Load Balancer Controller, the legacy ALB Ingress Controller, and the in-tree cloud provider.
+func loadBalancerOwners(objs []unstructured.Unstructured, clusterName string) []loadBalancerOwner {
+	var owners []loadBalancerOwner
+
+	clusterTag := "kubernetes.io/cluster/" + clusterName
+
+	for _, obj := range objs {
+		name := obj.GetNamespace() + "/" + obj.GetName()
+		gvk := obj.GroupVersionKind()
+
+		switch {
+		case gvk.Group == "" && gvk.Kind == "Service":
+			if t, _, _ := unstructured.NestedString(obj.Object, "spec", "type"); t != "LoadBalancer" {
+				continue
+			}
+
+			owners = append(owners,
+				loadBalancerOwner{Object: objectName(obj), Tags: map[string]string{"elbv2.k8s.aws/cluster": clusterName, "service.k8s.aws/stack": name}},
+				loadBalancerOwner{Object: objectName(obj), Tags: map[string]string{clusterTag: "", "kubernetes.io/service-name": name}},
+			)
+		case (gvk.Group == "networking.k8s.io" || gvk.Group == "extensions") && gvk.Kind == "Ingress":
+			if group := obj.GetAnnotations()[ingressGroupAnnotation]; group != "" {
+				// The ALB is deleted only after all the ingresses in the group are deleted
+				log.Printf("[INFO] not waiting for the load balancer of %s, as it is shared within the ingress group %s", objectName(obj), group)
+
+				continue
+			}
+
+			owners = append(owners,
+				loadBalancerOwner{Object: objectName(obj), Tags: map[string]string{"elbv2.k8s.aws/cluster": clusterName, "ingress.k8s.aws/stack": name}},
+				loadBalancerOwner{Object: objectName(obj), Tags: map[string]string{clusterTag: "", "kubernetes.io/namespace": obj.GetNamespace(), "kubernetes.io/ingress-name": obj.GetName()}},
+			)
+		}
+	}
+
+	return owners
+}
+
+func (o loadBalancerOwner) owns(tags map[string]string) bool {
+	for k, v := range o.Tags {
+		actual, ok := tags[k]
+		if !ok || (v != "" && actual != v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// remainingLoadBalancers returns the load balancers owned by any of owners, given tags of load balancers keyed by name.
+func remainingLoadBalancers(owners []loadBalancerOwner, lbTags map[string]map[string]string) []string {
+	var remaining []string
+
+	for name, tags := range lbTags {
+		for _, o := range owners {
+			if o.owns(tags) {
+				remaining = append(remaining, fmt.Sprintf("load balancer %s for %s", name, o.Object))
+
+				break
+			}
+		}
+	}
+
+	sort.Strings(remaining)
+
+	return remaining
+}
+
+// getLoadBalancerTags returns tags of all the elbv2 and Classic load balancers in the region, keyed by load balancer name.
+func getLoadBalancerTags(svc elbv2iface.ELBV2API, classic elbiface.ELBAPI) (map[string]map[string]string, error) {
+	lbTags, err := getELBV2LoadBalancerTags(svc)
 	if err != nil {
-		return xerrors.Errorf("creating temp kubeconfig file: %w", err)
+		return nil, err
 	}
 
-	kubeconfigPath := kubeconfig.Name()
+	classicTags, err := getClassicLoadBalancerTags(classic)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := kubeconfig.Close(); err != nil {
-		return xerrors.Errorf("writing kubeconfig: %w", err)
+	for name, tags := range classicTags {
+		lbTags[name] = tags
 	}
 
-	clusterName := cluster.Name + "-" + id
+	return lbTags, nil
+}
+
+// getClassicLoadBalancerTags returns tags of all the Classic load balancers in the region, keyed by load balancer name.
+// The in-tree cloud provider creates a Classic load balancer for a Service of type LoadBalancer by default.
+func getClassicLoadBalancerTags(svc elbiface.ELBAPI) (map[string]map[string]string, error) {
+	var names []*string
 
-	writeKubeconfigCmd, err := newEksctlCommandWithAWSProfile(cluster, "utils", "write-kubeconfig", "--kubeconfig", kubeconfigPath, "--cluster", clusterName, "--region", cluster.Region)
+	err := svc.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(out *elb.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, lb := range out.LoadBalancerDescriptions {
+			names = append(names, lb.LoadBalancerName)
+		}
+
+		return true
+	})
 	if err != nil {
-		return xerrors.Errorf("initializing eksctl-utils-write-kubeconfig: %w", err)
+		return nil, fmt.Errorf("describing classic load balancers: %w", err)
+	}
+
+	lbTags := map[string]map[string]string{}
+
+	// DescribeTags accepts up to 20 names at once
+	for i := 0; i < len(names); i += 20 {
+		end := i + 20
+		if end > len(names) {
+			end = len(names)
+		}
+
+		out, err := svc.DescribeTags(&elb.DescribeTagsInput{LoadBalancerNames: names[i:end]})
+		if err != nil {
+			return nil, fmt.Errorf("describing classic load balancer tags: %w", err)
+		}
+
+		for _, d := range out.TagDescriptions {
+			tags := map[string]string{}
+
+			for _, t := range d.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+			}
+
+			lbTags[aws.StringValue(d.LoadBalancerName)] = tags
+		}
 	}
 
-	if _, err := ctx.Run(writeKubeconfigCmd); err != nil {
-		return xerrors.Errorf("running eksctl-utils-write-kubeconfig: %w", err)
+	return lbTags, nil
+}
+
+// getELBV2LoadBalancerTags returns tags of all the elbv2 load balancers in the region, keyed by load balancer name.
+func getELBV2LoadBalancerTags(svc elbv2iface.ELBV2API) (map[string]map[string]string, error) {
+	names := map[string]string{}
+
+	var arns []*string
+
+	err := svc.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(out *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, lb := range out.LoadBalancers {
+			names[aws.StringValue(lb.LoadBalancerArn)] = aws.StringValue(lb.LoadBalancerName)
+			arns = append(arns, lb.LoadBalancerArn)
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing load balancers: %w", err)
 	}
 
-	for _, d := range cluster.DeleteKubernetesResourcesBeforeDestroy {
-		kubectlCmd := exec.Command(cluster.KubectlBin, "delete", "-n", d.Namespace, d.Kind, d.Name)
+	lbTags := map[string]map[string]string{}
+
+	// DescribeTags accepts up to 20 ARNs at once
+	for i := 0; i < len(arns); i += 20 {
+		end := i + 20
+		if end > len(arns) {
+			end = len(arns)
+		}
+
+		out, err := svc.DescribeTags(&elbv2.DescribeTagsInput{ResourceArns: arns[i:end]})
+		if err != nil {
+			return nil, fmt.Errorf("describing load balancer tags: %w", err)
+		}
 
-		for _, env := range os.Environ() {
-			if !strings.HasPrefix(env, "KUBECONFIG=") {
-				kubectlCmd.Env = append(kubectlCmd.Env, env)
+		for _, d := range out.TagDescriptions {
+			tags := map[string]string{}
+
+			for _, t := range d.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
 			}
+
+			lbTags[names[aws.StringValue(d.ResourceArn)]] = tags
 		}
+	}
 
-		kubectlCmd.Env = append(kubectlCmd.Env, "KUBECONFIG="+kubeconfigPath)
+	return lbTags, nil
+}
 
-		if _, err := ctx.Run(kubectlCmd); err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				log.Printf("Ignoring `kubectl delete` error %s. %s/%s/%s seems already deleted. Perhaps it is a stale cluster that was in the middle of deletion process?", err.Error(), d.Namespace, d.Kind, d.Name)
-				continue
+// doDeleteKubernetesResourcesBeforeDestroy deletes objects selected by kubernetes_resource_deletion_before_destroy
+// and waits until they are gone along with load balancers of Services and Ingresses among them,
+// so that finalizers are run and the load balancers don't block deleting the VPC.
+func doDeleteKubernetesResourcesBeforeDestroy(cluster *Cluster, clusterName string) error {
+	if len(cluster.DeleteKubernetesResourcesBeforeDestroy) == 0 {
+		return nil
+	}
+
+	kube, err := newKubeClient(cluster, clusterName)
+	if err != nil {
+		return err
+	}
+
+	clients := sdk.NewClients(AWSSessionFromCluster(cluster))
+	elbv2Svc, elbSvc := clients.ELBV2(), clients.ELB()
+
+	reqCtx := context.Background()
+
+	for i, r := range cluster.DeleteKubernetesResourcesBeforeDestroy {
+		objs, err := r.delete(reqCtx, kube)
+		if err != nil {
+			return fmt.Errorf("%s[%d] %s: %w", KeyKubernetesResourceDeletionBeforeDestroy, i, r, err)
+		}
+
+		if len(objs) == 0 {
+			continue
+		}
+
+		owners := loadBalancerOwners(objs, clusterName)
+
+		var remaining []string
+
+		err = wait.PollImmediate(deletionPollInterval, r.Timeout, func() (bool, error) {
+			objs, err := remainingObjects(reqCtx, kube, objs)
+			if err != nil {
+				// The API server might be temporarily unavailable
+				remaining = []string{err.Error()}
+
+				return false, nil
+			}
+
+			remaining = objs
+
+			if len(remaining) > 0 || len(owners) == 0 {
+				return len(remaining) == 0, nil
+			}
+
+			lbTags, err := getLoadBalancerTags(elbv2Svc, elbSvc)
+			if err != nil {
+				remaining = []string{err.Error()}
+
+				return false, nil
 			}
 
-			return xerrors.Errorf("running kubectl-delete: %w", err)
+			remaining = remainingLoadBalancers(owners, lbTags)
+
+			return len(remaining) == 0, nil
+		})
+
+		if errors.Is(err, wait.ErrWaitTimeout) {
+			return fmt.Errorf("%s[%d] %s: not deleted after %s: %s", KeyKubernetesResourceDeletionBeforeDestroy, i, r, r.Timeout, strings.Join(remaining, "; "))
+		} else if err != nil {
+			return err
 		}
+
+		log.Printf("[INFO] %s has been deleted", r)
 	}
 
 	return nil
@@ -0,0 +1,250 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReadDeleteKubernetesResources(t *testing.T) {
+	s := map[string]*schema.Schema{KeyKubernetesResourceDeletionBeforeDestroy: kubernetesResourceDeletionSchema()}
+
+	d := schema.TestResourceDataRaw(t, s, map[string]interface{}{
+		KeyKubernetesResourceDeletionBeforeDestroy: []interface{}{
+			map[string]interface{}{
+				"kind": "deploy",
+				"name": "flux",
+			},
+			map[string]interface{}{
+				"kind":           "svc",
+				"all_namespaces": true,
+				"field_selector": "spec.type=LoadBalancer",
+				"timeout_sec":    300,
+			},
+		},
+	})
+
+	deletions, err := ReadDeleteKubernetesResources(d)
+	require.NoError(t, err)
+	assert.Equal(t, []DeleteKubernetesResource{
+		{Kind: "deploy", Namespace: "default", Name: "flux", Timeout: 600 * time.Second},
+		{Kind: "svc", Namespace: "default", AllNamespaces: true, FieldSelector: "spec.type=LoadBalancer", Timeout: 300 * time.Second},
+	}, deletions)
+	assert.Equal(t, "deploy default/flux", deletions[0].String())
+	assert.Equal(t, `svc in all namespaces with fields "spec.type=LoadBalancer"`, deletions[1].String())
+
+	d = schema.TestResourceDataRaw(t, s, map[string]interface{}{
+		KeyKubernetesResourceDeletionBeforeDestroy: []interface{}{
+			map[string]interface{}{
+				"kind": "pvc",
+			},
+		},
+	})
+
+	_, err = ReadDeleteKubernetesResources(d)
+	assert.EqualError(t, err, "kubernetes_resource_deletion_before_destroy[0]: one of name, label_selector and field_selector is required")
+}
+
+func TestRemainingLoadBalancers(t *testing.T) {
+	objs := []unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "web"},
+			"spec":       map[string]interface{}{"type": "LoadBalancer"},
+		}},
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "internal"},
+			"spec":       map[string]interface{}{"type": "ClusterIP"},
+		}},
+		{Object: map[string]interface{}{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "Ingress",
+			"metadata":   map[string]interface{}{"namespace": "app", "name": "api"},
+		}},
+		{Object: map[string]interface{}{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "Ingress",
+			"metadata": map[string]interface{}{"namespace": "app", "name": "shared", "annotations": map[string]interface{}{
+				ingressGroupAnnotation: "shared",
+			}},
+		}},
+	}
+
+	owners := loadBalancerOwners(objs, "prod")
+	require.Len(t, owners, 4)
+
+	lbTags := map[string]map[string]string{
+		// In-tree NLB for default/web
+		"a1b2c3": {"kubernetes.io/cluster/prod": "owned", "kubernetes.io/service-name": "default/web"},
+		// ALB for app/api created by AWS Load Balancer Controller
+		"k8s-app-api": {"elbv2.k8s.aws/cluster": "prod", "ingress.k8s.aws/stack": "app/api"},
+		// ALB for the same ingress in another cluster
+		"k8s-app-api-2": {"elbv2.k8s.aws/cluster": "staging", "ingress.k8s.aws/stack": "app/api"},
+		// ALB shared within the ingress group
+		"k8s-shared": {"elbv2.k8s.aws/cluster": "prod", "ingress.k8s.aws/stack": "shared"},
+		"unrelated":  {},
+	}
+
+	assert.Equal(t, []string{
+		"load balancer a1b2c3 for Service default/web",
+		"load balancer k8s-app-api for Ingress app/api",
+	}, remainingLoadBalancers(owners, lbTags))
+}
+
+// classicELB is the fake Classic ELB API serving the given load balancers and their tags
+type classicELB struct {
+	elbiface.ELBAPI
+
+	tags map[string]map[string]string
+
+	describeTagsCalls int
+}
+
+func (m *classicELB) DescribeLoadBalancersPages(_ *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool) error {
+	out := &elb.DescribeLoadBalancersOutput{}
+
+	for name := range m.tags {
+		out.LoadBalancerDescriptions = append(out.LoadBalancerDescriptions, &elb.LoadBalancerDescription{LoadBalancerName: aws.String(name)})
+	}
+
+	fn(out, true)
+
+	return nil
+}
+
+func (m *classicELB) DescribeTags(in *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
+	m.describeTagsCalls++
+
+	if len(in.LoadBalancerNames) > 20 {
+		return nil, fmt.Errorf("too many load balancer names: %d", len(in.LoadBalancerNames))
+	}
+
+	out := &elb.DescribeTagsOutput{}
+
+	for _, name := range in.LoadBalancerNames {
+		d := &elb.TagDescription{LoadBalancerName: name}
+
+		for k, v := range m.tags[aws.StringValue(name)] {
+			d.Tags = append(d.Tags, &elb.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		out.TagDescriptions = append(out.TagDescriptions, d)
+	}
+
+	return out, nil
+}
+
+func TestGetClassicLoadBalancerTags(t *testing.T) {
+	svc := &classicELB{tags: map[string]map[string]string{
+		// In-tree Classic ELB for default/web
+		"a1b2c3": {"kubernetes.io/cluster/prod": "owned", "kubernetes.io/service-name": "default/web"},
+	}}
+
+	for i := 0; i < 20; i++ {
+		svc.tags[fmt.Sprintf("unrelated-%d", i)] = map[string]string{}
+	}
+
+	lbTags, err := getClassicLoadBalancerTags(svc)
+	require.NoError(t, err)
+
+	assert.Len(t, lbTags, 21)
+	assert.Equal(t, 2, svc.describeTagsCalls)
+
+	objs := []unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"namespace": "default", "name": "web"},
+			"spec":       map[string]interface{}{"type": "LoadBalancer"},
+		}},
+	}
+
+	assert.Equal(t, []string{
+		"load balancer a1b2c3 for Service default/web",
+	}, remainingLoadBalancers(loadBalancerOwners(objs, "prod"), lbTags))
+}
+
+// elbv2API is the fake ELBV2 API serving the given load balancers and their tags, keyed by load balancer name
+type elbv2API struct {
+	elbv2iface.ELBV2API
+
+	tags map[string]map[string]string
+
+	describeTagsCalls int
+}
+
+func (m *elbv2API) DescribeLoadBalancersPages(_ *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool) error {
+	out := &elbv2.DescribeLoadBalancersOutput{}
+
+	for name := range m.tags {
+		out.LoadBalancers = append(out.LoadBalancers, &elbv2.LoadBalancer{
+			LoadBalancerArn:  aws.String("arn:aws:elasticloadbalancing:us-east-2:123456789012:loadbalancer/app/" + name),
+			LoadBalancerName: aws.String(name),
+		})
+	}
+
+	fn(out, true)
+
+	return nil
+}
+
+func (m *elbv2API) DescribeTags(in *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	m.describeTagsCalls++
+
+	if len(in.ResourceArns) > 20 {
+		return nil, fmt.Errorf("too many resource arns: %d", len(in.ResourceArns))
+	}
+
+	out := &elbv2.DescribeTagsOutput{}
+
+	for _, arn := range in.ResourceArns {
+		d := &elbv2.TagDescription{ResourceArn: arn}
+
+		name := strings.TrimPrefix(aws.StringValue(arn), "arn:aws:elasticloadbalancing:us-east-2:123456789012:loadbalancer/app/")
+
+		for k, v := range m.tags[name] {
+			d.Tags = append(d.Tags, &elbv2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+
+		out.TagDescriptions = append(out.TagDescriptions, d)
+	}
+
+	return out, nil
+}
+
+func TestGetLoadBalancerTags(t *testing.T) {
+	svc := &elbv2API{tags: map[string]map[string]string{
+		// ALB for the Ingress app/api
+		"k8s-app-api": {"elbv2.k8s.aws/cluster": "prod", "ingress.k8s.aws/stack": "app/api"},
+	}}
+
+	for i := 0; i < 20; i++ {
+		svc.tags[fmt.Sprintf("unrelated-%d", i)] = map[string]string{}
+	}
+
+	classic := &classicELB{tags: map[string]map[string]string{
+		"a1b2c3": {"kubernetes.io/cluster/prod": "owned", "kubernetes.io/service-name": "default/web"},
+	}}
+
+	lbTags, err := getLoadBalancerTags(svc, classic)
+	require.NoError(t, err)
+
+	assert.Len(t, lbTags, 22)
+	assert.Equal(t, 2, svc.describeTagsCalls)
+	assert.Equal(t, map[string]string{"elbv2.k8s.aws/cluster": "prod", "ingress.k8s.aws/stack": "app/api"}, lbTags["k8s-app-api"])
+	assert.Equal(t, "default/web", lbTags["a1b2c3"]["kubernetes.io/service-name"])
+}
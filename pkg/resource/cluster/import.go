@@ -1,10 +1,18 @@
 package cluster
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"log"
+	"sort"
 	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"gopkg.in/yaml.v3"
 )
 
 func (m *Manager) importCluster(d *schema.ResourceData) (*schema.ResourceData, error) {
@@ -21,7 +29,8 @@ func (m *Manager) importCluster(d *schema.ResourceData) (*schema.ResourceData, e
 	}
 
 	type resourceVpcConfig struct {
-		VpcId string `json:"VpcId"`
+		VpcId     string   `json:"VpcId"`
+		SubnetIds []string `json:"SubnetIds"`
 	}
 
 	type cluster struct {
@@ -72,5 +81,278 @@ func (m *Manager) importCluster(d *schema.ResourceData) (*schema.ResourceData, e
 	d.Set(KeyRegion, region)
 	d.Set(KeyVersion, found.Version)
 
+	imported := &Cluster{
+		EksctlBin: "eksctl",
+		Name:      clusterName,
+		Region:    region,
+	}
+
+	live, err := readImportedCluster(d, imported, found.ResourceVpcConfig.SubnetIds)
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster %s to reconstruct spec: %w", clusterName, err)
+	}
+
+	spec, warnings, err := buildImportedSpec(live)
+	if err != nil {
+		return nil, fmt.Errorf("reconstructing spec of cluster %s: %w", clusterName, err)
+	}
+
+	for _, w := range warnings {
+		log.Printf("[WARN] importing cluster %s: %s", clusterName, w)
+	}
+
+	d.Set(KeySpec, spec)
+
 	return d, nil
 }
+
+// importedCluster is the live state of the cluster that the spec is reconstructed from.
+type importedCluster struct {
+	Subnets         []importedSubnet
+	NodeGroups      []nodeGroupSummary
+	FargateProfiles []importedFargateProfile
+	ServiceAccounts []importedServiceAccount
+
+	// Errors are failures in reading fargateprofiles and iamserviceaccounts, which are reported as warnings.
+	// The update never deletes undeclared fargateprofiles, and deletes undeclared iamserviceaccounts only when
+	// iam.withOIDC is enabled, so omitting them from the spec is safe.
+	Errors []string
+}
+
+type importedSubnet struct {
+	ID               string
+	AvailabilityZone string
+	Public           bool
+
+	// ClassifiedByTag is true when the subnet is public or private according to the
+	// kubernetes.io/role/elb or kubernetes.io/role/internal-elb tag
+	ClassifiedByTag bool
+}
+
+type importedFargateProfile struct {
+	Name                string                    `json:"name" yaml:"name"`
+	PodExecutionRoleARN string                    `json:"podExecutionRoleARN,omitempty" yaml:"podExecutionRoleARN,omitempty"`
+	Selectors           []importedFargateSelector `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+	Subnets             []string                  `json:"subnets,omitempty" yaml:"subnets,omitempty"`
+}
+
+type importedFargateSelector struct {
+	Namespace string            `json:"namespace" yaml:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type importedServiceAccount struct {
+	Metadata struct {
+		Name      string `json:"name" yaml:"name"`
+		Namespace string `json:"namespace" yaml:"namespace"`
+	} `json:"metadata" yaml:"metadata"`
+	AttachPolicyARNs []string `json:"attachPolicyARNs,omitempty" yaml:"attachPolicyARNs,omitempty"`
+	AttachRoleARN    string   `json:"attachRoleARN,omitempty" yaml:"attachRoleARN,omitempty"`
+	Status           *struct {
+		RoleARN string `json:"roleARN" yaml:"-"`
+	} `json:"status,omitempty" yaml:"-"`
+}
+
+func readImportedCluster(d *schema.ResourceData, cluster *Cluster, subnetIDs []string) (*importedCluster, error) {
+	ctx := mustNewContext(cluster)
+
+	var live importedCluster
+
+	subnets, err := describeImportedSubnets(cluster, subnetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	live.Subnets = subnets
+
+	// Nodegroups are required, as nodegroups missing in the spec are deleted on the next apply
+	if err := runEksctlGet(ctx, d, cluster, "nodegroup", &live.NodeGroups); err != nil {
+		return nil, err
+	}
+
+	if err := runEksctlGet(ctx, d, cluster, "fargateprofile", &live.FargateProfiles); err != nil {
+		live.Errors = append(live.Errors, fmt.Sprintf("fargateProfiles could not be read: %v", err))
+	}
+
+	if err := runEksctlGet(ctx, d, cluster, "iamserviceaccount", &live.ServiceAccounts); err != nil {
+		live.Errors = append(live.Errors, fmt.Sprintf("iam.serviceAccounts could not be read: %v", err))
+	}
+
+	return &live, nil
+}
+
+// describeImportedSubnets returns the availability zone of each subnet, and whether it is public or private
+// judging from the tags that eksctl and the load balancer controllers use, or from mapPublicIpOnLaunch.
+func describeImportedSubnets(cluster *Cluster, subnetIDs []string) ([]importedSubnet, error) {
+	if len(subnetIDs) == 0 {
+		return nil, nil
+	}
+
+	out, err := sdk.NewClients(AWSSessionFromCluster(cluster)).EC2().DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing subnets: %w", err)
+	}
+
+	var subnets []importedSubnet
+
+	for _, s := range out.Subnets {
+		subnet := importedSubnet{
+			ID:               aws.StringValue(s.SubnetId),
+			AvailabilityZone: aws.StringValue(s.AvailabilityZone),
+			Public:           aws.BoolValue(s.MapPublicIpOnLaunch),
+		}
+
+		for _, t := range s.Tags {
+			switch aws.StringValue(t.Key) {
+			case "kubernetes.io/role/elb":
+				subnet.Public = true
+				subnet.ClassifiedByTag = true
+			case "kubernetes.io/role/internal-elb":
+				subnet.Public = false
+				subnet.ClassifiedByTag = true
+			}
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	sort.Slice(subnets, func(i, j int) bool { return subnets[i].ID < subnets[j].ID })
+
+	return subnets, nil
+}
+
+type importedSpec struct {
+	VPC               *importedVPC             `yaml:"vpc,omitempty"`
+	IAM               *importedIAM             `yaml:"iam,omitempty"`
+	NodeGroups        []importedNodeGroup      `yaml:"nodeGroups,omitempty"`
+	ManagedNodeGroups []importedNodeGroup      `yaml:"managedNodeGroups,omitempty"`
+	FargateProfiles   []importedFargateProfile `yaml:"fargateProfiles,omitempty"`
+}
+
+type importedVPC struct {
+	Subnets importedSubnets `yaml:"subnets"`
+}
+
+type importedSubnets struct {
+	Public  map[string]Subnet `yaml:"public,omitempty"`
+	Private map[string]Subnet `yaml:"private,omitempty"`
+}
+
+type importedIAM struct {
+	WithOIDC        bool                     `yaml:"withOIDC"`
+	ServiceAccounts []importedServiceAccount `yaml:"serviceAccounts"`
+}
+
+type importedNodeGroup struct {
+	Name            string `yaml:"name"`
+	InstanceType    string `yaml:"instanceType,omitempty"`
+	MinSize         int    `yaml:"minSize"`
+	MaxSize         int    `yaml:"maxSize"`
+	DesiredCapacity int    `yaml:"desiredCapacity"`
+}
+
+// importedSpecUnrecoverable is what `eksctl get` and the cluster's VPC configuration don't tell.
+var importedSpecUnrecoverable = []string{
+	"nodegroup settings other than instanceType, minSize, maxSize and desiredCapacity, like labels, taints, tags, volumes, ssh and iam",
+	"addons, cloudWatch, secretsEncryption, kubernetesNetworkConfig and git",
+}
+
+// buildImportedSpec reconstructs the spec from the live cluster, so that planning after import doesn't delete
+// nodegroups and iamserviceaccounts. The returned warnings list what could not be recovered, and are also
+// written as a comment at the top of the spec.
+func buildImportedSpec(live *importedCluster) (string, []string, error) {
+	var spec importedSpec
+
+	warnings := append([]string{}, importedSpecUnrecoverable...)
+
+	warnings = append(warnings, live.Errors...)
+
+	if len(live.Subnets) > 0 {
+		vpc := &importedVPC{Subnets: importedSubnets{Public: map[string]Subnet{}, Private: map[string]Subnet{}}}
+
+		for _, s := range live.Subnets {
+			subnets, kind := vpc.Subnets.Private, "private"
+			if s.Public {
+				subnets, kind = vpc.Subnets.Public, "public"
+			}
+
+			if !s.ClassifiedByTag {
+				warnings = append(warnings, fmt.Sprintf("subnet %s is assumed to be %s by mapPublicIpOnLaunch, as it has neither kubernetes.io/role/elb nor kubernetes.io/role/internal-elb tag", s.ID, kind))
+			}
+
+			if existing, ok := subnets[s.AvailabilityZone]; ok {
+				warnings = append(warnings, fmt.Sprintf("subnet %s is omitted, as %s subnet %s is in the same availability zone %s", s.ID, kind, existing.ID, s.AvailabilityZone))
+
+				continue
+			}
+
+			subnets[s.AvailabilityZone] = Subnet{ID: s.ID}
+		}
+
+		spec.VPC = vpc
+	}
+
+	for _, ng := range live.NodeGroups {
+		n := importedNodeGroup{
+			Name:            ng.Name,
+			InstanceType:    ng.InstanceType,
+			MinSize:         ng.MinSize,
+			MaxSize:         ng.MaxSize,
+			DesiredCapacity: ng.DesiredCapacity,
+		}
+
+		switch ng.NodeGroupType {
+		case "managed":
+			spec.ManagedNodeGroups = append(spec.ManagedNodeGroups, n)
+		case "unmanaged":
+			spec.NodeGroups = append(spec.NodeGroups, n)
+		default:
+			warnings = append(warnings, fmt.Sprintf("nodegroup %s is assumed to be unmanaged, as eksctl didn't report the nodegroup type", ng.Name))
+
+			spec.NodeGroups = append(spec.NodeGroups, n)
+		}
+	}
+
+	if len(live.ServiceAccounts) > 0 {
+		iam := &importedIAM{WithOIDC: true}
+
+		for _, sa := range live.ServiceAccounts {
+			name := serviceAccountName(sa.Metadata.Namespace, sa.Metadata.Name)
+
+			if len(sa.AttachPolicyARNs) == 0 && sa.AttachRoleARN == "" {
+				if sa.Status == nil || sa.Status.RoleARN == "" {
+					warnings = append(warnings, fmt.Sprintf("iamserviceaccount %s is declared without any policy or role, as its IAM role could not be read", name))
+				} else {
+					sa.AttachRoleARN = sa.Status.RoleARN
+
+					warnings = append(warnings, fmt.Sprintf("iamserviceaccount %s is declared with attachRoleARN of the existing role, as its policies could not be recovered", name))
+				}
+			}
+
+			iam.ServiceAccounts = append(iam.ServiceAccounts, sa)
+		}
+
+		spec.IAM = iam
+	}
+
+	spec.FargateProfiles = live.FargateProfiles
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# Reconstructed by terraform import. The following could not be recovered:\n")
+
+	for _, w := range warnings {
+		buf.WriteString("# - " + w + "\n")
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(spec); err != nil {
+		return "", nil, err
+	}
+
+	return buf.String(), warnings, nil
+}
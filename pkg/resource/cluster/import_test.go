@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildImportedSpec(t *testing.T) {
+	var live importedCluster
+
+	// Trimmed outputs of `eksctl get nodegroup|iamserviceaccount|fargateprofile -o json`
+	require.NoError(t, json.Unmarshal([]byte(`[
+  {"Name": "ng1", "InstanceType": "m5.large", "MinSize": 1, "MaxSize": 3, "DesiredCapacity": 2, "NodeGroupType": "unmanaged"},
+  {"Name": "mng1", "InstanceType": "t3.large", "MinSize": 0, "MaxSize": 2, "DesiredCapacity": 1, "NodeGroupType": "managed"},
+  {"Name": "ng2", "InstanceType": "c5.large", "MinSize": 1, "MaxSize": 1, "DesiredCapacity": 1}
+]`), &live.NodeGroups))
+	require.NoError(t, json.Unmarshal([]byte(`[
+  {"metadata": {"name": "s3-reader", "namespace": "default"}, "status": {"roleARN": "arn:aws:iam::123456789012:role/s3-reader"}}
+]`), &live.ServiceAccounts))
+	require.NoError(t, json.Unmarshal([]byte(`[
+  {"name": "fp1", "podExecutionRoleARN": "arn:aws:iam::123456789012:role/fp1", "selectors": [{"namespace": "serverless", "labels": {"app": "a"}}], "subnets": ["subnet-2"]}
+]`), &live.FargateProfiles))
+
+	live.Subnets = []importedSubnet{
+		{ID: "subnet-1", AvailabilityZone: "us-east-2a", Public: true, ClassifiedByTag: true},
+		{ID: "subnet-2", AvailabilityZone: "us-east-2a", Public: false, ClassifiedByTag: true},
+		{ID: "subnet-3", AvailabilityZone: "us-east-2b", Public: false},
+		{ID: "subnet-4", AvailabilityZone: "us-east-2b", Public: false, ClassifiedByTag: true},
+	}
+
+	spec, warnings, err := buildImportedSpec(&live)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(spec, "# Reconstructed by terraform import."), spec)
+
+	// The update deletes nodegroups and iamserviceaccounts missing in the spec, so all of them must be declared
+	summary, err := parseClusterConfigSummary(spec)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ng1", "ng2", "mng1"}, summary.nodeGroupNames())
+	assert.Equal(t, []string{"default/s3-reader"}, summary.serviceAccountNames())
+
+	var parsed struct {
+		VPC struct {
+			Subnets struct {
+				Public  map[string]Subnet `yaml:"public"`
+				Private map[string]Subnet `yaml:"private"`
+			} `yaml:"subnets"`
+		} `yaml:"vpc"`
+		NodeGroups []struct {
+			Name            string `yaml:"name"`
+			InstanceType    string `yaml:"instanceType"`
+			MinSize         int    `yaml:"minSize"`
+			MaxSize         int    `yaml:"maxSize"`
+			DesiredCapacity int    `yaml:"desiredCapacity"`
+		} `yaml:"nodeGroups"`
+		IAM struct {
+			WithOIDC        bool `yaml:"withOIDC"`
+			ServiceAccounts []struct {
+				Metadata struct {
+					Name      string `yaml:"name"`
+					Namespace string `yaml:"namespace"`
+				} `yaml:"metadata"`
+				AttachRoleARN string `yaml:"attachRoleARN"`
+			} `yaml:"serviceAccounts"`
+		} `yaml:"iam"`
+		FargateProfiles []importedFargateProfile `yaml:"fargateProfiles"`
+	}
+
+	require.NoError(t, yaml.Unmarshal([]byte(spec), &parsed))
+
+	assert.Equal(t, map[string]Subnet{"us-east-2a": {ID: "subnet-1"}}, parsed.VPC.Subnets.Public)
+	assert.Equal(t, map[string]Subnet{"us-east-2a": {ID: "subnet-2"}, "us-east-2b": {ID: "subnet-3"}}, parsed.VPC.Subnets.Private)
+
+	require.Len(t, parsed.NodeGroups, 2)
+	assert.Equal(t, "m5.large", parsed.NodeGroups[0].InstanceType)
+	assert.Equal(t, 1, parsed.NodeGroups[0].MinSize)
+	assert.Equal(t, 3, parsed.NodeGroups[0].MaxSize)
+	assert.Equal(t, 2, parsed.NodeGroups[0].DesiredCapacity)
+
+	assert.True(t, parsed.IAM.WithOIDC)
+	require.Len(t, parsed.IAM.ServiceAccounts, 1)
+	assert.Equal(t, "s3-reader", parsed.IAM.ServiceAccounts[0].Metadata.Name)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/s3-reader", parsed.IAM.ServiceAccounts[0].AttachRoleARN)
+
+	require.Len(t, parsed.FargateProfiles, 1)
+	assert.Equal(t, "fp1", parsed.FargateProfiles[0].Name)
+	assert.Equal(t, []importedFargateSelector{{Namespace: "serverless", Labels: map[string]string{"app": "a"}}}, parsed.FargateProfiles[0].Selectors)
+
+	joined := strings.Join(warnings, "\n")
+
+	assert.Contains(t, joined, "nodegroup ng2 is assumed to be unmanaged")
+	assert.Contains(t, joined, "subnet subnet-3 is assumed to be private by mapPublicIpOnLaunch")
+	assert.Contains(t, joined, "subnet subnet-4 is omitted")
+	assert.Contains(t, joined, "iamserviceaccount default/s3-reader is declared with attachRoleARN")
+
+	for _, w := range warnings {
+		assert.Contains(t, spec, "# - "+w+"\n")
+	}
+}
+
+func TestBuildImportedSpec_readErrors(t *testing.T) {
+	live := importedCluster{
+		NodeGroups: []nodeGroupSummary{{Name: "ng1", NodeGroupType: "unmanaged"}},
+		Errors:     []string{"fargateProfiles could not be read: boom"},
+	}
+
+	spec, warnings, err := buildImportedSpec(&live)
+	require.NoError(t, err)
+
+	assert.Contains(t, warnings, "fargateProfiles could not be read: boom")
+	assert.NotContains(t, spec, "vpc:")
+	assert.NotContains(t, spec, "iam:")
+	assert.NotContains(t, spec, "fargateProfiles:")
+}
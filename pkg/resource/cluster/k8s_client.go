@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// newKubernetesRESTConfig returns the client config for the cluster, generated in-process from eks.DescribeCluster
+// and pre-signed STS tokens.
+func newKubernetesRESTConfig(cluster *Cluster, clusterName string) (*rest.Config, error) {
+	access, err := getClusterAccess(cluster, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return access.restConfig()
+}
+
+// resettableRESTMapper is a RESTMapper whose cache can be invalidated, like restmapper.DeferredDiscoveryRESTMapper
+type resettableRESTMapper interface {
+	meta.RESTMapper
+
+	Reset()
+}
+
+// kubeClient accesses Kubernetes objects of any kind in the cluster.
+type kubeClient struct {
+	client dynamic.Interface
+	mapper resettableRESTMapper
+
+	// expander is the mapper that also resolves short names like `svc` and `pvc`
+	expander meta.RESTMapper
+}
+
+func newKubeClient(cluster *Cluster, clusterName string) (*kubeClient, error) {
+	config, err := newKubernetesRESTConfig(cluster, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes discovery client: %w", err)
+	}
+
+	cached := memory.NewMemCacheClient(dc)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cached)
+
+	return &kubeClient{
+		client:   client,
+		mapper:   mapper,
+		expander: restmapper.NewShortcutExpander(mapper, cached),
+	}, nil
+}
+
+// resource returns the client for objects of the kind in the namespace, and whether the kind is namespaced.
+// The namespace defaults to `default` for namespaced kinds, and is ignored for cluster-scoped kinds.
+func (c *kubeClient) resource(apiVersion, kind, namespace string) (dynamic.ResourceInterface, bool, error) {
+	gv, err := runtimeschema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, false, err
+	}
+
+	gk := gv.WithKind(kind).GroupKind()
+
+	mapping, err := c.mapper.RESTMapping(gk, gv.Version)
+	if meta.IsNoMatchError(err) {
+		// The kind might be defined by a CustomResourceDefinition that has just been applied
+		c.mapper.Reset()
+
+		mapping, err = c.mapper.RESTMapping(gk, gv.Version)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.client.Resource(mapping.Resource), false, nil
+	}
+
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	return c.client.Resource(mapping.Resource).Namespace(namespace), true, nil
+}
+
+// mappingFor returns the mapping for the kind specified like kubectl does, that is, by a kind, a resource name,
+// a short name, or any of them qualified by the group like `ingresses.networking.k8s.io`.
+func (c *kubeClient) mappingFor(kind string) (*meta.RESTMapping, error) {
+	mapping, err := c.mappingForArg(kind)
+	if meta.IsNoMatchError(err) {
+		c.mapper.Reset()
+
+		mapping, err = c.mappingForArg(kind)
+	}
+
+	return mapping, err
+}
+
+func (c *kubeClient) mappingForArg(kind string) (*meta.RESTMapping, error) {
+	fullySpecified, gr := runtimeschema.ParseResourceArg(strings.ToLower(kind))
+
+	var (
+		gvr runtimeschema.GroupVersionResource
+		err error
+	)
+
+	if fullySpecified != nil {
+		gvr, err = c.expander.ResourceFor(*fullySpecified)
+	}
+
+	if fullySpecified == nil || err != nil {
+		gvr, err = c.expander.ResourceFor(gr.WithVersion(""))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gvk, err := c.expander.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
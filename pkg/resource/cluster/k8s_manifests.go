@@ -1,58 +1,355 @@
 package cluster
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
-	"io/ioutil"
-	"os"
-	"os/exec"
+	"io"
+	"log"
+	"sort"
 	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
-func doApplyKubernetesManifests(ctx *sdk.Context, cluster *Cluster, id string) error {
-	if len(cluster.Manifests) == 0 {
-		return nil
+const (
+	KeyAppliedManifestObjects = "applied_manifest_objects"
+
+	// manifestsFieldManager is the field manager of the server-side apply of manifests
+	manifestsFieldManager = "terraform-provider-eksctl"
+)
+
+// manifestObject identifies a Kubernetes object applied from manifests.
+type manifestObject struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+func (o manifestObject) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s %s %s", o.APIVersion, o.Kind, o.Name)
+	}
+
+	return fmt.Sprintf("%s %s %s/%s", o.APIVersion, o.Kind, o.Namespace, o.Name)
+}
+
+// manifestObjectKey identifies an object regardless of the API version it is applied with.
+type manifestObjectKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o manifestObject) key() manifestObjectKey {
+	var group string
+
+	if gv, err := runtimeschema.ParseGroupVersion(o.APIVersion); err == nil {
+		group = gv.Group
+	}
+
+	return manifestObjectKey{Group: group, Kind: o.Kind, Namespace: o.Namespace, Name: o.Name}
+}
+
+func appliedManifestObjectsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Computed:    true,
+		Description: "The Kubernetes objects applied from manifests. Objects removed from manifests are deleted on the next apply",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"api_version": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"kind": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"namespace": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+func readAppliedManifestObjects(d api.Getter) []manifestObject {
+	var objs []manifestObject
+
+	vs, _ := d.Get(KeyAppliedManifestObjects).([]interface{})
+
+	for _, v := range vs {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		objs = append(objs, manifestObject{
+			APIVersion: m["api_version"].(string),
+			Kind:       m["kind"].(string),
+			Namespace:  m["namespace"].(string),
+			Name:       m["name"].(string),
+		})
+	}
+
+	return objs
+}
+
+func manifestObjectsToState(objs []manifestObject) []interface{} {
+	var vs []interface{}
+
+	for _, o := range objs {
+		vs = append(vs, map[string]interface{}{
+			"api_version": o.APIVersion,
+			"kind":        o.Kind,
+			"namespace":   o.Namespace,
+			"name":        o.Name,
+		})
+	}
+
+	return vs
+}
+
+// parseManifests parses manifests, each of which can contain multiple YAML documents, into objects.
+// Namespaces and CustomResourceDefinitions come first, so that objects depending on them can be applied.
+func parseManifests(manifests []string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+
+	for i, m := range manifests {
+		dec := yaml.NewYAMLOrJSONDecoder(strings.NewReader(m), 4096)
+
+		for j := 0; ; j++ {
+			var o map[string]interface{}
+
+			if err := dec.Decode(&o); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("parsing manifests[%d]: %w", i, err)
+			}
+
+			// Empty document
+			if len(o) == 0 {
+				continue
+			}
+
+			obj := &unstructured.Unstructured{Object: o}
+
+			if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+				return nil, fmt.Errorf("parsing manifests[%d]: document %d: apiVersion, kind and metadata.name are required", i, j)
+			}
+
+			objs = append(objs, obj)
+		}
 	}
 
-	kubeconfig, err := ioutil.TempFile("", "terraform-provider-eksctl-kubeconfig-")
+	sort.SliceStable(objs, func(i, j int) bool {
+		return manifestKindOrder(objs[i].GetKind()) < manifestKindOrder(objs[j].GetKind())
+	})
+
+	return objs, nil
+}
+
+func manifestKindOrder(kind string) int {
+	switch kind {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// manifestObjectsToPrune returns previously applied objects that are no longer in manifests, in the reverse order of
+// the application. Objects are compared without versions, so that bumping the apiVersion of a manifest doesn't
+// delete the object just applied, and without namespaces when namespaces are omitted in manifests, as
+// the default namespace is filled only on apply.
+func manifestObjectsToPrune(prev []manifestObject, objs []*unstructured.Unstructured) []manifestObject {
+	desired := map[manifestObjectKey]bool{}
+
+	for _, o := range objs {
+		desired[manifestObject{APIVersion: o.GetAPIVersion(), Kind: o.GetKind(), Namespace: o.GetNamespace(), Name: o.GetName()}.key()] = true
+	}
+
+	var prune []manifestObject
+
+	for i := len(prev) - 1; i >= 0; i-- {
+		p := prev[i]
+
+		if desired[p.key()] {
+			continue
+		}
+
+		if p.Namespace == "default" {
+			withoutNS := p.key()
+			withoutNS.Namespace = ""
+
+			if desired[withoutNS] {
+				continue
+			}
+		}
+
+		prune = append(prune, p)
+	}
+
+	return prune
+}
+
+// mergeManifestObjects returns objs followed by objects in others that are not in objs.
+func mergeManifestObjects(objs, others []manifestObject) []manifestObject {
+	merged := append([]manifestObject{}, objs...)
+
+	seen := map[manifestObject]bool{}
+
+	for _, o := range objs {
+		seen[o] = true
+	}
+
+	for _, o := range others {
+		if !seen[o] {
+			merged = append(merged, o)
+		}
+	}
+
+	return merged
+}
+
+// manifestApplier applies objects with server-side apply and deletes pruned objects.
+type manifestApplier struct {
+	kube *kubeClient
+}
+
+func (a *manifestApplier) apply(ctx context.Context, obj *unstructured.Unstructured) (*manifestObject, error) {
+	r, namespaced, err := a.kube.resource(obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	if namespaced && obj.GetNamespace() == "" {
+		obj.SetNamespace(metav1.NamespaceDefault)
+	}
+
+	data, err := obj.MarshalJSON()
 	if err != nil {
+		return nil, err
+	}
+
+	force := true
+
+	if _, err := r.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: manifestsFieldManager, Force: &force}); err != nil {
+		return nil, err
+	}
+
+	return &manifestObject{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}, nil
+}
+
+func (a *manifestApplier) delete(ctx context.Context, o manifestObject) error {
+	r, _, err := a.kube.resource(o.APIVersion, o.Kind, o.Namespace)
+	if meta.IsNoMatchError(err) {
+		log.Printf("[INFO] skipped deleting %s, as the kind no longer exists in the cluster", o)
+
+		return nil
+	} else if err != nil {
 		return err
 	}
 
-	kubeconfigPath := kubeconfig.Name()
+	policy := metav1.DeletePropagationBackground
 
-	if err := kubeconfig.Close(); err != nil {
+	if err := r.Delete(ctx, o.Name, metav1.DeleteOptions{PropagationPolicy: &policy}); err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
 
-	clusterName := cluster.Name + "-" + id
+	return nil
+}
 
-	writeKubeconfigCmd, err := newEksctlCommandWithAWSProfile(cluster, "utils", "write-kubeconfig", "--kubeconfig", kubeconfigPath, "--cluster", clusterName, "--region", cluster.Region)
+// doApplyKubernetesManifests applies manifests to the cluster with server-side apply, and deletes objects applied by
+// the previous apply but removed from manifests since then.
+// The applied objects are recorded in applied_manifest_objects, including ones applied before a failure, so that
+// they can be pruned later.
+func doApplyKubernetesManifests(d api.ReadWrite, cluster *Cluster, clusterName string) error {
+	objs, err := parseManifests(cluster.Manifests)
 	if err != nil {
-		return fmt.Errorf("creating eksctl-utils-write-kubeconfig command: %w", err)
+		return err
+	}
+
+	prev := readAppliedManifestObjects(d)
+
+	if len(objs) == 0 && len(prev) == 0 {
+		return nil
 	}
 
-	if _, err := ctx.Run(writeKubeconfigCmd); err != nil {
+	kube, err := newKubeClient(cluster, clusterName)
+	if err != nil {
 		return err
 	}
 
-	all := strings.Join(cluster.Manifests, "\n---\n")
+	a := &manifestApplier{kube: kube}
 
-	kubectlCmd := exec.Command(cluster.KubectlBin, "apply", "-f", "-")
+	reqCtx := context.Background()
 
-	for _, env := range os.Environ() {
-		if !strings.HasPrefix(env, "KUBECONFIG=") {
-			kubectlCmd.Env = append(kubectlCmd.Env, env)
+	var (
+		applied []manifestObject
+		errs    []string
+	)
+
+	for _, obj := range objs {
+		o, err := a.apply(reqCtx, obj)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("applying %s %s: %v", obj.GetKind(), obj.GetName(), err))
+
+			continue
 		}
+
+		log.Printf("[INFO] applied %s", o)
+
+		applied = append(applied, *o)
 	}
 
-	kubectlCmd.Env = append(kubectlCmd.Env, "KUBECONFIG="+kubeconfigPath)
+	if len(errs) > 0 {
+		// Keep tracking all the previously applied objects, as nothing is pruned on failure
+		if err := d.Set(KeyAppliedManifestObjects, manifestObjectsToState(mergeManifestObjects(applied, prev))); err != nil {
+			log.Printf("[WARN] setting %s: %v", KeyAppliedManifestObjects, err)
+		}
 
-	kubectlCmd.Stdin = bytes.NewBufferString(all)
+		return errors.New(strings.Join(errs, "\n"))
+	}
 
-	if _, err := ctx.Run(kubectlCmd); err != nil {
-		return err
+	var remaining []manifestObject
+
+	for _, p := range manifestObjectsToPrune(prev, objs) {
+		if err := a.delete(reqCtx, p); err != nil {
+			errs = append(errs, fmt.Sprintf("deleting %s: %v", p, err))
+
+			remaining = append(remaining, p)
+
+			continue
+		}
+
+		log.Printf("[INFO] deleted %s, as it was removed from manifests", p)
+	}
+
+	if err := d.Set(KeyAppliedManifestObjects, manifestObjectsToState(append(applied, remaining...))); err != nil {
+		return fmt.Errorf("setting %s: %w", KeyAppliedManifestObjects, err)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
 	}
 
 	return nil
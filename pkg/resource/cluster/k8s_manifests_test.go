@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifests(t *testing.T) {
+	objs, err := parseManifests([]string{`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm1
+  namespace: foo
+---
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo
+`, `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app"}}`})
+	require.NoError(t, err)
+
+	var names []string
+	for _, o := range objs {
+		names = append(names, o.GetKind()+"/"+o.GetName())
+	}
+
+	// Namespaces come first
+	assert.Equal(t, []string{"Namespace/foo", "ConfigMap/cm1", "Deployment/app"}, names)
+
+	_, err = parseManifests([]string{`
+apiVersion: v1
+kind: ConfigMap
+`})
+	assert.EqualError(t, err, "parsing manifests[0]: document 0: apiVersion, kind and metadata.name are required")
+}
+
+func TestManifestObjectsToPrune(t *testing.T) {
+	objs, err := parseManifests([]string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+`})
+	require.NoError(t, err)
+
+	prev := []manifestObject{
+		{APIVersion: "v1", Kind: "Namespace", Name: "foo"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "foo", Name: "cm1"},
+	}
+
+	// The deployment is kept, as the namespace omitted in the manifest defaults to `default`.
+	// Others are pruned in the reverse order of the application.
+	assert.Equal(t, []manifestObject{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "foo", Name: "cm1"},
+		{APIVersion: "v1", Kind: "Namespace", Name: "foo"},
+	}, manifestObjectsToPrune(prev, objs))
+
+	assert.Equal(t, "v1 ConfigMap foo/cm1", prev[2].String())
+	assert.Equal(t, "v1 Namespace foo", prev[0].String())
+}
+
+func TestManifestObjectsToPrune_apiVersionBumped(t *testing.T) {
+	objs, err := parseManifests([]string{`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: app
+`})
+	require.NoError(t, err)
+
+	prev := []manifestObject{
+		{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "app", Name: "web"},
+		{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "app", Name: "removed"},
+	}
+
+	// The ingress is kept, as only its version changed, and the one removed from manifests is pruned.
+	assert.Equal(t, []manifestObject{
+		{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "app", Name: "removed"},
+	}, manifestObjectsToPrune(prev, objs))
+}
+
+func TestMergeManifestObjects(t *testing.T) {
+	a := manifestObject{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"}
+	b := manifestObject{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "b"}
+
+	assert.Equal(t, []manifestObject{b, a}, mergeManifestObjects([]manifestObject{b}, []manifestObject{a, b}))
+}
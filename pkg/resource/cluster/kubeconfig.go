@@ -0,0 +1,364 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	KeyEndpoint                 = "endpoint"
+	KeyCertificateAuthorityData = "certificate_authority_data"
+	KeyToken                    = "token"
+	KeyKubeconfigRaw            = "kubeconfig_raw"
+
+	// tokenPrefix, clusterIDHeader and tokenExpiration are the same as aws-iam-authenticator and `aws eks get-token`,
+	// which EKS expects
+	tokenPrefix     = "k8s-aws-v1."
+	clusterIDHeader = "x-k8s-aws-id"
+	tokenExpiration = 15 * time.Minute
+
+	// tokenRefreshMargin is how long before the expiration a token is regenerated
+	tokenRefreshMargin = time.Minute
+)
+
+// clusterAccess is what clients need to access the Kubernetes API of the cluster.
+type clusterAccess struct {
+	ClusterName string
+	Region      string
+
+	Endpoint string
+
+	// CertificateAuthorityData is the base64-encoded CA certificate of the API server
+	CertificateAuthorityData string
+
+	tokens *tokenSource
+}
+
+// getClusterAccess reads the endpoint and the CA of the cluster via eks.DescribeCluster.
+func getClusterAccess(cluster *Cluster, clusterName string) (*clusterAccess, error) {
+	sess := AWSSessionFromCluster(cluster)
+
+	out, err := sdk.NewClients(sess).EKS().DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if err != nil {
+		return nil, fmt.Errorf("describing eks cluster %s: %w", clusterName, err)
+	}
+
+	c := out.Cluster
+
+	if aws.StringValue(c.Endpoint) == "" || c.CertificateAuthority == nil {
+		return nil, fmt.Errorf("eks cluster %s has no endpoint yet in status %s", clusterName, aws.StringValue(c.Status))
+	}
+
+	return &clusterAccess{
+		ClusterName:              clusterName,
+		Region:                   cluster.Region,
+		Endpoint:                 aws.StringValue(c.Endpoint),
+		CertificateAuthorityData: aws.StringValue(c.CertificateAuthority.Data),
+		tokens:                   &tokenSource{sess: sess, clusterName: clusterName, now: time.Now},
+	}, nil
+}
+
+// restConfig returns the client config that authenticates with a token regenerated before it expires,
+// so that long-running operations like readiness checks keep working.
+func (a *clusterAccess) restConfig() (*rest.Config, error) {
+	ca, err := base64.StdEncoding.DecodeString(a.CertificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("decoding certificate authority data of cluster %s: %w", a.ClusterName, err)
+	}
+
+	return &rest.Config{
+		Host: a.Endpoint,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: ca,
+		},
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			return &tokenRoundTripper{tokens: a.tokens, rt: rt}
+		},
+	}, nil
+}
+
+// kubeconfig returns the kubeconfig that obtains tokens with `aws eks get-token`, so that it keeps working
+// after the token exposed by the `token` attribute expires.
+func (a *clusterAccess) kubeconfig(cluster *Cluster) (*clientcmdapi.Config, error) {
+	ca, err := base64.StdEncoding.DecodeString(a.CertificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("decoding certificate authority data of cluster %s: %w", a.ClusterName, err)
+	}
+
+	args := []string{"eks", "get-token", "--cluster-name", a.ClusterName, "--region", a.Region}
+
+	if cluster.AssumeRoleConfig != nil && cluster.AssumeRoleConfig.RoleARN != "" {
+		args = append(args, "--role-arn", cluster.AssumeRoleConfig.RoleARN)
+	}
+
+	var env []clientcmdapi.ExecEnvVar
+
+	if cluster.Profile != "" {
+		env = append(env, clientcmdapi.ExecEnvVar{Name: "AWS_PROFILE", Value: cluster.Profile})
+	}
+
+	name := a.ClusterName + "." + a.Region + ".eksctl.io"
+
+	config := clientcmdapi.NewConfig()
+
+	config.Clusters[name] = &clientcmdapi.Cluster{
+		Server:                   a.Endpoint,
+		CertificateAuthorityData: ca,
+	}
+
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion: "client.authentication.k8s.io/v1beta1",
+			Command:    "aws",
+			Args:       args,
+			Env:        env,
+		},
+	}
+
+	config.Contexts[name] = &clientcmdapi.Context{
+		Cluster:  name,
+		AuthInfo: name,
+	}
+
+	config.CurrentContext = name
+
+	return config, nil
+}
+
+// mergeKubeconfig adds the cluster, the user and the context in config to the kubeconfig file at path,
+// keeping other entries like `eksctl utils write-kubeconfig` does. The context is made current when switchContext is true
+// or the file has no current context.
+func mergeKubeconfig(path string, config *clientcmdapi.Config, switchContext bool) error {
+	existing := clientcmdapi.NewConfig()
+
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		existing, err = clientcmd.LoadFromFile(path)
+		if err != nil {
+			return fmt.Errorf("loading kubeconfig %s: %w", path, err)
+		}
+	}
+
+	for k, v := range config.Clusters {
+		existing.Clusters[k] = v
+	}
+
+	for k, v := range config.AuthInfos {
+		existing.AuthInfos[k] = v
+	}
+
+	for k, v := range config.Contexts {
+		existing.Contexts[k] = v
+	}
+
+	if switchContext || existing.CurrentContext == "" {
+		existing.CurrentContext = config.CurrentContext
+	}
+
+	return clientcmd.WriteToFile(*existing, path)
+}
+
+// tokenSource generates EKS authentication tokens, that are pre-signed URLs of sts:GetCallerIdentity
+// identifying the cluster, and caches each until shortly before it expires.
+type tokenSource struct {
+	sess        *session.Session
+	clusterName string
+
+	mu         sync.Mutex
+	token      string
+	expiration time.Time
+
+	now func() time.Time
+}
+
+func (s *tokenSource) Token() (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Before(s.expiration.Add(-tokenRefreshMargin)) {
+		return s.token, s.expiration, nil
+	}
+
+	req, _ := sdk.NewClients(s.sess).STS().GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.HTTPRequest.Header.Add(clusterIDHeader, s.clusterName)
+
+	url, err := req.Presign(tokenExpiration)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("presigning sts:GetCallerIdentity for cluster %s: %w", s.clusterName, err)
+	}
+
+	s.token = tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(url))
+	s.expiration = s.now().Add(tokenExpiration)
+
+	return s.token, s.expiration, nil
+}
+
+type tokenRoundTripper struct {
+	tokens *tokenSource
+	rt     http.RoundTripper
+}
+
+func (t *tokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, _, err := t.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.rt.RoundTrip(req)
+}
+
+// doWriteKubeconfig sets endpoint, certificate_authority_data, token and kubeconfig_raw of the cluster,
+// and writes the kubeconfig to kubeconfig_path, which defaults to a temporary file.
+func doWriteKubeconfig(d api.ReadWrite, cluster *Cluster, clusterName string) error {
+	return writeKubeconfig(d, cluster, clusterName, kubeconfigWrite{overwrite: true, switchContext: true})
+}
+
+// doRefreshKubeconfig is doWriteKubeconfig on read, which writes the kubeconfig only when kubeconfig_path is missing,
+// so that refreshes don't keep rewriting the user's kubeconfig.
+func doRefreshKubeconfig(d api.ReadWrite, cluster *Cluster, clusterName string) error {
+	return writeKubeconfig(d, cluster, clusterName, kubeconfigWrite{switchContext: true})
+}
+
+// doReadKubeconfig is doWriteKubeconfig for the data source, which is read on every plan.
+// kubeconfig_path defaults to the same file per cluster rather than a new temporary file, and
+// the current context of the kubeconfig at the user-supplied kubeconfig_path is kept.
+func doReadKubeconfig(d api.ReadWrite, cluster *Cluster, clusterName string) error {
+	return writeKubeconfig(d, cluster, clusterName, kubeconfigWrite{
+		overwrite: true,
+		defaultPath: func() (string, error) {
+			path := dataSourceKubeconfigPath(cluster.Region, clusterName)
+
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return "", err
+			}
+
+			return path, nil
+		},
+	})
+}
+
+// dataSourceKubeconfigPath is the default kubeconfig_path of the data source for the cluster.
+func dataSourceKubeconfigPath(region, clusterName string) string {
+	return filepath.Join(os.TempDir(), "terraform-provider-eksctl", "kubeconfigs", region, clusterName+".kubeconfig")
+}
+
+type kubeconfigWrite struct {
+	// overwrite writes the kubeconfig even when the file at kubeconfig_path already exists
+	overwrite bool
+	// switchContext makes the context of the cluster current in the kubeconfig at the user-supplied kubeconfig_path.
+	// The context is always made current in the file at the default path.
+	switchContext bool
+	// defaultPath returns the path used when kubeconfig_path is unset. Defaults to a new temporary file.
+	defaultPath func() (string, error)
+}
+
+func writeKubeconfig(d api.ReadWrite, cluster *Cluster, clusterName string, opts kubeconfigWrite) error {
+	access, err := getClusterAccess(cluster, clusterName)
+	if err != nil {
+		return err
+	}
+
+	token, expiration, err := access.tokens.Token()
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := access.kubeconfig(cluster)
+	if err != nil {
+		return fmt.Errorf("generating kubeconfig: %w", err)
+	}
+
+	raw, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return fmt.Errorf("generating kubeconfig: %w", err)
+	}
+
+	for k, v := range map[string]string{
+		KeyEndpoint:                 access.Endpoint,
+		KeyCertificateAuthorityData: access.CertificateAuthorityData,
+		KeyToken:                    token,
+		KeyKubeconfigRaw:            string(raw),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return fmt.Errorf("setting %s: %w", k, err)
+		}
+	}
+
+	log.Printf("[DEBUG] generated token for cluster %s, which expires at %s", clusterName, expiration.Format(time.RFC3339))
+
+	var path string
+
+	if v := d.Get(KeyKubeconfigPath); v != nil {
+		path = v.(string)
+	}
+
+	switchContext := opts.switchContext
+
+	if path == "" {
+		if opts.defaultPath != nil {
+			path, err = opts.defaultPath()
+		} else {
+			path, err = newTempKubeconfigPath()
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed generating kubeconfig path: %w", err)
+		}
+
+		if err := d.Set(KeyKubeconfigPath, path); err != nil {
+			return fmt.Errorf("setting %s: %w", KeyKubeconfigPath, err)
+		}
+
+		switchContext = true
+	}
+
+	if !opts.overwrite && !kubeconfigMissing(path) {
+		return nil
+	}
+
+	if err := mergeKubeconfig(path, kubeconfig, switchContext); err != nil {
+		return fmt.Errorf("writing kubeconfig to %s: %w", path, err)
+	}
+
+	log.Printf("Wrote kubeconfig for cluster %s to %s", clusterName, path)
+
+	return nil
+}
+
+func newTempKubeconfigPath() (string, error) {
+	f, err := ioutil.TempFile(os.TempDir(), "tf-eksctl-kubeconfig")
+	if err != nil {
+		return "", err
+	}
+
+	_ = f.Close()
+
+	return f.Name(), nil
+}
+
+// kubeconfigMissing returns true when the kubeconfig at path needs to be reproduced, like when the state was
+// created on another machine.
+func kubeconfigMissing(path string) bool {
+	info, err := os.Stat(path)
+
+	return err != nil || info.Size() == 0
+}
@@ -0,0 +1,183 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newTestTokenSource(t *testing.T, now *time.Time) *tokenSource {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-2"),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	})
+	require.NoError(t, err)
+
+	return &tokenSource{sess: sess, clusterName: "prod", now: func() time.Time { return *now }}
+}
+
+func TestTokenSource(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newTestTokenSource(t, &now)
+
+	token, expiration, err := s.Token()
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), expiration)
+
+	require.True(t, strings.HasPrefix(token, "k8s-aws-v1."))
+
+	url, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, "k8s-aws-v1."))
+	require.NoError(t, err)
+	assert.Contains(t, string(url), "Action=GetCallerIdentity")
+	assert.Contains(t, string(url), "X-Amz-Expires=900")
+	assert.Contains(t, string(url), "x-k8s-aws-id")
+
+	// The token is reused until shortly before it expires
+	now = now.Add(13 * time.Minute)
+
+	cached, _, err := s.Token()
+	require.NoError(t, err)
+	assert.Equal(t, token, cached)
+
+	now = now.Add(time.Minute)
+
+	_, refreshedExpiration, err := s.Token()
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), refreshedExpiration)
+}
+
+func TestClusterAccess_restConfig(t *testing.T) {
+	var authorization string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	now := time.Now()
+
+	a := &clusterAccess{
+		ClusterName: "prod",
+		Region:      "us-east-2",
+		Endpoint:    server.URL,
+		// Any valid base64 works, as the transport is replaced below
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("ca")),
+		tokens:                   newTestTokenSource(t, &now),
+	}
+
+	config, err := a.restConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ca"), config.TLSClientConfig.CAData)
+
+	client := &http.Client{Transport: config.WrapTransport(server.Client().Transport)}
+
+	res, err := client.Get(server.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	assert.True(t, strings.HasPrefix(authorization, "Bearer k8s-aws-v1."))
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+
+	other := clientcmdapi.NewConfig()
+	other.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://other"}
+	other.AuthInfos["other"] = &clientcmdapi.AuthInfo{Token: "other"}
+	other.Contexts["other"] = &clientcmdapi.Context{Cluster: "other", AuthInfo: "other"}
+	other.CurrentContext = "other"
+
+	require.NoError(t, clientcmd.WriteToFile(*other, path))
+
+	a := &clusterAccess{
+		ClusterName:              "prod",
+		Region:                   "us-east-2",
+		Endpoint:                 "https://prod.eks.amazonaws.com",
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("ca")),
+	}
+
+	config, err := a.kubeconfig(&Cluster{Region: "us-east-2", Profile: "admin"})
+	require.NoError(t, err)
+
+	// The user's current context is kept unless switching the context
+	require.NoError(t, mergeKubeconfig(path, config, false))
+
+	merged, err := clientcmd.LoadFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "other", merged.CurrentContext)
+	assert.Contains(t, merged.Contexts, "prod.us-east-2.eksctl.io")
+
+	require.NoError(t, mergeKubeconfig(path, config, true))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	merged, err = clientcmd.Load(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod.us-east-2.eksctl.io", merged.CurrentContext)
+	assert.Contains(t, merged.Contexts, "other")
+	assert.Equal(t, "https://prod.eks.amazonaws.com", merged.Clusters["prod.us-east-2.eksctl.io"].Server)
+	assert.Equal(t, []byte("ca"), merged.Clusters["prod.us-east-2.eksctl.io"].CertificateAuthorityData)
+
+	exec := merged.AuthInfos["prod.us-east-2.eksctl.io"].Exec
+	require.NotNil(t, exec)
+	assert.Equal(t, "aws", exec.Command)
+	assert.Equal(t, []string{"eks", "get-token", "--cluster-name", "prod", "--region", "us-east-2"}, exec.Args)
+	assert.Equal(t, []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "admin"}}, exec.Env)
+}
+
+func TestMergeKubeconfig_newFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+
+	config := clientcmdapi.NewConfig()
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod"}
+	config.CurrentContext = "prod"
+
+	// The context is made current in the file without any current context
+	require.NoError(t, mergeKubeconfig(path, config, false))
+
+	merged, err := clientcmd.LoadFromFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod", merged.CurrentContext)
+}
+
+func TestDataSourceKubeconfigPath(t *testing.T) {
+	path := dataSourceKubeconfigPath("us-east-2", "prod")
+
+	assert.Equal(t, path, dataSourceKubeconfigPath("us-east-2", "prod"))
+	assert.NotEqual(t, path, dataSourceKubeconfigPath("us-west-2", "prod"))
+	assert.Equal(t, filepath.Join("us-east-2", "prod.kubeconfig"), filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path)))
+}
+
+func TestKubeconfigMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+
+	written := filepath.Join(dir, "kubeconfig")
+	require.NoError(t, clientcmd.WriteToFile(*clientcmdapi.NewConfig(), written))
+
+	assert.True(t, kubeconfigMissing(filepath.Join(dir, "inexistent")))
+	assert.True(t, kubeconfigMissing(empty))
+	assert.False(t, kubeconfigMissing(written))
+}
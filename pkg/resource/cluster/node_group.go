@@ -0,0 +1,385 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+)
+
+const (
+	KeyNodeGroup        = "node_group"
+	KeyManagedNodeGroup = "managed_node_group"
+
+	// unsetSize is the default of size attributes of node groups, which leaves the size to eksctl.
+	// 0 cannot be used for that, as it is a valid size for scaling node groups down to zero.
+	unsetSize = -1
+)
+
+// NodeGroupConfig is the typed configuration of an eksctl nodegroup or managed nodegroup.
+type NodeGroupConfig struct {
+	Name              string
+	InstanceType      string
+	InstanceTypes     []string
+	DesiredCapacity   int
+	MinSize           int
+	MaxSize           int
+	Labels            map[string]string
+	Taints            []Taint
+	VolumeSize        int
+	VolumeType        string
+	AMIFamily         string
+	PrivateNetworking bool
+	AttachPolicyARNs  []string
+	InstanceRoleARN   string
+}
+
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func nodeGroupSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:       schema.TypeList,
+		Optional:   true,
+		ConfigMode: schema.SchemaConfigModeBlock,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"instance_type": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"instance_types": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "Instance types to use instead of `instance_type`. For `node_group`, this results in a mixed-instances node group",
+				},
+				"desired_capacity": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      unsetSize,
+					ValidateFunc: validation.IntAtLeast(unsetSize),
+					Description:  "Defaults to -1, which leaves it to eksctl",
+				},
+				"min_size": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      unsetSize,
+					ValidateFunc: validation.IntAtLeast(unsetSize),
+					Description:  "Defaults to -1, which leaves it to eksctl",
+				},
+				"max_size": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      unsetSize,
+					ValidateFunc: validation.IntAtLeast(unsetSize),
+					Description:  "Defaults to -1, which leaves it to eksctl",
+				},
+				"labels": {
+					Type:     schema.TypeMap,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"taint": {
+					Type:       schema.TypeList,
+					Optional:   true,
+					ConfigMode: schema.SchemaConfigModeBlock,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"key": {
+								Type:     schema.TypeString,
+								Required: true,
+							},
+							"value": {
+								Type:     schema.TypeString,
+								Optional: true,
+								Default:  "",
+							},
+							"effect": {
+								Type:         schema.TypeString,
+								Required:     true,
+								ValidateFunc: validation.StringInSlice([]string{"NoSchedule", "PreferNoSchedule", "NoExecute"}, false),
+							},
+						},
+					},
+				},
+				"volume_size": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "The size of the root volume in GiB. 0 leaves it to eksctl",
+				},
+				"volume_type": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"ami_family": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "",
+					Description: "The AMI family like AmazonLinux2, Ubuntu2004, and Bottlerocket",
+				},
+				"private_networking": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"attach_policy_arns": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"instance_role_arn": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+			},
+		},
+	}
+}
+
+// ReadNodeGroups reads node group blocks at the key.
+func ReadNodeGroups(d api.Getter, key string) ([]NodeGroupConfig, error) {
+	var ngs []NodeGroupConfig
+
+	vs, _ := d.Get(key).([]interface{})
+
+	for _, v := range vs {
+		m := v.(map[string]interface{})
+
+		ng := NodeGroupConfig{
+			Name:              m["name"].(string),
+			InstanceType:      m["instance_type"].(string),
+			DesiredCapacity:   m["desired_capacity"].(int),
+			MinSize:           m["min_size"].(int),
+			MaxSize:           m["max_size"].(int),
+			VolumeSize:        m["volume_size"].(int),
+			VolumeType:        m["volume_type"].(string),
+			AMIFamily:         m["ami_family"].(string),
+			PrivateNetworking: m["private_networking"].(bool),
+			InstanceRoleARN:   m["instance_role_arn"].(string),
+		}
+
+		for _, t := range m["instance_types"].([]interface{}) {
+			ng.InstanceTypes = append(ng.InstanceTypes, t.(string))
+		}
+
+		if ng.InstanceType != "" && len(ng.InstanceTypes) > 0 {
+			return nil, fmt.Errorf("%s %q: only one of instance_type and instance_types can be set", key, ng.Name)
+		}
+
+		if ls, ok := m["labels"].(map[string]interface{}); ok && len(ls) > 0 {
+			ng.Labels = map[string]string{}
+
+			for k, v := range ls {
+				ng.Labels[k] = v.(string)
+			}
+		}
+
+		for _, rt := range m["taint"].([]interface{}) {
+			t := rt.(map[string]interface{})
+
+			ng.Taints = append(ng.Taints, Taint{
+				Key:    t["key"].(string),
+				Value:  t["value"].(string),
+				Effect: t["effect"].(string),
+			})
+		}
+
+		for _, a := range m["attach_policy_arns"].([]interface{}) {
+			ng.AttachPolicyARNs = append(ng.AttachPolicyARNs, a.(string))
+		}
+
+		ngs = append(ngs, ng)
+	}
+
+	return ngs, nil
+}
+
+// toSpec converts the node group to an item of `nodeGroups` or `managedNodeGroups` in the eksctl cluster config.
+func (ng NodeGroupConfig) toSpec(managed bool) map[string]interface{} {
+	m := map[string]interface{}{
+		"name": ng.Name,
+	}
+
+	if ng.InstanceType != "" {
+		m["instanceType"] = ng.InstanceType
+	}
+
+	if len(ng.InstanceTypes) > 0 {
+		if managed {
+			m["instanceTypes"] = ng.InstanceTypes
+		} else {
+			m["instancesDistribution"] = map[string]interface{}{
+				"instanceTypes": ng.InstanceTypes,
+			}
+		}
+	}
+
+	for k, v := range map[string]int{
+		"desiredCapacity": ng.DesiredCapacity,
+		"minSize":         ng.MinSize,
+		"maxSize":         ng.MaxSize,
+	} {
+		if v != unsetSize {
+			m[k] = v
+		}
+	}
+
+	if len(ng.Labels) > 0 {
+		m["labels"] = ng.Labels
+	}
+
+	if len(ng.Taints) > 0 {
+		if managed {
+			var taints []interface{}
+
+			for _, t := range ng.Taints {
+				taints = append(taints, map[string]interface{}{
+					"key":    t.Key,
+					"value":  t.Value,
+					"effect": t.Effect,
+				})
+			}
+
+			m["taints"] = taints
+		} else {
+			// Unmanaged nodegroups accept taints in the form of `key: value:effect`
+			taints := map[string]interface{}{}
+
+			for _, t := range ng.Taints {
+				taints[t.Key] = t.Value + ":" + t.Effect
+			}
+
+			m["taints"] = taints
+		}
+	}
+
+	if ng.VolumeSize > 0 {
+		m["volumeSize"] = ng.VolumeSize
+	}
+
+	if ng.VolumeType != "" {
+		m["volumeType"] = ng.VolumeType
+	}
+
+	if ng.AMIFamily != "" {
+		m["amiFamily"] = ng.AMIFamily
+	}
+
+	if ng.PrivateNetworking {
+		m["privateNetworking"] = true
+	}
+
+	iam := map[string]interface{}{}
+
+	if len(ng.AttachPolicyARNs) > 0 {
+		iam["attachPolicyARNs"] = ng.AttachPolicyARNs
+	}
+
+	if ng.InstanceRoleARN != "" {
+		iam["instanceRoleARN"] = ng.InstanceRoleARN
+	}
+
+	if len(iam) > 0 {
+		m["iam"] = iam
+	}
+
+	return m
+}
+
+// mergeNodeGroups appends node groups declared in `node_group` and `managed_node_group` blocks to the spec.
+// It fails when any node group has the same name as another, either declared in the blocks or in the spec,
+// as eksctl requires names to be unique across nodeGroups and managedNodeGroups.
+func mergeNodeGroups(spec map[string]interface{}, nodeGroups, managedNodeGroups []NodeGroupConfig) error {
+	if len(nodeGroups) == 0 && len(managedNodeGroups) == 0 {
+		return nil
+	}
+
+	// declared maps node group names to where they are declared, for error messages
+	declared := map[string]string{}
+
+	for _, specKey := range []string{"nodeGroups", "managedNodeGroups"} {
+		items, err := specNodeGroups(spec, specKey)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			if name, ok := nodeGroupName(item); ok {
+				declared[name] = fmt.Sprintf("%s[%d] in %s", specKey, i, KeySpec)
+			}
+		}
+	}
+
+	for _, g := range []struct {
+		key, specKey string
+		managed      bool
+		ngs          []NodeGroupConfig
+	}{
+		{KeyNodeGroup, "nodeGroups", false, nodeGroups},
+		{KeyManagedNodeGroup, "managedNodeGroups", true, managedNodeGroups},
+	} {
+		if len(g.ngs) == 0 {
+			continue
+		}
+
+		items, err := specNodeGroups(spec, g.specKey)
+		if err != nil {
+			return err
+		}
+
+		for _, ng := range g.ngs {
+			if where, ok := declared[ng.Name]; ok {
+				return fmt.Errorf("%s %q conflicts with the node group of the same name declared in %s", g.key, ng.Name, where)
+			}
+
+			declared[ng.Name] = fmt.Sprintf("%s block", g.key)
+
+			items = append(items, ng.toSpec(g.managed))
+		}
+
+		spec[g.specKey] = items
+	}
+
+	return nil
+}
+
+func specNodeGroups(spec map[string]interface{}, specKey string) ([]interface{}, error) {
+	v, ok := spec[specKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s in %s must be a list: got %T", specKey, KeySpec, v)
+	}
+
+	return items, nil
+}
+
+func nodeGroupName(item interface{}) (string, bool) {
+	switch m := item.(type) {
+	case map[string]interface{}:
+		name, ok := m["name"].(string)
+		return name, ok
+	case map[interface{}]interface{}:
+		name, ok := m["name"].(string)
+		return name, ok
+	}
+
+	return "", false
+}
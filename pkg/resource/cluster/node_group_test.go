@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeNodeGroups(t *testing.T) {
+	spec := map[string]interface{}{}
+
+	require.NoError(t, yaml.Unmarshal([]byte(`
+nodeGroups:
+- name: ng1
+  instanceType: m5.large
+`), spec))
+
+	err := mergeNodeGroups(spec,
+		[]NodeGroupConfig{
+			{
+				Name:             "ng2",
+				InstanceTypes:    []string{"m5.large", "m5a.large"},
+				DesiredCapacity:  0,
+				MinSize:          0,
+				MaxSize:          3,
+				Labels:           map[string]string{"role": "worker"},
+				Taints:           []Taint{{Key: "dedicated", Value: "worker", Effect: "NoSchedule"}},
+				AttachPolicyARNs: []string{"arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy"},
+			},
+		},
+		[]NodeGroupConfig{
+			{
+				Name:            "mng1",
+				InstanceType:    "t3.medium",
+				DesiredCapacity: unsetSize,
+				MinSize:         unsetSize,
+				MaxSize:         unsetSize,
+				Taints:          []Taint{{Key: "dedicated", Effect: "NoExecute"}},
+				VolumeSize:      50,
+				AMIFamily:       "AmazonLinux2",
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	require.NoError(t, enc.Encode(spec))
+
+	assert.Equal(t, strings.TrimLeft(`
+managedNodeGroups:
+  - amiFamily: AmazonLinux2
+    instanceType: t3.medium
+    name: mng1
+    taints:
+      - effect: NoExecute
+        key: dedicated
+        value: ""
+    volumeSize: 50
+nodeGroups:
+  - instanceType: m5.large
+    name: ng1
+  - desiredCapacity: 0
+    iam:
+      attachPolicyARNs:
+        - arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy
+    instancesDistribution:
+      instanceTypes:
+        - m5.large
+        - m5a.large
+    labels:
+      role: worker
+    maxSize: 3
+    minSize: 0
+    name: ng2
+    taints:
+      dedicated: worker:NoSchedule
+`, "\n"), buf.String())
+}
+
+func TestMergeNodeGroups_Conflict(t *testing.T) {
+	spec := map[string]interface{}{}
+
+	require.NoError(t, yaml.Unmarshal([]byte(`
+managedNodeGroups:
+- name: ng1
+`), spec))
+
+	err := mergeNodeGroups(spec, []NodeGroupConfig{{Name: "ng1"}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `node_group "ng1" conflicts with the node group of the same name declared in managedNodeGroups[0] in spec`)
+
+	err = mergeNodeGroups(map[string]interface{}{}, []NodeGroupConfig{{Name: "ng2"}}, []NodeGroupConfig{{Name: "ng2"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `managed_node_group "ng2" conflicts with the node group of the same name declared in node_group block`)
+}
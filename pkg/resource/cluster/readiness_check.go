@@ -0,0 +1,571 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	KeyReadinessCheck = "readiness_check"
+
+	readinessCheckPollInterval = 5 * time.Second
+)
+
+// readinessAPIVersions is the default apiVersion of each kind supported by readiness checks without jsonpath.
+var readinessAPIVersions = map[string]string{
+	"Deployment":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"ReplicaSet":  "apps/v1",
+	"Job":         "batch/v1",
+	"Pod":         "v1",
+}
+
+// ReadinessCheck is a condition that the objects selected by name or labels need to satisfy after create and update.
+type ReadinessCheck struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Labels     map[string]string
+
+	// JSONPath and Value replace the built-in readiness of the kind with the condition that the JSONPath template
+	// evaluates to Value, or to any non-empty string when Value is empty
+	JSONPath string
+	Value    string
+
+	// MinReadyReplicas replaces the rollout completion with the condition that at least this number of
+	// replicas or pods are ready. It is unsetSize when not set.
+	MinReadyReplicas int
+
+	Timeout time.Duration
+}
+
+func readinessCheckSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:       schema.TypeList,
+		Optional:   true,
+		ConfigMode: schema.SchemaConfigModeBlock,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"kind": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"api_version": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "",
+					Description: "Defaults to the apiVersion of the kind when it is one of Deployment, StatefulSet, DaemonSet, ReplicaSet, Job and Pod",
+				},
+				"namespace": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  metav1.NamespaceDefault,
+				},
+				"name": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"labels": {
+					Type:     schema.TypeMap,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"jsonpath": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "",
+					Description: "A JSONPath template like `{.status.phase}` evaluated against each object. Required for kinds without built-in readiness",
+				},
+				"value": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "",
+				},
+				"min_ready_replicas": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      unsetSize,
+					ValidateFunc: validation.IntAtLeast(unsetSize),
+				},
+				"timeout_sec": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      300,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+			},
+		},
+	}
+}
+
+func ReadReadinessChecks(d api.Getter) ([]ReadinessCheck, error) {
+	var checks []ReadinessCheck
+
+	vs, _ := d.Get(KeyReadinessCheck).([]interface{})
+
+	for i, v := range vs {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		c := ReadinessCheck{
+			APIVersion:       m["api_version"].(string),
+			Kind:             m["kind"].(string),
+			Namespace:        m["namespace"].(string),
+			Name:             m["name"].(string),
+			Labels:           map[string]string{},
+			JSONPath:         m["jsonpath"].(string),
+			Value:            m["value"].(string),
+			MinReadyReplicas: m["min_ready_replicas"].(int),
+			Timeout:          time.Duration(m["timeout_sec"].(int)) * time.Second,
+		}
+
+		if ls, ok := m["labels"].(map[string]interface{}); ok {
+			for k, v := range ls {
+				c.Labels[k] = v.(string)
+			}
+		}
+
+		if err := c.complete(); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", KeyReadinessCheck, i, err)
+		}
+
+		checks = append(checks, c)
+	}
+
+	return checks, nil
+}
+
+// complete fills the default apiVersion and validates the check.
+func (c *ReadinessCheck) complete() error {
+	_, builtin := readinessAPIVersions[c.Kind]
+
+	if c.APIVersion == "" {
+		if !builtin {
+			return fmt.Errorf("api_version is required for kind %s", c.Kind)
+		}
+
+		c.APIVersion = readinessAPIVersions[c.Kind]
+	}
+
+	if c.JSONPath != "" {
+		if err := jsonpath.New("readiness_check").Parse(c.JSONPath); err != nil {
+			return fmt.Errorf("parsing jsonpath %q: %w", c.JSONPath, err)
+		}
+	} else if !builtin {
+		return fmt.Errorf("jsonpath is required for kind %s, as it has no built-in readiness", c.Kind)
+	}
+
+	if c.MinReadyReplicas != unsetSize {
+		if c.JSONPath != "" {
+			return errors.New("min_ready_replicas and jsonpath are mutually exclusive")
+		}
+
+		if c.Kind == "Job" {
+			return errors.New("min_ready_replicas is not supported for kind Job")
+		}
+	}
+
+	return nil
+}
+
+func (c ReadinessCheck) String() string {
+	if c.Name != "" {
+		return fmt.Sprintf("%s %s/%s", c.Kind, c.Namespace, c.Name)
+	}
+
+	if len(c.Labels) == 0 {
+		return fmt.Sprintf("%s in namespace %s", c.Kind, c.Namespace)
+	}
+
+	return fmt.Sprintf("%s in namespace %s with labels %s", c.Kind, c.Namespace, labels.SelectorFromSet(c.Labels))
+}
+
+// evaluate returns whether the objects selected by the check are ready, and the reason when they're not.
+// An error is returned when they can never be ready, like when a Job failed.
+func (c ReadinessCheck) evaluate(objs []unstructured.Unstructured) (bool, string, error) {
+	if len(objs) == 0 {
+		return false, "no matching object found", nil
+	}
+
+	if c.Kind == "Pod" && c.MinReadyReplicas != unsetSize {
+		var ready int
+
+		for i := range objs {
+			if ok, _ := podReadiness(&objs[i]); ok {
+				ready++
+			}
+		}
+
+		if ready < c.MinReadyReplicas {
+			return false, fmt.Sprintf("%d of minimum %d pods are ready", ready, c.MinReadyReplicas), nil
+		}
+
+		return true, "", nil
+	}
+
+	var reasons []string
+
+	for i := range objs {
+		obj := &objs[i]
+
+		ok, reason, err := c.objectReadiness(obj)
+		if err != nil {
+			return false, "", fmt.Errorf("%s: %w", obj.GetName(), err)
+		}
+
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", obj.GetName(), reason))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return false, strings.Join(reasons, ", "), nil
+	}
+
+	return true, "", nil
+}
+
+func (c ReadinessCheck) objectReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	if c.JSONPath != "" {
+		return jsonPathReadiness(obj, c.JSONPath, c.Value)
+	}
+
+	if c.MinReadyReplicas != unsetSize {
+		field := "readyReplicas"
+		if c.Kind == "DaemonSet" {
+			field = "numberReady"
+		}
+
+		ready := nestedInt(obj, "status", field)
+
+		if ready < int64(c.MinReadyReplicas) {
+			return false, fmt.Sprintf("%d of minimum %d replicas are ready", ready, c.MinReadyReplicas), nil
+		}
+
+		return true, "", nil
+	}
+
+	switch c.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet":
+		if nestedInt(obj, "metadata", "generation") > nestedInt(obj, "status", "observedGeneration") {
+			return false, "waiting for the latest generation to be observed", nil
+		}
+	}
+
+	switch c.Kind {
+	case "Deployment":
+		return deploymentReadiness(obj)
+	case "StatefulSet":
+		return statefulSetReadiness(obj)
+	case "DaemonSet":
+		return daemonSetReadiness(obj)
+	case "ReplicaSet":
+		return replicaSetReadiness(obj)
+	case "Job":
+		return jobReadiness(obj)
+	case "Pod":
+		ok, reason := podReadiness(obj)
+		return ok, reason, nil
+	}
+
+	return false, "", fmt.Errorf("unsupported kind %s", c.Kind)
+}
+
+// deploymentReadiness tells whether the rollout is complete, the same way as `kubectl rollout status` does.
+func deploymentReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	if cond := findCondition(obj, "Progressing"); cond != nil && cond["reason"] == "ProgressDeadlineExceeded" {
+		return false, "", fmt.Errorf("deployment exceeded its progress deadline")
+	}
+
+	replicas := specReplicas(obj)
+	updated := nestedInt(obj, "status", "updatedReplicas")
+	total := nestedInt(obj, "status", "replicas")
+	available := nestedInt(obj, "status", "availableReplicas")
+
+	switch {
+	case updated < replicas:
+		return false, fmt.Sprintf("%d out of %d new replicas have been updated", updated, replicas), nil
+	case total > updated:
+		return false, fmt.Sprintf("%d old replicas are pending termination", total-updated), nil
+	case available < updated:
+		return false, fmt.Sprintf("%d of %d updated replicas are available", available, updated), nil
+	}
+
+	return true, "", nil
+}
+
+func statefulSetReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	replicas := specReplicas(obj)
+	ready := nestedInt(obj, "status", "readyReplicas")
+
+	if ready < replicas {
+		return false, fmt.Sprintf("%d of %d replicas are ready", ready, replicas), nil
+	}
+
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+	if strategy == "OnDelete" {
+		return true, "", nil
+	}
+
+	if partition, found, _ := unstructured.NestedInt64(obj.Object, "spec", "updateStrategy", "rollingUpdate", "partition"); found && partition > 0 {
+		updated := nestedInt(obj, "status", "updatedReplicas")
+
+		if updated < replicas-partition {
+			return false, fmt.Sprintf("%d of %d new pods have been updated", updated, replicas-partition), nil
+		}
+
+		return true, "", nil
+	}
+
+	current, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+	update, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+
+	if current != update {
+		return false, fmt.Sprintf("waiting for the rolling update to complete: %d of %d pods have been updated", nestedInt(obj, "status", "updatedReplicas"), replicas), nil
+	}
+
+	return true, "", nil
+}
+
+func daemonSetReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	desired := nestedInt(obj, "status", "desiredNumberScheduled")
+	scheduled := nestedInt(obj, "status", "currentNumberScheduled")
+	updated := nestedInt(obj, "status", "updatedNumberScheduled")
+	available := nestedInt(obj, "status", "numberAvailable")
+
+	switch {
+	case scheduled < desired:
+		return false, fmt.Sprintf("%d of %d pods are scheduled", scheduled, desired), nil
+	case updated < desired:
+		return false, fmt.Sprintf("%d out of %d new pods have been updated", updated, desired), nil
+	case available < desired:
+		return false, fmt.Sprintf("%d of %d updated pods are available", available, desired), nil
+	}
+
+	return true, "", nil
+}
+
+func replicaSetReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	replicas := specReplicas(obj)
+	ready := nestedInt(obj, "status", "readyReplicas")
+
+	if ready < replicas {
+		return false, fmt.Sprintf("%d of %d replicas are ready", ready, replicas), nil
+	}
+
+	return true, "", nil
+}
+
+func jobReadiness(obj *unstructured.Unstructured) (bool, string, error) {
+	if cond := findCondition(obj, "Failed"); cond != nil && cond["status"] == "True" {
+		return false, "", fmt.Errorf("job failed: %v: %v", cond["reason"], cond["message"])
+	}
+
+	if cond := findCondition(obj, "Complete"); cond != nil && cond["status"] == "True" {
+		return true, "", nil
+	}
+
+	completions, found, _ := unstructured.NestedInt64(obj.Object, "spec", "completions")
+	if !found {
+		completions = 1
+	}
+
+	return false, fmt.Sprintf("%d of %d completions succeeded", nestedInt(obj, "status", "succeeded"), completions), nil
+}
+
+func podReadiness(obj *unstructured.Unstructured) (bool, string) {
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase == "Succeeded" {
+		return true, ""
+	}
+
+	if cond := findCondition(obj, "Ready"); cond != nil && cond["status"] == "True" {
+		return true, ""
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+
+	return false, fmt.Sprintf("pod is not ready in phase %q", phase)
+}
+
+func jsonPathReadiness(obj *unstructured.Unstructured, template, value string) (bool, string, error) {
+	jp := jsonpath.New("readiness_check").AllowMissingKeys(true)
+
+	if err := jp.Parse(template); err != nil {
+		return false, "", err
+	}
+
+	var buf bytes.Buffer
+
+	if err := jp.Execute(&buf, obj.Object); err != nil {
+		return false, "", err
+	}
+
+	got := buf.String()
+
+	if value == "" {
+		if got == "" {
+			return false, fmt.Sprintf("jsonpath %s is empty", template), nil
+		}
+
+		return true, "", nil
+	}
+
+	if got != value {
+		return false, fmt.Sprintf("jsonpath %s is %q, want %q", template, got, value), nil
+	}
+
+	return true, "", nil
+}
+
+func specReplicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		return 1
+	}
+
+	return replicas
+}
+
+func nestedInt(obj *unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+
+	return v
+}
+
+func findCondition(obj *unstructured.Unstructured, typ string) map[string]interface{} {
+	conds, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+
+	for _, c := range conds {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == typ {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func (c ReadinessCheck) list(ctx context.Context, kube *kubeClient) ([]unstructured.Unstructured, error) {
+	r, _, err := kube.resource(c.APIVersion, c.Kind, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Name != "" {
+		obj, err := r.Get(ctx, c.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		return []unstructured.Unstructured{*obj}, nil
+	}
+
+	list, err := r.List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(c.Labels).String()})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+
+	return list.Items, nil
+}
+
+// wait polls the objects until they get ready, returning an error describing why they're not ready on timeout.
+func (c ReadinessCheck) wait(kube *kubeClient) error {
+	var reason string
+
+	err := wait.PollImmediate(readinessCheckPollInterval, c.Timeout, func() (bool, error) {
+		objs, err := c.list(context.Background(), kube)
+		if err != nil {
+			// The object might not be created yet, or the API server might be temporarily unavailable
+			reason = err.Error()
+
+			return false, nil
+		}
+
+		ready, r, err := c.evaluate(objs)
+
+		reason = r
+
+		return ready, err
+	})
+
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("not ready after %s: %s", c.Timeout, reason)
+	}
+
+	return err
+}
+
+// doCheckReadiness runs all the readiness checks concurrently, each with its own timeout,
+// and reports every check that failed.
+func doCheckReadiness(cluster *Cluster, clusterName string) error {
+	if len(cluster.ReadinessChecks) == 0 {
+		return nil
+	}
+
+	kube, err := newKubeClient(cluster, clusterName)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, len(cluster.ReadinessChecks))
+
+	for i := range cluster.ReadinessChecks {
+		i, c := i, cluster.ReadinessChecks[i]
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			log.Printf("[INFO] waiting for %s to be ready", c)
+
+			if err := c.wait(kube); err != nil {
+				errs[i] = err
+
+				return
+			}
+
+			log.Printf("[INFO] %s is ready", c)
+		}()
+	}
+
+	wg.Wait()
+
+	var msgs []string
+
+	for i, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s[%d] %s: %v", KeyReadinessCheck, i, cluster.ReadinessChecks[i], err))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+
+	return nil
+}
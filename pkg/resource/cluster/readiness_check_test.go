@@ -0,0 +1,188 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func obj(name string, fields map[string]interface{}) unstructured.Unstructured {
+	o := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "generation": int64(2)},
+	}
+
+	for k, v := range fields {
+		o[k] = v
+	}
+
+	return unstructured.Unstructured{Object: o}
+}
+
+func TestReadinessCheck_Deployment(t *testing.T) {
+	c := ReadinessCheck{Kind: "Deployment", MinReadyReplicas: unsetSize}
+
+	rolling := obj("app", map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(3)},
+		"status": map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(4), "updatedReplicas": int64(3), "availableReplicas": int64(2)},
+	})
+
+	ready, reason, err := c.evaluate([]unstructured.Unstructured{rolling})
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, "app: 1 old replicas are pending termination", reason)
+
+	rolledOut := obj("app", map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(3)},
+		"status": map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(3), "updatedReplicas": int64(3), "availableReplicas": int64(3)},
+	})
+
+	ready, _, err = c.evaluate([]unstructured.Unstructured{rolledOut})
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	// The minimum ready replicas replaces the rollout completion
+	c.MinReadyReplicas = 2
+
+	rolling.Object["status"].(map[string]interface{})["readyReplicas"] = int64(2)
+
+	ready, _, err = c.evaluate([]unstructured.Unstructured{rolling})
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	ready, reason, err = c.evaluate(nil)
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, "no matching object found", reason)
+}
+
+func TestReadinessCheck_DaemonSet(t *testing.T) {
+	c := ReadinessCheck{Kind: "DaemonSet", MinReadyReplicas: unsetSize}
+
+	ready, reason, err := c.evaluate([]unstructured.Unstructured{obj("ds", map[string]interface{}{
+		"status": map[string]interface{}{"observedGeneration": int64(2), "desiredNumberScheduled": int64(3), "currentNumberScheduled": int64(2)},
+	})})
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, "ds: 2 of 3 pods are scheduled", reason)
+}
+
+func TestReadinessCheck_Job(t *testing.T) {
+	c := ReadinessCheck{Kind: "Job", MinReadyReplicas: unsetSize}
+
+	ready, reason, err := c.evaluate([]unstructured.Unstructured{obj("job", map[string]interface{}{
+		"status": map[string]interface{}{"succeeded": int64(0)},
+	})})
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, "job: 0 of 1 completions succeeded", reason)
+
+	ready, _, err = c.evaluate([]unstructured.Unstructured{obj("job", map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}},
+	})})
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	_, _, err = c.evaluate([]unstructured.Unstructured{obj("job", map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "message": "Job has reached the specified backoff limit"}}},
+	})})
+	assert.EqualError(t, err, "job: job failed: BackoffLimitExceeded: Job has reached the specified backoff limit")
+}
+
+func TestReadinessCheck_Pods(t *testing.T) {
+	c := ReadinessCheck{Kind: "Pod", MinReadyReplicas: 1}
+
+	readyPod := obj("pod1", map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Running", "conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}},
+	})
+	pendingPod := obj("pod2", map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Pending"},
+	})
+
+	ready, _, err := c.evaluate([]unstructured.Unstructured{readyPod, pendingPod})
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	c.MinReadyReplicas = unsetSize
+
+	ready, reason, err := c.evaluate([]unstructured.Unstructured{readyPod, pendingPod})
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, `pod2: pod is not ready in phase "Pending"`, reason)
+}
+
+func TestCheckPodsReadiness_readinessCheck(t *testing.T) {
+	c := CheckPodsReadiness{namespace: "kube-system", labels: map[string]string{"app": "web"}, timeoutSec: 60}.readinessCheck()
+
+	require.NoError(t, c.complete())
+	assert.Equal(t, ReadinessCheck{
+		APIVersion:       "v1",
+		Kind:             "Pod",
+		Namespace:        "kube-system",
+		Labels:           map[string]string{"app": "web"},
+		MinReadyReplicas: unsetSize,
+		Timeout:          time.Minute,
+	}, c)
+	assert.Equal(t, "Pod in namespace kube-system with labels app=web", c.String())
+}
+
+func TestReadinessCheck_JSONPath(t *testing.T) {
+	c := ReadinessCheck{Kind: "Certificate", JSONPath: `{.status.conditions[?(@.type=="Ready")].status}`, Value: "True", MinReadyReplicas: unsetSize}
+
+	cert := obj("cert", map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}}},
+	})
+
+	ready, reason, err := c.evaluate([]unstructured.Unstructured{cert})
+	require.NoError(t, err)
+	assert.False(t, ready)
+	assert.Equal(t, `cert: jsonpath {.status.conditions[?(@.type=="Ready")].status} is "False", want "True"`, reason)
+
+	cert.Object["status"].(map[string]interface{})["conditions"].([]interface{})[0].(map[string]interface{})["status"] = "True"
+
+	ready, _, err = c.evaluate([]unstructured.Unstructured{cert})
+	require.NoError(t, err)
+	assert.True(t, ready)
+}
+
+func TestReadReadinessChecks(t *testing.T) {
+	s := map[string]*schema.Schema{KeyReadinessCheck: readinessCheckSchema()}
+
+	d := schema.TestResourceDataRaw(t, s, map[string]interface{}{
+		KeyReadinessCheck: []interface{}{
+			map[string]interface{}{
+				"kind":        "Deployment",
+				"name":        "app",
+				"timeout_sec": 60,
+			},
+		},
+	})
+
+	checks, err := ReadReadinessChecks(d)
+	require.NoError(t, err)
+	assert.Equal(t, []ReadinessCheck{{
+		APIVersion:       "apps/v1",
+		Kind:             "Deployment",
+		Namespace:        "default",
+		Name:             "app",
+		Labels:           map[string]string{},
+		MinReadyReplicas: unsetSize,
+		Timeout:          60 * time.Second,
+	}}, checks)
+	assert.Equal(t, "Deployment default/app", checks[0].String())
+
+	d = schema.TestResourceDataRaw(t, s, map[string]interface{}{
+		KeyReadinessCheck: []interface{}{
+			map[string]interface{}{
+				"kind":        "Certificate",
+				"api_version": "cert-manager.io/v1",
+			},
+		},
+	})
+
+	_, err = ReadReadinessChecks(d)
+	assert.EqualError(t, err, "readiness_check[0]: jsonpath is required for kind Certificate, as it has no built-in readiness")
+}
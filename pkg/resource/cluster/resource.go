@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,6 +33,15 @@ func ResourceCluster() *schema.Resource {
 
 			d.SetId(set.ClusterID)
 
+			if err := d.Set(KeyClusterConfig, string(set.ClusterConfig)); err != nil {
+				return fmt.Errorf("setting %s: %w", KeyClusterConfig, err)
+			}
+
+			// The operations are done. Leaving them makes the next plan show a diff.
+			if err := d.Set(KeyPlannedOperations, nil); err != nil {
+				return fmt.Errorf("setting %s: %w", KeyPlannedOperations, err)
+			}
+
 			if err := loadOIDCProviderURLAndARN(d, set.Cluster); err != nil {
 				return fmt.Errorf("loading oidc issuer url: %w", err)
 			}
@@ -65,6 +75,18 @@ func ResourceCluster() *schema.Resource {
 				return fmt.Errorf("drain error: %s", err)
 			}
 
+			if err := m.planClusterConfig(d); err != nil {
+				return err
+			}
+
+			if err := planDriftReconciliation(d); err != nil {
+				return fmt.Errorf("planning drift reconciliation: %w", err)
+			}
+
+			if err := planAWSAuth(d); err != nil {
+				return fmt.Errorf("planning aws-auth: %w", err)
+			}
+
 			return nil
 		},
 		Update: func(d *schema.ResourceData, meta interface{}) (finalErr error) {
@@ -81,6 +103,20 @@ func ResourceCluster() *schema.Resource {
 				return fmt.Errorf("updating cluster: %w", err)
 			}
 
+			if err := d.Set(KeyClusterConfig, string(set.ClusterConfig)); err != nil {
+				return fmt.Errorf("setting %s: %w", KeyClusterConfig, err)
+			}
+
+			// The operations are done. Leaving them makes the next plan show a diff.
+			if err := d.Set(KeyPlannedOperations, nil); err != nil {
+				return fmt.Errorf("setting %s: %w", KeyPlannedOperations, err)
+			}
+
+			// The update reconciled the drift
+			if err := d.Set(KeyDrift, nil); err != nil {
+				return fmt.Errorf("setting %s: %w", KeyDrift, err)
+			}
+
 			if err := loadOIDCProviderURLAndARN(d, set.Cluster); err != nil {
 				return fmt.Errorf("loading oidc issuer url: %w", err)
 			}
@@ -195,9 +231,11 @@ func ResourceCluster() *schema.Resource {
 				Default:  "",
 			},
 			KeyKubectlBin: {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "kubectl",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "kubectl",
+				Description: "Unused. None of pods_readiness_check, manifests, readiness_check and kubernetes_resource_deletion_before_destroy require kubectl",
+				Deprecated:  "kubectl is no longer used, as the provider accesses the cluster in-process",
 			},
 			KeyKubeconfigPath: {
 				Type:     schema.TypeString,
@@ -209,7 +247,8 @@ func ResourceCluster() *schema.Resource {
 			// Until then, this is the primary place you configure the cluster as you like.
 			KeySpec: {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Default:  "",
 				ValidateFunc: func(v interface{}, name string) ([]string, []error) {
 					s := v.(string)
 
@@ -228,9 +267,30 @@ func ResourceCluster() *schema.Resource {
 						return nil, []error{fmt.Errorf("validating eksctl_cluster's \"spec\": vpc.id must not be set within the spec yaml. use \"vpc_id\" attribute instead, becaues the provider uses it for generating the final eksctl cluster config yaml")}
 					}
 
-					return nil, nil
+					// api_version is unavailable here. The merged cluster config is validated against the schema
+					// for the api_version later in CustomizeDiff.
+					warnings, errs := ValidateClusterConfig(DefaultAPIVersion, []byte(s))
+
+					for i := range warnings {
+						warnings[i] = fmt.Sprintf("eksctl_cluster's \"spec\": %s", warnings[i])
+					}
+
+					for i := range errs {
+						errs[i] = fmt.Errorf("validating eksctl_cluster's \"spec\": %w", errs[i])
+					}
+
+					return warnings, errs
 				},
 			},
+			KeyNodeGroup:        nodeGroupSchema(),
+			KeyManagedNodeGroup: nodeGroupSchema(),
+			KeyUpdateConcurrency: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The maximum number of update tasks like creating nodegroups and iamserviceaccounts to run concurrently. Defaults to 1, which runs them one by one",
+			},
 			KeyDrainNodeGroups: {
 				Type:     schema.TypeMap,
 				Optional: true,
@@ -241,54 +301,79 @@ func ResourceCluster() *schema.Resource {
 			KeyIAMIdentityMapping: {
 				Type:     schema.TypeSet,
 				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"iamarn": {
-							Required: true,
-							Type:     schema.TypeString,
-						},
-						"username": {
-							Required: true,
-							Type:     schema.TypeString,
-						},
-						"groups": {
-							Required: true,
-							Type:     schema.TypeList,
-							Elem: &schema.Schema{
-								Type: schema.TypeString,
-							},
-						},
-					},
-				},
+				Elem:     iamIdentityMappingResource(),
+			},
+			KeyMapAccounts: {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "AWS account IDs to add to mapAccounts in the aws-auth configmap",
+			},
+			KeyAWSAuthAuthoritative: {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "When true, entries in the aws-auth configmap not declared in iam_identity_mapping and map_accounts are deleted, except ones for nodes managed by eksctl, and out-of-band changes to aws-auth show up as plan diffs",
 			},
 			KeyAWSAuthConfigMap: {
 				Type:     schema.TypeSet,
 				Computed: true,
 				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"iamarn": {
-							Required: true,
-							Type:     schema.TypeString,
-						},
-						"username": {
-							Required: true,
-							Type:     schema.TypeString,
-						},
-						"groups": {
-							Required: true,
-							Type:     schema.TypeList,
-							Elem: &schema.Schema{
-								Type: schema.TypeString,
-							},
-						},
-					},
-				},
+				Elem:     iamIdentityMappingResource(),
+			},
+			KeyAWSAuthMapAccounts: {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "AWS account IDs in mapAccounts of the aws-auth configmap",
 			},
 			sdk.KeyOutput: {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			KeyClusterConfig: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The cluster config generated from spec and other attributes, which is passed to eksctl",
+			},
+			KeyEndpoint: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The endpoint of the Kubernetes API server",
+			},
+			KeyCertificateAuthorityData: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The base64-encoded CA certificate of the Kubernetes API server",
+			},
+			KeyToken: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The token for authenticating to the Kubernetes API server. It expires in 15 minutes, and is regenerated on every refresh",
+			},
+			KeyKubeconfigRaw: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The kubeconfig that obtains tokens with `aws eks get-token`",
+			},
+			KeyUpdateCheckpoint:                        updateCheckpointSchema(),
+			KeyAppliedManifestObjects:                  appliedManifestObjectsSchema(),
+			KeyReadinessCheck:                          readinessCheckSchema(),
+			KeyKubernetesResourceDeletionBeforeDestroy: kubernetesResourceDeletionSchema(),
+			KeyPlannedOperations: {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The operations that the next apply runs, like `create nodegroup ng2`. Empty after apply",
+			},
+			KeyDrift: {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The differences between nodegroups, iamserviceaccounts and fargateprofiles declared and ones in the cluster, detected on refresh",
+			},
 			KeyOIDCProviderURL: {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -318,10 +403,23 @@ func validateDrainNodeGroups(d *schema.ResourceDiff) error {
 			spec = s.(string)
 		}
 
+		declared := map[string]bool{}
+
+		for _, key := range []string{KeyNodeGroup, KeyManagedNodeGroup} {
+			ngs, _ := d.Get(key).([]interface{})
+			for _, ng := range ngs {
+				if m, ok := ng.(map[string]interface{}); ok {
+					if name, ok := m["name"].(string); ok {
+						declared[name] = true
+					}
+				}
+			}
+		}
+
 		nodegroups := v.(map[string]interface{})
 		for k := range nodegroups {
 			reg := regexp.MustCompile(`- name: ` + k)
-			if !reg.MatchString(spec) {
+			if !declared[k] && !reg.MatchString(spec) {
 				return fmt.Errorf("no such nodegroup to drain '%s'", k)
 			}
 		}
@@ -329,3 +427,72 @@ func validateDrainNodeGroups(d *schema.ResourceDiff) error {
 
 	return nil
 }
+
+// planClusterConfig validates the cluster config generated from spec and node group blocks against the schema
+// for api_version, so that errors are reported on plan rather than by eksctl minutes after apply started.
+// It also sets the cluster config to cluster_config, so that the plan shows what's going to be passed to eksctl.
+func (m *Manager) planClusterConfig(d *schema.ResourceDiff) error {
+	for _, k := range []string{KeySpec, KeyNodeGroup, KeyManagedNodeGroup, KeyAPIVersion} {
+		if !d.NewValueKnown(k) {
+			log.Printf("[DEBUG] skipped validating cluster config: %s is not known until apply", k)
+
+			return setNewComputed(d, KeyClusterConfig, KeyPlannedOperations)
+		}
+	}
+
+	id := d.Id()
+	if id == "" {
+		// Any id works, as it affects only the cluster name suffix
+		id = newClusterID()
+	}
+
+	set, err := m.PrepareClusterSet(&tfsdk.DiffReadWrite{D: d}, id)
+	if err != nil {
+		return fmt.Errorf("generating cluster config: %w", err)
+	}
+
+	warnings, errs := ValidateClusterConfig(set.Cluster.APIVersion, set.ClusterConfig)
+
+	for _, w := range warnings {
+		log.Printf("[WARN] eksctl_cluster %q: %s", set.Cluster.Name, w)
+	}
+
+	if len(errs) > 0 {
+		var msgs []string
+
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+
+		return fmt.Errorf("validating cluster config:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	// Unknown values are read as zero values, which results in a cluster config that is still valid but
+	// differs from the one used on apply.
+	for _, k := range []string{KeyName, KeyRegion, KeyVersion, KeyTags, KeyVPCID} {
+		if !d.NewValueKnown(k) {
+			return setNewComputed(d, KeyClusterConfig, KeyPlannedOperations)
+		}
+	}
+
+	// The cluster name depends on the resource id that is generated on create
+	if d.Id() == "" && !m.DisableClusterNameSuffix {
+		return setNewComputed(d, KeyClusterConfig, KeyPlannedOperations)
+	}
+
+	if err := planOperations(d, set.Cluster, set.ClusterConfig); err != nil {
+		return fmt.Errorf("planning operations: %w", err)
+	}
+
+	return d.SetNew(KeyClusterConfig, string(set.ClusterConfig))
+}
+
+func setNewComputed(d *schema.ResourceDiff, keys ...string) error {
+	for _, k := range keys {
+		if err := d.SetNewComputed(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
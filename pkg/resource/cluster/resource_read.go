@@ -30,6 +30,10 @@ func ReadCluster(d api.Getter) (*Cluster, error) {
 
 	a.VPCID = d.Get(KeyVPCID).(string)
 
+	if v, ok := d.Get(KeyUpdateConcurrency).(int); ok {
+		a.UpdateConcurrency = v
+	}
+
 	if v := d.Get(KeyPodsReadinessCheck); v != nil {
 		rawCheckPodsReadiness := v.([]interface{})
 		for _, r := range rawCheckPodsReadiness {
@@ -52,21 +56,20 @@ func ReadCluster(d api.Getter) (*Cluster, error) {
 		}
 	}
 
-	if v := d.Get(KeyKubernetesResourceDeletionBeforeDestroy); v != nil {
-		resourceDeletions := v.([]interface{})
-		for _, r := range resourceDeletions {
-			m := r.(map[string]interface{})
+	readinessChecks, err := ReadReadinessChecks(d)
+	if err != nil {
+		return nil, err
+	}
 
-			d := DeleteKubernetesResource{
-				Namespace: m["namespace"].(string),
-				Name:      m["name"].(string),
-				Kind:      m["kind"].(string),
-			}
+	a.ReadinessChecks = readinessChecks
 
-			a.DeleteKubernetesResourcesBeforeDestroy = append(a.DeleteKubernetesResourcesBeforeDestroy, d)
-		}
+	deletions, err := ReadDeleteKubernetesResources(d)
+	if err != nil {
+		return nil, err
 	}
 
+	a.DeleteKubernetesResourcesBeforeDestroy = deletions
+
 	if v := d.Get(KeyManifests); v != nil {
 		rawManifests := v.([]interface{})
 		for _, m := range rawManifests {
@@ -81,6 +84,20 @@ func ReadCluster(d api.Getter) (*Cluster, error) {
 		}
 	}
 
+	nodeGroups, err := ReadNodeGroups(d, KeyNodeGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	a.NodeGroups = nodeGroups
+
+	managedNodeGroups, err := ReadNodeGroups(d, KeyManagedNodeGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	a.ManagedNodeGroups = managedNodeGroups
+
 	if cfg := tfsdk.GetAssumeRoleConfig(d); cfg != nil {
 		a.AssumeRoleConfig = cfg
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"log"
 )
 
@@ -15,7 +16,7 @@ const (
 )
 
 func getTargetGroupARNs(sess *session.Session, clusterNamePrefixy string) ([]string, error) {
-	api := resourcegroupstaggingapi.New(sess)
+	api := sdk.NewClients(sess).ResourceGroupsTaggingAPI()
 
 	var token *string
 
@@ -54,7 +55,7 @@ func getTargetGroupARNs(sess *session.Session, clusterNamePrefixy string) ([]str
 }
 
 func deleteTargetGroups(set *ClusterSet) error {
-	elb := elbv2.New(AWSSessionFromCluster(set.Cluster))
+	elb := sdk.NewClients(AWSSessionFromCluster(set.Cluster)).ELBV2()
 
 	for _, tgARN := range set.Cluster.TargetGroupARNs {
 		log.Printf("Deleting target group %s for %s", tgARN, set.ClusterName)
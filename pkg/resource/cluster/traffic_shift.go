@@ -3,12 +3,10 @@ package cluster
 import (
 	"context"
 	"log"
-	"sync"
-	"time"
 
-	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
 	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/xerrors"
 )
@@ -16,7 +14,7 @@ import (
 func graduallyShiftTraffic(set *ClusterSet, opts courier.CanaryOpts) error {
 	cluster := set.Cluster
 
-	svc := elbv2.New(AWSSessionFromCluster(cluster))
+	svc := sdk.NewClients(AWSSessionFromCluster(cluster)).ELBV2()
 
 	listenerStatuses := set.ListenerStatuses
 
@@ -52,53 +50,34 @@ func (m *ALBRouter) SwitchTargetGroup(listenerStatuses ListenerStatuses, opts co
 		return nil
 	}
 
-	tCtx, cancel := context.WithCancel(context.Background())
-	g, gctx := errgroup.WithContext(tCtx)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	wg := &sync.WaitGroup{}
+	g, gctx := errgroup.WithContext(ctx)
+
+	shifts, sctx := errgroup.WithContext(gctx)
 
 	for i := range listenerStatuses {
 		l := listenerStatuses[i]
 
-		g.Go(func() error {
-			return courier.DoGradualTrafficShift(tCtx, svc, l, 1, opts)
-		})
-	}
-
-	// Check per cluster metrics
-	for i := range m.Analyzers {
-		a := m.Analyzers[i]
-
-		g.Go(func() error {
-			ticker := time.NewTicker(courier.DefaultAnalyzeInterval)
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-gctx.Done():
-					// Deployment finished. Stop checking as not necessary anymore
-					return nil
-				case <-ticker.C:
-					if err := a.Analyze(opts); err != nil {
-						return xerrors.Errorf("analyze: %w", err)
-					}
-				}
-			}
+		shifts.Go(func() error {
+			return courier.DoGradualTrafficShift(sctx, svc, l, 1, opts)
 		})
 	}
 
-	go func() {
+	g.Go(func() error {
+		// Deployment finished. Stop analyzers as not necessary anymore
 		defer cancel()
 
-		wg.Wait()
-	}()
+		return shifts.Wait()
+	})
 
-	var err error
-	{
-		defer cancel()
+	// Check per cluster metrics
+	g.Go(func() error {
+		return courier.RunAnalyzers(gctx, m.Analyzers, opts)
+	})
 
-		err = g.Wait()
-	}
+	err := g.Wait()
 
 	if err == nil {
 		log.Printf("Traffic shifting finished successfully.")
@@ -0,0 +1,249 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/api"
+	"golang.org/x/sync/errgroup"
+)
+
+const KeyUpdateCheckpoint = "update_checkpoint"
+
+// updateTask is a named step of the update pipeline of the cluster.
+type updateTask struct {
+	Name string
+	Run  func() error
+
+	// DependsOn is the names of tasks that need to succeed before this task runs
+	DependsOn []string
+
+	// AlwaysRun makes the task run even when the previous update completed it.
+	// This is for tasks without side effects on the cluster that set outputs, which would be lost otherwise.
+	AlwaysRun bool
+}
+
+// updateCheckpoint records tasks that have been completed by an update that failed in the middle.
+// The next update skips the tasks as long as the config hash is unchanged.
+type updateCheckpoint struct {
+	ConfigHash     string
+	CompletedTasks []string
+}
+
+func updateCheckpointSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Computed:    true,
+		Description: "The tasks completed by the last update that failed in the middle. They are skipped in the next update unless the config changes",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"config_hash": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"completed_tasks": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+func readUpdateCheckpoint(d api.Getter) updateCheckpoint {
+	var cp updateCheckpoint
+
+	vs, _ := d.Get(KeyUpdateCheckpoint).([]interface{})
+
+	for _, v := range vs {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		cp.ConfigHash = m["config_hash"].(string)
+
+		for _, t := range m["completed_tasks"].([]interface{}) {
+			cp.CompletedTasks = append(cp.CompletedTasks, t.(string))
+		}
+	}
+
+	return cp
+}
+
+func (cp updateCheckpoint) toState() []interface{} {
+	if cp.ConfigHash == "" {
+		return nil
+	}
+
+	var tasks []interface{}
+
+	for _, t := range cp.CompletedTasks {
+		tasks = append(tasks, t)
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"config_hash":     cp.ConfigHash,
+			"completed_tasks": tasks,
+		},
+	}
+}
+
+// runUpdatePipeline runs the tasks, skipping ones completed for the same config hash according to the checkpoint
+// unless they are AlwaysRun.
+// Up to `concurrency` tasks whose dependencies are completed run concurrently. Ready tasks are started in the order
+// of declaration, so that the tasks run exactly in that order when the concurrency is 1.
+//
+// A failed task doesn't stop other tasks not depending on it, and the returned error contains all the failures.
+// It returns the checkpoint to be persisted, which is empty when all the tasks succeeded.
+func runUpdatePipeline(tasks []updateTask, configHash string, cp updateCheckpoint, concurrency int) (updateCheckpoint, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	index := map[string]int{}
+
+	for i, t := range tasks {
+		index[t.Name] = i
+	}
+
+	for _, t := range tasks {
+		for _, dep := range t.DependsOn {
+			if j, ok := index[dep]; !ok || j >= index[t.Name] {
+				return cp, fmt.Errorf("bug: update task %q must depend on a task declared before it: %q", t.Name, dep)
+			}
+		}
+	}
+
+	if cp.ConfigHash != configHash {
+		if cp.ConfigHash != "" {
+			log.Printf("[INFO] discarding the update checkpoint for config hash %s, as the config hash changed to %s", cp.ConfigHash, configHash)
+		}
+
+		cp = updateCheckpoint{ConfigHash: configHash}
+	}
+
+	completed := map[string]bool{}
+
+	var completedTasks []string
+
+	for _, t := range cp.CompletedTasks {
+		if j, ok := index[t]; ok && tasks[j].AlwaysRun {
+			continue
+		}
+
+		completed[t] = true
+		completedTasks = append(completedTasks, t)
+	}
+
+	cp.CompletedTasks = completedTasks
+
+	type result struct {
+		i   int
+		err error
+	}
+
+	var (
+		g       errgroup.Group
+		results = make(chan result)
+		started = map[string]bool{}
+		failed  = map[string]error{}
+		running int
+	)
+
+	for {
+		for i, t := range tasks {
+			if running >= concurrency {
+				break
+			}
+
+			if started[t.Name] {
+				continue
+			}
+
+			if completed[t.Name] {
+				started[t.Name] = true
+
+				log.Printf("[INFO] skipping update task %d/%d %q, as it has been completed by the previous update", i+1, len(tasks), t.Name)
+
+				continue
+			}
+
+			ready := true
+
+			for _, dep := range t.DependsOn {
+				if !completed[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if !ready {
+				continue
+			}
+
+			started[t.Name] = true
+			running++
+
+			log.Printf("[INFO] running update task %d/%d %q", i+1, len(tasks), t.Name)
+
+			i, t := i, t
+
+			g.Go(func() error {
+				err := t.Run()
+
+				results <- result{i: i, err: err}
+
+				return err
+			})
+		}
+
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+
+		t := tasks[r.i]
+
+		if r.err != nil {
+			log.Printf("[INFO] update task %d/%d %q failed: %v", r.i+1, len(tasks), t.Name, r.err)
+
+			failed[t.Name] = r.err
+		} else {
+			completed[t.Name] = true
+			cp.CompletedTasks = append(cp.CompletedTasks, t.Name)
+		}
+	}
+
+	// All the errors are already in failed
+	_ = g.Wait()
+
+	if len(failed) == 0 {
+		return updateCheckpoint{}, nil
+	}
+
+	var msgs, skipped []string
+
+	for i, t := range tasks {
+		if err, ok := failed[t.Name]; ok {
+			msgs = append(msgs, fmt.Sprintf("update task %d/%d %q failed: %v", i+1, len(tasks), t.Name, err))
+		} else if !started[t.Name] {
+			skipped = append(skipped, t.Name)
+		}
+	}
+
+	if len(skipped) > 0 {
+		msgs = append(msgs, fmt.Sprintf("skipped %d task(s) depending on the failed task(s): %v", len(skipped), skipped))
+	}
+
+	msgs = append(msgs, fmt.Sprintf("The next apply resumes from the failed task(s), skipping %d completed task(s): %v", len(cp.CompletedTasks), cp.CompletedTasks))
+
+	return cp, errors.New(strings.Join(msgs, "\n\n"))
+}
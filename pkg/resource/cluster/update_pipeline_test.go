@@ -0,0 +1,167 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUpdatePipeline(t *testing.T) {
+	var ran []string
+
+	fail := true
+
+	tasks := []updateTask{
+		{Name: "a", Run: func() error { ran = append(ran, "a"); return nil }},
+		{Name: "b", Run: func() error {
+			ran = append(ran, "b")
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		}},
+		{Name: "c", Run: func() error { ran = append(ran, "c"); return nil }, DependsOn: []string{"b"}},
+	}
+
+	cp, err := runUpdatePipeline(tasks, "hash1", updateCheckpoint{}, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `update task 2/3 "b" failed: boom`)
+	assert.Contains(t, err.Error(), `skipped 1 task(s) depending on the failed task(s): [c]`)
+	assert.Equal(t, updateCheckpoint{ConfigHash: "hash1", CompletedTasks: []string{"a"}}, cp)
+	assert.Equal(t, []string{"a", "b"}, ran)
+
+	// Resumes from the failed task
+	ran = nil
+	fail = false
+
+	cp2, err := runUpdatePipeline(tasks, "hash1", cp, 1)
+	require.NoError(t, err)
+	assert.Equal(t, updateCheckpoint{}, cp2)
+	assert.Equal(t, []string{"b", "c"}, ran)
+
+	// Reruns everything once the config changes
+	ran = nil
+
+	_, err = runUpdatePipeline(tasks, "hash2", cp, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, ran)
+}
+
+func TestRunUpdatePipeline_AlwaysRun(t *testing.T) {
+	var ran []string
+
+	tasks := []updateTask{
+		{Name: "a", Run: func() error { ran = append(ran, "a"); return nil }},
+		{Name: "output", Run: func() error { ran = append(ran, "output"); return nil }, DependsOn: []string{"a"}, AlwaysRun: true},
+		{Name: "b", Run: func() error { ran = append(ran, "b"); return errors.New("boom") }, DependsOn: []string{"a"}},
+	}
+
+	cp, err := runUpdatePipeline(tasks, "hash1", updateCheckpoint{}, 1)
+	require.Error(t, err)
+	assert.Equal(t, updateCheckpoint{ConfigHash: "hash1", CompletedTasks: []string{"a", "output"}}, cp)
+
+	// The output task is rerun on resume, while the other completed task is skipped
+	ran = nil
+
+	cp, err = runUpdatePipeline(tasks, "hash1", cp, 1)
+	require.Error(t, err)
+	assert.Equal(t, updateCheckpoint{ConfigHash: "hash1", CompletedTasks: []string{"a", "output"}}, cp)
+	assert.Equal(t, []string{"output", "b"}, ran)
+}
+
+func TestUpdateConfigHash(t *testing.T) {
+	config := []byte("metadata:\n  name: mycluster\n")
+
+	g := fakeChangeGetter{new: map[string]interface{}{
+		KeyIAMIdentityMapping: newIAMIdentityMappingSet(nil),
+		KeyMapAccounts:        newAccountSet([]string{"111111111111"}),
+	}}
+
+	h := updateConfigHash(g, config)
+
+	assert.Equal(t, h, updateConfigHash(g, config))
+	assert.NotEqual(t, h, updateConfigHash(g, append(config, "nodeGroups: []\n"...)))
+
+	for k, v := range map[string]interface{}{
+		KeyMapAccounts:        newAccountSet([]string{"222222222222"}),
+		KeyReadinessCheck:     []interface{}{map[string]interface{}{"kind": "Deployment"}},
+		KeyPodsReadinessCheck: []interface{}{map[string]interface{}{"namespace": "default"}},
+		KeyBin:                "eksctl-0.40.0",
+		KeyEksctlVersion:      "0.40.0",
+		KeyKubeconfigPath:     "/tmp/kubeconfig",
+	} {
+		changed := fakeChangeGetter{new: map[string]interface{}{}}
+
+		for k2, v2 := range g.new {
+			changed.new[k2] = v2
+		}
+
+		changed.new[k] = v
+
+		assert.NotEqual(t, h, updateConfigHash(changed, config), "changing %s", k)
+	}
+}
+
+func TestRunUpdatePipeline_Concurrent(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		running, maxSeen int
+		ran              []string
+	)
+
+	task := func(name string, err error) func() error {
+		return func() error {
+			mu.Lock()
+			running++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			ran = append(ran, name)
+			mu.Unlock()
+
+			return err
+		}
+	}
+
+	tasks := []updateTask{
+		{Name: "root", Run: task("root", nil)},
+		{Name: "a", Run: task("a", errors.New("a failed")), DependsOn: []string{"root"}},
+		{Name: "b", Run: task("b", errors.New("b failed")), DependsOn: []string{"root"}},
+		{Name: "c", Run: task("c", nil), DependsOn: []string{"root"}},
+		{Name: "d", Run: task("d", nil), DependsOn: []string{"root"}},
+		{Name: "after-a", Run: task("after-a", nil), DependsOn: []string{"a"}},
+	}
+
+	cp, err := runUpdatePipeline(tasks, "hash", updateCheckpoint{}, 2)
+	require.Error(t, err)
+
+	// All the failures are reported
+	assert.Contains(t, err.Error(), `update task 2/6 "a" failed: a failed`)
+	assert.Contains(t, err.Error(), `update task 3/6 "b" failed: b failed`)
+	assert.Contains(t, err.Error(), `[after-a]`)
+
+	assert.Equal(t, "root", ran[0])
+	assert.ElementsMatch(t, []string{"root", "a", "b", "c", "d"}, ran)
+	assert.ElementsMatch(t, []string{"root", "c", "d"}, cp.CompletedTasks)
+	assert.Equal(t, 2, maxSeen)
+}
+
+func TestRunUpdatePipeline_InvalidDependency(t *testing.T) {
+	_, err := runUpdatePipeline([]updateTask{
+		{Name: "a", Run: func() error { return nil }, DependsOn: []string{"b"}},
+		{Name: "b", Run: func() error { return nil }},
+	}, "hash", updateCheckpoint{}, 1)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `bug: update task "a" must depend on a task declared before it: "b"`)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
 	"golang.org/x/xerrors"
 	"log"
 )
@@ -15,7 +16,7 @@ func createVPCResourceTags(cluster *Cluster, clusterName ClusterName) error {
 		return nil
 	}
 
-	ec2session := ec2.New(AWSSessionFromCluster(cluster))
+	ec2session := sdk.NewClients(AWSSessionFromCluster(cluster)).EC2()
 
 	tagKey := fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)
 	tagValue := "shared"
@@ -67,7 +68,7 @@ func deleteVPCResourceTags(cluster *Cluster, clusterName ClusterName) error {
 		return nil
 	}
 
-	ec2session := ec2.New(AWSSessionFromCluster(cluster))
+	ec2session := sdk.NewClients(AWSSessionFromCluster(cluster)).EC2()
 
 	tagKey := fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)
 
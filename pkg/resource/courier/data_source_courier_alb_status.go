@@ -0,0 +1,214 @@
+package courier
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
+)
+
+var healthSummarySchema = map[string]*schema.Schema{
+	"healthy": {
+		Type:     schema.TypeInt,
+		Computed: true,
+	},
+	"unhealthy": {
+		Type:     schema.TypeInt,
+		Computed: true,
+	},
+	"other": {
+		Type:        schema.TypeInt,
+		Computed:    true,
+		Description: "The number of targets that are neither healthy nor unhealthy, like initial, draining, and unused ones",
+	},
+}
+
+func flattenHealthSummary(h courier.HealthSummary) map[string]interface{} {
+	return map[string]interface{}{
+		"healthy":   h.Healthy,
+		"unhealthy": h.Unhealthy,
+		"other":     h.Other,
+	}
+}
+
+// DataSourceALBStatus describes the current weights, conditions, and health of an ALB listener rule.
+func DataSourceALBStatus() *schema.Resource {
+	return &schema.Resource{
+		Read: func(d *schema.ResourceData, meta interface{}) error {
+			sess := tfsdk.AWSSessionFromResourceData(d)
+
+			if v := d.Get("address").(string); v != "" {
+				sess.Config.Endpoint = aws.String(v)
+			}
+
+			status, err := courier.DescribeALBRuleStatus(
+				sdk.NewClients(sess).ELBV2(),
+				d.Get("rule_arn").(string),
+				d.Get("listener_arn").(string),
+				d.Get("priority").(int),
+			)
+			if err != nil {
+				return fmt.Errorf("reading courier_alb_status: %w", err)
+			}
+
+			d.SetId(status.RuleARN)
+
+			var tgs []interface{}
+
+			for _, tg := range status.TargetGroups {
+				tgs = append(tgs, map[string]interface{}{
+					"arn":    tg.ARN,
+					"weight": tg.Weight,
+					"health": []interface{}{flattenHealthSummary(tg.Health)},
+				})
+			}
+
+			var headers []interface{}
+
+			var names []string
+
+			for n := range status.Conditions.Headers {
+				names = append(names, n)
+			}
+
+			sort.Strings(names)
+
+			for _, n := range names {
+				headers = append(headers, map[string]interface{}{
+					"name":   n,
+					"values": status.Conditions.Headers[n],
+				})
+			}
+
+			attrs := map[string]interface{}{
+				"rule_arn":      status.RuleARN,
+				"listener_arn":  status.ListenerARN,
+				"priority":      status.Priority,
+				"is_default":    status.IsDefault,
+				"hosts":         status.Conditions.Hosts,
+				"path_patterns": status.Conditions.PathPatterns,
+				"methods":       status.Conditions.Methods,
+				"source_ips":    status.Conditions.SourceIPs,
+				"header":        headers,
+				"querystrings":  status.Conditions.QueryStrings,
+				"target_group":  tgs,
+			}
+
+			for k, v := range attrs {
+				if err := d.Set(k, v); err != nil {
+					return fmt.Errorf("setting %s: %w", k, err)
+				}
+			}
+
+			return nil
+		},
+		Schema: map[string]*schema.Schema{
+			tfsdk.KeyRegion: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			tfsdk.KeyProfile: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"address": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			tfsdk.KeyAssumeRole: tfsdk.SchemaAssumeRole(),
+			"rule_arn": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"listener_arn"},
+				Description:   "The ARN of the listener rule to describe. Either this or `listener_arn` is required",
+			},
+			"listener_arn": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"rule_arn"},
+				Description:   "The ARN of the listener whose rule at `priority` is described",
+			},
+			"priority": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"is_default": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"hosts": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"path_patterns": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"methods": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"source_ips": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"header": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"values": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"querystrings": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"target_group": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"arn": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"weight": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"health": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Resource{Schema: healthSummarySchema},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package courier
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/sdk/tfsdk"
+)
+
+// DataSourceRoute53Status describes the current weights and health of weighted Route 53 records sharing a name.
+func DataSourceRoute53Status() *schema.Resource {
+	return &schema.Resource{
+		Read: func(d *schema.ResourceData, meta interface{}) error {
+			sess := tfsdk.AWSSessionFromResourceData(d)
+
+			if v := d.Get("address").(string); v != "" {
+				sess.Config.Endpoint = aws.String(v)
+			}
+
+			zoneID := d.Get("zone_id").(string)
+
+			status, err := courier.DescribeRoute53RecordStatus(sdk.NewClients(sess).Route53(), zoneID, d.Get("name").(string), d.Get("type").(string))
+			if err != nil {
+				return fmt.Errorf("reading courier_route53_status: %w", err)
+			}
+
+			d.SetId(zoneID + "_" + status.Name + "_" + status.Type)
+
+			var rss []interface{}
+
+			for _, rs := range status.RecordSets {
+				rss = append(rss, map[string]interface{}{
+					"set_identifier":  rs.SetIdentifier,
+					"weight":          rs.Weight,
+					"values":          rs.Values,
+					"health_check_id": rs.HealthCheckID,
+					"health":          []interface{}{flattenHealthSummary(rs.Health)},
+				})
+			}
+
+			if err := d.Set("type", status.Type); err != nil {
+				return fmt.Errorf("setting type: %w", err)
+			}
+
+			if err := d.Set("destination", rss); err != nil {
+				return fmt.Errorf("setting destination: %w", err)
+			}
+
+			return nil
+		},
+		Schema: map[string]*schema.Schema{
+			tfsdk.KeyRegion: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			tfsdk.KeyProfile: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"address": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			tfsdk.KeyAssumeRole: tfsdk.SchemaAssumeRole(),
+			"zone_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The type of the records, like A and CNAME. Defaults to the type of the first weighted record of the name",
+			},
+			"destination": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"set_identifier": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"weight": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"values": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The DNS name of the alias target, or the values of the record",
+						},
+						"health_check_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"health": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Resource{Schema: healthSummarySchema},
+							Description: "The number of Route 53 health checkers reporting the record healthy or unhealthy",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -36,12 +36,14 @@ var MetricResourceSchema = map[string]*schema.Schema{
 		Required: true,
 	},
 	"max": {
-		Type:     schema.TypeFloat,
-		Optional: true,
+		Type:        schema.TypeFloat,
+		Optional:    true,
+		Description: "The analysis fails when the value is above max. Unset it along with a positive min to not check the upper bound",
 	},
 	"min": {
-		Type:     schema.TypeFloat,
-		Optional: true,
+		Type:        schema.TypeFloat,
+		Optional:    true,
+		Description: "The analysis fails when the value is below min. Unset it along with a negative max to not check the lower bound",
 	},
 	"interval": {
 		Type:     schema.TypeString,
@@ -59,6 +61,300 @@ var MetricsSchema = &schema.Schema{
 	},
 }
 
+var CloudWatchLogsMetricsSchema = &schema.Schema{
+	Type:       schema.TypeList,
+	Optional:   true,
+	ConfigMode: schema.SchemaConfigModeBlock,
+	Elem: &schema.Resource{
+		Schema: cloudWatchLogsMetricResourceSchema(),
+	},
+}
+
+func cloudWatchLogsMetricResourceSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{}
+
+	for k, v := range MetricResourceSchema {
+		s[k] = v
+	}
+
+	s["log_group_names"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Required: true,
+		MinItems: 1,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+	s["field"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     "",
+		Description: "The field in the first row of the query result used as the metric value. Defaults to the first field not prefixed with `@`",
+	}
+	s["window"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "5m",
+		ValidateFunc: ValidateDuration,
+		Description:  "The length of the sliding window, ending at the time of the query, that the Logs Insights query runs over",
+	}
+
+	return s
+}
+
+var HTTPProbeMetricsSchema = &schema.Schema{
+	Type:       schema.TypeList,
+	Optional:   true,
+	ConfigMode: schema.SchemaConfigModeBlock,
+	Elem: &schema.Resource{
+		Schema: httpProbeMetricResourceSchema(),
+	},
+}
+
+func httpProbeMetricResourceSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{}
+
+	for k, v := range MetricResourceSchema {
+		s[k] = v
+	}
+
+	s["query"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Required:    true,
+		Description: "Either `success_rate` for the percentage of successful requests, or `latency_pNN` like `latency_p99` for the NN-th percentile of response times in milliseconds",
+	}
+	s["url"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+	}
+	s["method"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  "GET",
+	}
+	s["headers"] = &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Request headers. Set ones matching the listener rule of the canary to force routing probes to it",
+	}
+	s["requests"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Default:      10,
+		ValidateFunc: validation.IntAtLeast(1),
+		Description:  "The number of requests sent on each analysis",
+	}
+	s["timeout"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "5s",
+		ValidateFunc: ValidateDuration,
+	}
+	s["success_status_codes"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "Status codes of successful responses. Defaults to any 2xx and 3xx",
+	}
+
+	return s
+}
+
+var SLOSchema = &schema.Schema{
+	Type:       schema.TypeList,
+	Optional:   true,
+	ConfigMode: schema.SchemaConfigModeBlock,
+	Elem: &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"provider": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"datadog", "cloudwatch", "cloudwatch_logs"}, false),
+				Description:  "The metrics provider to run `good_query` and `total_query` on. Either `datadog`, `cloudwatch`, or `cloudwatch_logs`",
+			},
+			"aws_region": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"aws_profile": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"address": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"log_group_names": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"field": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+			"objective": {
+				Type:         schema.TypeFloat,
+				Required:     true,
+				ValidateFunc: validateObjective,
+				Description:  "The target percentage of good events, like 99.9. Must be greater than 0 and less than 100",
+			},
+			"good_query": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"total_query": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"window": {
+				Type:       schema.TypeList,
+				Required:   true,
+				MinItems:   1,
+				ConfigMode: schema.SchemaConfigModeBlock,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"long": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: ValidateDuration,
+						},
+						"short": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: ValidateDuration,
+						},
+						"max_burn_rate": {
+							Type:     schema.TypeFloat,
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var ModeSchema = &schema.Schema{
+	Type:         schema.TypeString,
+	Optional:     true,
+	Default:      courier.ModeRollout,
+	ValidateFunc: validation.StringInSlice([]string{courier.ModeRollout, courier.ModeExperiment}, false),
+	Description: "`rollout` gradually shifts all the traffic to the destination with the higher weight. " +
+		"`experiment` holds the split declared by the destinations' weights for `duration`, routing the lower share to the variant",
+}
+
+var ExperimentDurationSchema = &schema.Schema{
+	Type:         schema.TypeString,
+	Optional:     true,
+	Default:      "",
+	ValidateFunc: validateOptionalDuration,
+	Description:  "How long the experiment holds the split. Required when `mode` is `experiment`",
+}
+
+var OnCompleteSchema = &schema.Schema{
+	Type:         schema.TypeString,
+	Optional:     true,
+	Default:      courier.OnCompleteLeave,
+	ValidateFunc: validation.StringInSlice([]string{courier.OnCompletePromote, courier.OnCompleteRevert, courier.OnCompleteLeave}, false),
+	Description:  "What to do after the experiment: `promote` shifts all the traffic to the variant, `revert` routes it back to the baseline, and `leave` keeps the split",
+}
+
+var TelemetrySchema = &schema.Schema{
+	Type:        schema.TypeList,
+	Optional:    true,
+	MaxItems:    1,
+	ConfigMode:  schema.SchemaConfigModeBlock,
+	Description: "Pushes gauges and counters of rollouts to either the Prometheus Pushgateway or the OTLP/HTTP endpoint",
+	Elem: &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"pushgateway_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "The URL of the Prometheus Pushgateway, like http://pushgateway:9091",
+			},
+			"otlp_endpoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "The URL of the OTLP/HTTP metrics endpoint, like http://otel-collector:4318/v1/metrics",
+			},
+			"job": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     courier.DefaultTelemetryJob,
+				Description: "The job label of metrics pushed to the Pushgateway",
+			},
+			"headers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "HTTP headers added to push requests, like Authorization",
+			},
+		},
+	},
+}
+
+var PreviewSchema = &schema.Schema{
+	Type:       schema.TypeList,
+	Optional:   true,
+	MaxItems:   1,
+	ConfigMode: schema.SchemaConfigModeBlock,
+	Description: "Routes requests matching the rule conditions and the additional `headers` and `querystrings` to the desired target group, " +
+		"before weighted traffic shifting starts",
+	Elem: &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"priority": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The priority of the preview listener rule. Must be less than `priority` of the main rule",
+			},
+			"headers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"querystrings": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"require_approval": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Wait until the preview rule is tagged with `courier-preview-approved=true` before shifting traffic",
+			},
+			"approval_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1h",
+				ValidateFunc: ValidateDuration,
+			},
+			"duration": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5m",
+				ValidateFunc: ValidateDuration,
+				Description:  "How long the preview analysis runs for before shifting traffic, when `require_approval` is false",
+			},
+			"datadog_metric":         MetricsSchema,
+			"cloudwatch_metric":      MetricsSchema,
+			"cloudwatch_logs_metric": CloudWatchLogsMetricsSchema,
+			"http_probe":             HTTPProbeMetricsSchema,
+			"slo":                    SLOSchema,
+		},
+	},
+}
+
 func albSchema() *courier.ALBSchema {
 	return &courier.ALBSchema{
 		Address:                   "address",
@@ -69,26 +365,60 @@ func albSchema() *courier.ALBSchema {
 		DestinationWeight:         "weight",
 		StepWeight:                "step_weight",
 		StepInterval:              "step_interval",
+		Mode:                      "mode",
+		ExperimentDuration:        "duration",
+		OnComplete:                "on_complete",
+		OnDestroy:                 "on_destroy",
+		Fallback:                  "fallback",
+		Telemetry:                 "telemetry",
 		Hosts:                     "hosts",
 		PathPatterns:              "path_patterns",
 		Methods:                   "methods",
 		SourceIPs:                 "source_ips",
 		Headers:                   "headers",
 		QueryStrings:              "querystrings",
+		Preview:                   "preview",
+		PreviewPriority:           "priority",
+		PreviewHeaders:            "headers",
+		PreviewQueryStrings:       "querystrings",
+		PreviewRequireApproval:    "require_approval",
+		PreviewApprovalTimeout:    "approval_timeout",
+		PreviewDuration:           "duration",
 	}
 }
 
 func metricSchema() *courier.MetricSchema {
 	return &courier.MetricSchema{
-		DatadogMetric:    "datadog_metric",
-		CloudWatchMetric: "cloudwatch_metric",
-		Min:              "min",
-		Max:              "max",
-		Interval:         "interval",
-		Address:          "address",
-		Query:            "query",
-		AWSProfile:       "aws_profile",
-		AWSRegion:        "aws_region",
+		Name:                 "name",
+		DatadogMetric:        "datadog_metric",
+		CloudWatchMetric:     "cloudwatch_metric",
+		CloudWatchLogsMetric: "cloudwatch_logs_metric",
+		Min:                  "min",
+		Max:                  "max",
+		Interval:             "interval",
+		Address:              "address",
+		Query:                "query",
+		AWSProfile:           "aws_profile",
+		AWSRegion:            "aws_region",
+		LogGroupNames:        "log_group_names",
+		Field:                "field",
+		Window:               "window",
+		HTTPProbe:            "http_probe",
+		URL:                  "url",
+		Method:               "method",
+		Headers:              "headers",
+		Requests:             "requests",
+		Timeout:              "timeout",
+		SuccessStatusCodes:   "success_status_codes",
+		SLO:                  "slo",
+		Provider:             "provider",
+		Objective:            "objective",
+		GoodQuery:            "good_query",
+		TotalQuery:           "total_query",
+		BurnRateWindow:       "window",
+		LongWindow:           "long",
+		ShortWindow:          "short",
+		MaxBurnRate:          "max_burn_rate",
 	}
 }
 
@@ -102,13 +432,13 @@ func ResourceALB() *schema.Resource {
 			id := xid.New().String()
 			d.SetId(id)
 
-			if err := courier.CreateOrUpdateCourierALB(&tfsdk.Resource{d}, aSchema, mSchema); err != nil {
+			if err := courier.CreateOrUpdateCourierALB(&tfsdk.Resource{ResourceData: d}, aSchema, mSchema); err != nil {
 				return fmt.Errorf("creating courier_alb: %w", err)
 			}
 			return nil
 		},
 		Update: func(d *schema.ResourceData, meta interface{}) error {
-			if err := courier.CreateOrUpdateCourierALB(&tfsdk.Resource{d}, aSchema, mSchema); err != nil {
+			if err := courier.CreateOrUpdateCourierALB(&tfsdk.Resource{ResourceData: d}, aSchema, mSchema); err != nil {
 				return fmt.Errorf("updating courier_alb: %w", err)
 			}
 			return nil
@@ -117,7 +447,7 @@ func ResourceALB() *schema.Resource {
 			return nil
 		},
 		Delete: func(d *schema.ResourceData, meta interface{}) error {
-			if err := courier.DeleteCourierALB(&tfsdk.Resource{d}, aSchema, mSchema); err != nil {
+			if err := courier.DeleteCourierALB(&tfsdk.Resource{ResourceData: d}, aSchema, mSchema); err != nil {
 				return xerrors.Errorf("deleting courier ALB: %w", err)
 			}
 
@@ -159,6 +489,32 @@ func ResourceALB() *schema.Resource {
 				Required:     true,
 				ValidateFunc: ValidateDuration,
 			},
+			"mode":        ModeSchema,
+			"duration":    ExperimentDurationSchema,
+			"on_complete": OnCompleteSchema,
+			"telemetry":   TelemetrySchema,
+			"on_destroy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      courier.OnDestroyDelete,
+				ValidateFunc: validation.StringInSlice([]string{courier.OnDestroyDelete, courier.OnDestroyKeep, courier.OnDestroyFallback}, false),
+				Description: "What to do with the listener rule on destroy. `delete` deletes it, `keep` leaves it as is, and " +
+					"`fallback` gradually shifts all the traffic to the `fallback` target group before deleting it",
+			},
+			"fallback": {
+				Type:       schema.TypeList,
+				Optional:   true,
+				MaxItems:   1,
+				ConfigMode: schema.SchemaConfigModeBlock,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"target_group_arn": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
 			// Listener rule settings
 			"priority": {
 				Type:     schema.TypeInt,
@@ -290,8 +646,12 @@ produces:
  }
 `,
 			},
-			"datadog_metric":    MetricsSchema,
-			"cloudwatch_metric": MetricsSchema,
+			"datadog_metric":         MetricsSchema,
+			"cloudwatch_metric":      MetricsSchema,
+			"cloudwatch_logs_metric": CloudWatchLogsMetricsSchema,
+			"http_probe":             HTTPProbeMetricsSchema,
+			"slo":                    SLOSchema,
+			"preview":                PreviewSchema,
 			"destination": {
 				Type:       schema.TypeList,
 				Optional:   true,
@@ -313,6 +673,23 @@ produces:
 	}
 }
 
+// validateObjective rejects objectives of 0 and 100 as well as SLO.Validate does,
+// as neither leaves any error budget to compute burn rates against.
+func validateObjective(v interface{}, k string) (ws []string, errors []error) {
+	if o := v.(float64); o <= 0 || o >= 100 {
+		errors = append(errors, fmt.Errorf("%q must be greater than 0 and less than 100: got %v", k, o))
+	}
+	return
+}
+
+func validateOptionalDuration(v interface{}, k string) (ws []string, errors []error) {
+	if v.(string) == "" {
+		return
+	}
+
+	return ValidateDuration(v, k)
+}
+
 func ValidateDuration(v interface{}, k string) (ws []string, errors []error) {
 	if _, err := time.ParseDuration(v.(string)); err != nil {
 		errors = append(errors, fmt.Errorf("%q: invalid duration", k))
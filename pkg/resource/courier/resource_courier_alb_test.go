@@ -0,0 +1,118 @@
+package courier
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/mumoshu/terraform-provider-eksctl/pkg/courier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type constMetricProvider float64
+
+func (p constMetricProvider) Execute(string) (float64, error) {
+	return float64(p), nil
+}
+
+func TestReadMetrics_httpProbeWithoutMax(t *testing.T) {
+	// The `http_probe` example in README, which omits `max`
+	d := schema.TestResourceDataRaw(t, ResourceALB().Schema, map[string]interface{}{
+		"http_probe": []interface{}{
+			map[string]interface{}{
+				"name":     "canary_success_rate",
+				"url":      "https://myapp.example.com/healthz",
+				"requests": 20,
+				"timeout":  "5s",
+				"headers": map[string]interface{}{
+					"X-Canary": "always",
+				},
+				"query": "success_rate",
+				"min":   99,
+			},
+		},
+	})
+
+	metrics, err := courier.ReadMetrics(d, metricSchema())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+
+	require.NotNil(t, m.Min)
+	assert.Equal(t, 99.0, *m.Min)
+	assert.Nil(t, m.Max)
+
+	for v, wantErr := range map[float64]bool{100: false, 99: false, 98: true} {
+		a := &courier.Analyzer{MetricProvider: constMetricProvider(v), Query: m.Query, Min: m.Min, Max: m.Max}
+
+		if wantErr {
+			assert.Error(t, a.Analyze(nil), "success rate %v", v)
+		} else {
+			assert.NoError(t, a.Analyze(nil), "success rate %v", v)
+		}
+	}
+}
+
+func TestReadMetrics_zeroThresholds(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, ResourceALB().Schema, map[string]interface{}{
+		"cloudwatch_metric": []interface{}{
+			map[string]interface{}{
+				"name":  "errors",
+				"query": "errors",
+				"max":   0,
+			},
+		},
+	})
+
+	metrics, err := courier.ReadMetrics(d, metricSchema())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+
+	require.NotNil(t, m.Max)
+	assert.Equal(t, 0.0, *m.Max)
+
+	for v, wantErr := range map[float64]bool{0: false, 1: true} {
+		a := &courier.Analyzer{MetricProvider: constMetricProvider(v), Query: m.Query, Min: m.Min, Max: m.Max}
+
+		if wantErr {
+			assert.Error(t, a.Analyze(nil), "errors %v", v)
+		} else {
+			assert.NoError(t, a.Analyze(nil), "errors %v", v)
+		}
+	}
+}
+
+func TestReadMetrics_negativeMaxWithoutMin(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, ResourceALB().Schema, map[string]interface{}{
+		"cloudwatch_metric": []interface{}{
+			map[string]interface{}{
+				"name":  "latency_change",
+				"query": "latency_change",
+				"max":   -10,
+			},
+		},
+	})
+
+	metrics, err := courier.ReadMetrics(d, metricSchema())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	require.NotNil(t, metrics[0].Max)
+	assert.Equal(t, -10.0, *metrics[0].Max)
+	assert.Nil(t, metrics[0].Min)
+}
+
+func TestValidateObjective(t *testing.T) {
+	for _, v := range []float64{0.1, 50, 99.9} {
+		_, errs := validateObjective(v, "objective")
+		assert.Empty(t, errs, "objective %v", v)
+	}
+
+	for _, v := range []float64{-1, 0, 100, 100.1} {
+		_, errs := validateObjective(v, "objective")
+		assert.Len(t, errs, 1, "objective %v", v)
+	}
+}
@@ -19,13 +19,13 @@ func ResourceRoute53Record() *schema.Resource {
 			id := xid.New().String()
 			d.SetId(id)
 
-			if err := courier.CreateOrUpdateCourierRoute53Record(&tfsdk.Resource{d}, mSchema); err != nil {
+			if err := courier.CreateOrUpdateCourierRoute53Record(&tfsdk.Resource{ResourceData: d}, mSchema); err != nil {
 				return fmt.Errorf("updating courier_route53_record: %w", err)
 			}
 			return nil
 		},
 		Update: func(d *schema.ResourceData, meta interface{}) error {
-			if err := courier.CreateOrUpdateCourierRoute53Record(&tfsdk.Resource{d}, mSchema); err != nil {
+			if err := courier.CreateOrUpdateCourierRoute53Record(&tfsdk.Resource{ResourceData: d}, mSchema); err != nil {
 				return fmt.Errorf("updating courier_route53_record: %w", err)
 			}
 			return nil
@@ -76,8 +76,15 @@ func ResourceRoute53Record() *schema.Resource {
 				Required:     true,
 				ValidateFunc: ValidateDuration,
 			},
-			"datadog_metric":    MetricsSchema,
-			"cloudwatch_metric": MetricsSchema,
+			"mode":                   ModeSchema,
+			"duration":               ExperimentDurationSchema,
+			"on_complete":            OnCompleteSchema,
+			"telemetry":              TelemetrySchema,
+			"datadog_metric":         MetricsSchema,
+			"cloudwatch_metric":      MetricsSchema,
+			"cloudwatch_logs_metric": CloudWatchLogsMetricsSchema,
+			"http_probe":             HTTPProbeMetricsSchema,
+			"slo":                    SLOSchema,
 			"destination": {
 				Type:       schema.TypeList,
 				Optional:   true,
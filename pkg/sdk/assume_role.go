@@ -28,7 +28,7 @@ func AssumeRole(sess *session.Session, config AssumeRoleConfig) (*session.Sessio
 		awsDurationSeconds = &config.DurationSeconds
 	}
 
-	stsSvc := sts.New(sess)
+	stsSvc := NewClients(sess).STS()
 
 	sessionName := fmt.Sprintf("tf-eksctl-session-%d", rand.Int())
 	if config.SessionName != "" {
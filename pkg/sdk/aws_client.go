@@ -0,0 +1,356 @@
+package sdk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	DefaultMaxRetries = 8
+
+	// DefaultRateLimit is the number of requests per second allowed for services missing in RateLimits
+	DefaultRateLimit = 10
+)
+
+// RateLimits is the client-side limit of requests per second per service, shared by all the clients in the process.
+// The limits are set slightly below the documented API rate limits, so that concurrent rollouts are
+// rarely throttled by AWS in the first place. Use SetRateLimits to change them.
+//
+// Keys are service names of the SDK. Classic ELB and ELBV2 share the elbv2 limit, as both are `elasticloadbalancing`.
+var RateLimits = map[string]float64{
+	elbv2.ServiceName:                    10,
+	route53.ServiceName:                  4,
+	cloudwatch.ServiceName:               20,
+	cloudwatchlogs.ServiceName:           5,
+	cloudformation.ServiceName:           5,
+	autoscaling.ServiceName:              10,
+	ec2.ServiceName:                      20,
+	eks.ServiceName:                      10,
+	resourcegroupstaggingapi.ServiceName: 5,
+	sts.ServiceName:                      10,
+}
+
+// Clients creates AWS API clients that are rate-limited on the client side and retry on throttling and transient errors
+// with jittered exponential backoff.
+//
+// Use this instead of calling constructors like elbv2.New directly, so that many rollouts running in parallel
+// don't fail due to throttled API calls.
+type Clients struct {
+	Session *session.Session
+}
+
+func NewClients(sess *session.Session) *Clients {
+	return &Clients{Session: sess}
+}
+
+// ELB creates the client for Classic Load Balancers, which shares the rate limit with ELBV2 as they have the same service name.
+func (c *Clients) ELB() *elb.ELB {
+	svc := elb.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) ELBV2() *elbv2.ELBV2 {
+	svc := elbv2.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) Route53() *route53.Route53 {
+	svc := route53.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) CloudWatch() *cloudwatch.CloudWatch {
+	svc := cloudwatch.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) CloudWatchLogs() *cloudwatchlogs.CloudWatchLogs {
+	svc := cloudwatchlogs.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) CloudFormation() *cloudformation.CloudFormation {
+	svc := cloudformation.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) AutoScaling() *autoscaling.AutoScaling {
+	svc := autoscaling.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) EC2() *ec2.EC2 {
+	svc := ec2.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) EKS() *eks.EKS {
+	svc := eks.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) ResourceGroupsTaggingAPI() *resourcegroupstaggingapi.ResourceGroupsTaggingAPI {
+	svc := resourcegroupstaggingapi.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) STS() *sts.STS {
+	svc := sts.New(c.Session, c.config())
+	c.configure(svc.Client)
+
+	return svc
+}
+
+func (c *Clients) config() *aws.Config {
+	return request.WithRetryer(aws.NewConfig(), NewRetryer(DefaultMaxRetries))
+}
+
+// configure makes the client wait for the rate limiter of the service before sending every request, including retries.
+func (c *Clients) configure(cl *client.Client) {
+	limiter := rateLimiterFor(cl.ServiceName, aws.StringValue(cl.Config.Region))
+
+	cl.Handlers.Send.PushFrontNamed(request.NamedHandler{
+		Name: "sdk.RateLimitHandler",
+		Fn: func(r *request.Request) {
+			if err := limiter.Wait(r.Context()); err != nil {
+				r.Error = err
+			}
+		},
+	})
+}
+
+// Retryer retries throttled requests and transient errors with the "full jitter" exponential backoff.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type Retryer struct {
+	client.DefaultRetryer
+
+	// MinDelay and MaxDelay are the base and the cap of the backoff for transient errors
+	MinDelay time.Duration
+	MaxDelay time.Duration
+
+	// MinThrottleDelay and MaxThrottleDelay are the base and the cap of the backoff for throttled requests
+	MinThrottleDelay time.Duration
+	MaxThrottleDelay time.Duration
+}
+
+func NewRetryer(maxRetries int) *Retryer {
+	return &Retryer{
+		DefaultRetryer:   client.DefaultRetryer{NumMaxRetries: maxRetries},
+		MinDelay:         100 * time.Millisecond,
+		MaxDelay:         5 * time.Second,
+		MinThrottleDelay: 500 * time.Millisecond,
+		MaxThrottleDelay: 30 * time.Second,
+	}
+}
+
+func (r *Retryer) ShouldRetry(req *request.Request) bool {
+	if req.IsErrorThrottle() {
+		return true
+	}
+
+	return r.DefaultRetryer.ShouldRetry(req)
+}
+
+func (r *Retryer) RetryRules(req *request.Request) time.Duration {
+	min, max := r.MinDelay, r.MaxDelay
+	if req.IsErrorThrottle() {
+		min, max = r.MinThrottleDelay, r.MaxThrottleDelay
+	}
+
+	return backoff(req.RetryCount, min, max)
+}
+
+// backoff returns a random duration between 0 and min(max, min * 2^retryCount).
+func backoff(retryCount int, min, max time.Duration) time.Duration {
+	ceil := float64(max)
+
+	if retryCount < 62 {
+		ceil = math.Min(ceil, float64(min)*math.Pow(2, float64(retryCount)))
+	}
+
+	return time.Duration(rand.Int63n(int64(ceil) + 1))
+}
+
+// rateLimiter is the token bucket that allows bursts of up to one second worth of requests.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	rate   float64
+	tokens float64
+	last   time.Time
+
+	now func() time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		tokens: rate,
+		now:    time.Now,
+	}
+}
+
+func (l *rateLimiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = rate
+	l.tokens = math.Min(l.tokens, rate)
+}
+
+// reserve takes a token and returns how long the caller needs to wait before sending the request.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+
+	l.last = now
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *rateLimiter) Wait(ctx aws.Context) error {
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[string]*rateLimiter{}
+)
+
+func rateLimiterFor(service, region string) *rateLimiter {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	key := service + "/" + region
+
+	if l, ok := rateLimiters[key]; ok {
+		return l
+	}
+
+	rate, ok := RateLimits[service]
+	if !ok {
+		rate = DefaultRateLimit
+	}
+
+	l := newRateLimiter(rate)
+
+	rateLimiters[key] = l
+
+	return l
+}
+
+// SetRateLimits overrides RateLimits for the services, like `elasticloadbalancing` and `route53`.
+// Clients already created are limited by the new rates as well.
+func SetRateLimits(limits map[string]float64) error {
+	for service, rate := range limits {
+		if rate <= 0 {
+			return fmt.Errorf("rate limit of %s must be positive: got %v", service, rate)
+		}
+	}
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	for service, rate := range limits {
+		RateLimits[service] = rate
+
+		for key, l := range rateLimiters {
+			if strings.HasPrefix(key, service+"/") {
+				l.setRate(rate)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseRateLimits parses comma-separated pairs of a service name and its rate limit, like `elasticloadbalancing=5,route53=2`.
+func ParseRateLimits(s string) (map[string]float64, error) {
+	limits := map[string]float64{}
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("parsing rate limit %q: expected SERVICE=RATE", pair)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing rate limit %q: %w", pair, err)
+		}
+
+		limits[strings.TrimSpace(kv[0])] = rate
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,106 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClients_RetryOnThrottling(t *testing.T) {
+	var calls int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+
+			return
+		}
+
+		w.Write([]byte(`<DescribeRulesResponse><DescribeRulesResult><Rules><member><RuleArn>rule</RuleArn></member></Rules></DescribeRulesResult></DescribeRulesResponse>`))
+	}))
+	defer s.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(s.URL),
+		Credentials: credentials.NewStaticCredentials("x", "y", ""),
+	}))
+
+	o, err := NewClients(sess).ELBV2().DescribeRules(&elbv2.DescribeRulesInput{ListenerArn: aws.String("listener")})
+	require.NoError(t, err)
+
+	assert.Equal(t, "rule", *o.Rules[0].RuleArn)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestBackoff(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, int64(backoff(0, time.Second, time.Minute)), int64(time.Second))
+		assert.LessOrEqual(t, int64(backoff(3, time.Second, time.Minute)), int64(8*time.Second))
+		assert.LessOrEqual(t, int64(backoff(100, time.Second, time.Minute)), int64(time.Minute))
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := newRateLimiter(2)
+	l.now = func() time.Time { return now }
+
+	// Bursts of up to the rate are allowed
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+
+	assert.Equal(t, 500*time.Millisecond, l.reserve())
+	assert.Equal(t, time.Second, l.reserve())
+
+	now = now.Add(2 * time.Second)
+
+	assert.Equal(t, time.Duration(0), l.reserve())
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("elasticloadbalancing=5, route53=0.5,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"elasticloadbalancing": 5, "route53": 0.5}, limits)
+
+	_, err = ParseRateLimits("route53")
+	assert.Error(t, err)
+
+	_, err = ParseRateLimits("route53=fast")
+	assert.Error(t, err)
+}
+
+func TestSetRateLimits(t *testing.T) {
+	const service = "test-set-rate-limits"
+
+	defer func() {
+		rateLimitersMu.Lock()
+		delete(RateLimits, service)
+		delete(rateLimiters, service+"/us-east-1")
+		delete(rateLimiters, service+"/us-west-2")
+		rateLimitersMu.Unlock()
+	}()
+
+	l := rateLimiterFor(service, "us-east-1")
+	assert.Equal(t, float64(DefaultRateLimit), l.rate)
+
+	require.NoError(t, SetRateLimits(map[string]float64{service: 2}))
+
+	// The existing limiter is updated as well
+	assert.Equal(t, 2.0, l.rate)
+	assert.Equal(t, 2.0, l.tokens)
+	assert.Equal(t, 2.0, rateLimiterFor(service, "us-west-2").rate)
+
+	assert.Error(t, SetRateLimits(map[string]float64{service: 0}))
+}
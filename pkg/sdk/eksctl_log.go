@@ -0,0 +1,107 @@
+package sdk
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelSuccess = "success"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// eksctlLevels maps the level icons in eksctl's log lines to levels
+var eksctlLevels = map[string]string{
+	"▶": LevelDebug,
+	"ℹ": LevelInfo,
+	"✔": LevelSuccess,
+	"!": LevelWarning,
+	"✖": LevelError,
+}
+
+var (
+	// e.g. `2021-05-01 12:00:00 [ℹ]  waiting for CloudFormation stack "eksctl-foo-cluster"`
+	eksctlLogLineRegexp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) \[(.+?)\]\s+(.*)$`)
+
+	// e.g. `AWS::EKS::Cluster/ControlPlane: CREATE_FAILED – "Cannot create cluster"`
+	resourceStatusRegexp = regexp.MustCompile(`^(AWS::[A-Za-z0-9:]+)/(\S+): ([A-Z_]+)(?:\s+[–-]\s+(.*))?$`)
+
+	stackNameRegexp = regexp.MustCompile(`stack "([^"]+)"`)
+)
+
+// Event is a log line of eksctl parsed into a structured form.
+type Event struct {
+	Time    string
+	Level   string
+	Message string
+
+	// StackName is the name of the CloudFormation stack the event is about, if any
+	StackName string
+
+	// ResourceType, LogicalResourceID, ResourceStatus, and Reason are set for CloudFormation resource events
+	// like `AWS::EKS::Cluster/ControlPlane: CREATE_FAILED – "reason"`
+	ResourceType      string
+	LogicalResourceID string
+	ResourceStatus    string
+	Reason            string
+}
+
+// ParseEvent parses a log line of eksctl. It returns false for lines that don't look like eksctl's log,
+// like the JSON output of `eksctl get`.
+func ParseEvent(line string) (Event, bool) {
+	m := eksctlLogLineRegexp.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return Event{}, false
+	}
+
+	level, ok := eksctlLevels[m[2]]
+	if !ok {
+		level = LevelInfo
+	}
+
+	e := Event{
+		Time:    m[1],
+		Level:   level,
+		Message: strings.TrimSpace(m[3]),
+	}
+
+	if rm := resourceStatusRegexp.FindStringSubmatch(e.Message); rm != nil {
+		e.ResourceType = rm[1]
+		e.LogicalResourceID = rm[2]
+		e.ResourceStatus = rm[3]
+		e.Reason = strings.Trim(strings.TrimSpace(rm[4]), `"`)
+
+		if e.ResourceType == "AWS::CloudFormation::Stack" {
+			e.StackName = e.LogicalResourceID
+		}
+	}
+
+	if sm := stackNameRegexp.FindStringSubmatch(e.Message); sm != nil {
+		e.StackName = sm[1]
+	}
+
+	return e, true
+}
+
+// IsFailure returns true when the event tells the root cause of a CloudFormation failure.
+func (e Event) IsFailure() bool {
+	if !strings.HasSuffix(e.ResourceStatus, "_FAILED") || e.Reason == "" {
+		return false
+	}
+
+	// Consequences of another resource's failure
+	switch e.Reason {
+	case "Resource creation cancelled", "Resource update cancelled":
+		return false
+	}
+
+	return true
+}
+
+// FailureReason formats the failure like `AWS::EKS::Cluster/ControlPlane: CREATE_FAILED: reason`.
+func (e Event) FailureReason() string {
+	return e.ResourceType + "/" + e.LogicalResourceID + ": " + e.ResourceStatus + ": " + e.Reason
+}
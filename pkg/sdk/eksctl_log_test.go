@@ -0,0 +1,71 @@
+package sdk
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvent(t *testing.T) {
+	e, ok := ParseEvent(`2021-05-01 12:00:00 [ℹ]  waiting for CloudFormation stack "eksctl-foo-cluster"`)
+	require.True(t, ok)
+	assert.Equal(t, Event{
+		Time:      "2021-05-01 12:00:00",
+		Level:     LevelInfo,
+		Message:   `waiting for CloudFormation stack "eksctl-foo-cluster"`,
+		StackName: "eksctl-foo-cluster",
+	}, e)
+	assert.False(t, e.IsFailure())
+
+	e, ok = ParseEvent(`2021-05-01 12:00:01 [✖]  AWS::EKS::Cluster/ControlPlane: CREATE_FAILED – "Cannot create cluster 'foo' because us-east-1e does not have sufficient capacity"`)
+	require.True(t, ok)
+	assert.Equal(t, LevelError, e.Level)
+	assert.Equal(t, "AWS::EKS::Cluster", e.ResourceType)
+	assert.Equal(t, "ControlPlane", e.LogicalResourceID)
+	assert.Equal(t, "CREATE_FAILED", e.ResourceStatus)
+	assert.True(t, e.IsFailure())
+	assert.Equal(t, "AWS::EKS::Cluster/ControlPlane: CREATE_FAILED: Cannot create cluster 'foo' because us-east-1e does not have sufficient capacity", e.FailureReason())
+
+	e, ok = ParseEvent(`2021-05-01 12:00:02 [✖]  AWS::EC2::SecurityGroup/ClusterSharedNodeSecurityGroup: CREATE_FAILED – "Resource creation cancelled"`)
+	require.True(t, ok)
+	assert.False(t, e.IsFailure())
+
+	_, ok = ParseEvent(`[{"name": "ng1"}]`)
+	assert.False(t, ok)
+}
+
+func TestRun_Failure(t *testing.T) {
+	SetLogDir(t.TempDir())
+	defer SetLogDir("")
+
+	script := `echo '2021-05-01 12:00:00 [ℹ]  building cluster stack "eksctl-foo-cluster"'
+echo '2021-05-01 12:00:01 [✖]  AWS::EKS::Cluster/ControlPlane: CREATE_FAILED – "Cannot create cluster"' >&2
+echo 'Error: failed to create cluster "foo"' >&2
+exit 1`
+
+	_, err := Run(exec.Command("bash", "-c", script))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "CloudFormation failures:\n  - AWS::EKS::Cluster/ControlPlane: CREATE_FAILED: Cannot create cluster\n")
+
+	lines := strings.Split(msg, "\n")
+	assert.Equal(t, `Error: failed to create cluster "foo"`, lines[len(lines)-1])
+
+	var logFile string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "Complete output: ") {
+			logFile = strings.TrimPrefix(l, "Complete output: ")
+		}
+	}
+	require.NotEmpty(t, logFile)
+
+	bs, err := ioutil.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), `building cluster stack "eksctl-foo-cluster"`)
+	assert.Contains(t, string(bs), `Error: failed to create cluster "foo"`)
+}
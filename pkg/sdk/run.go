@@ -5,62 +5,104 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"github.com/armon/circbuf"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
-	"github.com/mitchellh/go-linereader"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 )
 
-func Run(cmd *exec.Cmd) (*CommandResult, error) {
-	const maxBufSize = 8 * 1024
+const (
+	// maxErrorOutputLines is the number of the last lines of the command output included in errors.
+	// The complete output is available in the log file.
+	maxErrorOutputLines = 100
 
-	// Setup the command
-	pr, pw, err := os.Pipe()
-	if err != nil {
-		return nil, err
-	}
-	cmd.Stdout = pw
+	// DefaultLogMaxAge and DefaultLogMaxFiles are the defaults of the retention of log files under LogDir.
+	DefaultLogMaxAge   = 7 * 24 * time.Hour
+	DefaultLogMaxFiles = 100
+)
 
-	output, _ := circbuf.NewBuffer(maxBufSize)
+var (
+	logDirMu sync.Mutex
+	logDir   string
 
-	// Write everything we read from the pipe to the output buffer too
-	tee := io.TeeReader(pr, output)
+	logMaxAge   = DefaultLogMaxAge
+	logMaxFiles = DefaultLogMaxFiles
 
-	// copy the teed output to the UI output
-	copyDoneCh := make(chan struct{})
-	//o := ctx.Value(schema.ProvOutputKey).(terraform.UIOutput)
-	go copyOutput(debugOut{}, tee, copyDoneCh)
+	// prunedLogDirs is the set of log directories already pruned by this process
+	prunedLogDirs = map[string]bool{}
+)
 
-	logDebug("starting to run eksctl", strings.Join(cmd.Args, " "))
+// SetLogRetention sets how long and how many log files are kept under LogDir. Zero disables the limit.
+// Older log files are deleted when the first command in the process writes its log file to the directory.
+func SetLogRetention(maxAge time.Duration, maxFiles int) {
+	logDirMu.Lock()
+	defer logDirMu.Unlock()
 
-	cmdToLog := fmt.Sprintf("%s %s", cmd.Path, strings.Join(cmd.Args, " "))
+	logMaxAge = maxAge
+	logMaxFiles = maxFiles
+}
 
-	log.Printf("[DEBUG] starting command %q", cmdToLog)
+// SetLogDir sets the directory to write the complete output of every command run by Run.
+func SetLogDir(dir string) {
+	logDirMu.Lock()
+	defer logDirMu.Unlock()
 
-	// Execute the command to completion
-	runErr := cmd.Run()
+	logDir = dir
+}
 
-	logDebug("closing pipe writer", strings.Join(cmd.Args, " "))
+// LogDir returns the directory to write command logs, which defaults to `terraform-provider-eksctl/logs` under the temp dir.
+func LogDir() string {
+	logDirMu.Lock()
+	defer logDirMu.Unlock()
 
-	if err := pw.Close(); err != nil {
-		return nil, err
+	if logDir != "" {
+		return logDir
 	}
 
-	logDebug("waiting for copying output", strings.Join(cmd.Args, " "))
+	return filepath.Join(os.TempDir(), "terraform-provider-eksctl", "logs")
+}
+
+// Run runs the command to completion, returning the stdout.
+//
+// Both stdout and stderr are parsed into eksctl's log events line by line as they are written. Events are logged at
+// INFO level and above so that the progress is visible while the command runs, and the complete output is
+// written to a log file under LogDir. On failure, the returned error contains CloudFormation failure reasons
+// found in the output, the path to the log file, and the last lines of the output.
+func Run(cmd *exec.Cmd) (*CommandResult, error) {
+	cmdToLog := fmt.Sprintf("%s %s", cmd.Path, strings.Join(cmd.Args, " "))
 
-	select {
-	case <-copyDoneCh:
-		//case <-ctx.Done():
-	}
+	rl := newRunLog(cmdToLog)
+	defer rl.Close()
+
+	var stdout bytes.Buffer
+
+	stdoutLines := &lineWriter{fn: func(l string) { rl.Line(l, &rl.stdoutTail) }}
+	stderrLines := &lineWriter{fn: func(l string) { rl.Line(l, &rl.stderrTail) }}
+
+	cmd.Stdout = io.MultiWriter(&stdout, stdoutLines)
+	cmd.Stderr = stderrLines
+
+	log.Printf("[DEBUG] starting command %q", cmdToLog)
+
+	// Execute the command to completion
+	runErr := cmd.Run()
+
+	stdoutLines.Flush()
+	stderrLines.Flush()
 
 	res := NewCommandResult()
 
-	out := output.String()
+	res.LogFile = rl.path
+
+	out := stdout.String()
 	log.Printf("[DEBUG] command %q finished with output: \"%s\"", cmdToLog, out)
 	var exitStatus int
 	if runErr != nil {
@@ -71,11 +113,11 @@ func Run(cmd *exec.Cmd) (*CommandResult, error) {
 			waitStatus := ee.Sys().(syscall.WaitStatus)
 			exitStatus = waitStatus.ExitStatus()
 			if exitStatus != 2 {
-				return nil, fmt.Errorf("%s: %v\n%s", cmd.Path, runErr, out)
+				return nil, fmt.Errorf("%s: %v\n%s", cmd.Path, runErr, rl.Summary())
 			}
 			res.ExitStatus = exitStatus
 		default:
-			return nil, fmt.Errorf("running %q: %v\n%s", cmdToLog, runErr, out)
+			return nil, fmt.Errorf("running %q: %v\n%s", cmdToLog, runErr, rl.Summary())
 		}
 	}
 
@@ -86,57 +128,245 @@ func Run(cmd *exec.Cmd) (*CommandResult, error) {
 	return res, nil
 }
 
-func Hash(data interface{}) string {
-	bs, err := json.Marshal(data)
+// runLog processes lines written by a command.
+type runLog struct {
+	mu sync.Mutex
+
+	path string
+	file *os.File
+
+	// stdoutTail and stderrTail are kept separately so that the error ends with the stderr of eksctl,
+	// which usually contains the `Error: ...` line, regardless of how the two streams are interleaved.
+	stdoutTail []string
+	stderrTail []string
+
+	failures []string
+	seen     map[string]bool
+}
+
+func newRunLog(cmdToLog string) *runLog {
+	rl := &runLog{seen: map[string]bool{}}
+
+	dir := LogDir()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("[WARN] creating log directory %s: %v", dir, err)
+
+		return rl
+	}
+
+	pruneLogsOnce(dir)
+
+	f, err := ioutil.TempFile(dir, time.Now().Format("20060102-150405")+"-*.log")
 	if err != nil {
-		panic(err)
+		log.Printf("[WARN] creating log file in %s: %v", dir, err)
+
+		return rl
 	}
-	first := sha256.New()
-	first.Write(bs)
-	return fmt.Sprintf("%x", first.Sum(nil))
+
+	fmt.Fprintf(f, "# %s\n", cmdToLog)
+
+	rl.path = f.Name()
+	rl.file = f
+
+	log.Printf("[INFO] writing the output of %q to %s", cmdToLog, rl.path)
+
+	return rl
 }
 
-func copyOutput(o Outputter, r io.Reader, doneCh chan<- struct{}) {
-	defer close(doneCh)
-	lr := linereader.New(r)
-	for line := range lr.Ch {
-		o.Output(line)
+// pruneLogsOnce deletes log files in dir beyond the retention, once per directory in the process,
+// so that log files don't pile up over plans and applies.
+func pruneLogsOnce(dir string) {
+	logDirMu.Lock()
+	defer logDirMu.Unlock()
+
+	if prunedLogDirs[dir] {
+		return
+	}
+
+	prunedLogDirs[dir] = true
+
+	if err := pruneLogs(dir, time.Now(), logMaxAge, logMaxFiles); err != nil {
+		log.Printf("[WARN] pruning log files in %s: %v", dir, err)
 	}
 }
 
-func SetOutput(d *schema.ResourceData, v string) {
-	d.Set(KeyOutput, v)
+// pruneLogs deletes log files in dir modified before maxAge ago, and the oldest ones beyond maxFiles-1
+// so that there's room for the next log file.
+func pruneLogs(dir string, now time.Time, maxAge time.Duration, maxFiles int) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var logs []os.FileInfo
+
+	for _, info := range infos {
+		if info.Mode().IsRegular() && filepath.Ext(info.Name()) == ".log" {
+			logs = append(logs, info)
+		}
+	}
+
+	// Newest first
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].ModTime().After(logs[j].ModTime())
+	})
+
+	for i, info := range logs {
+		expired := maxAge > 0 && now.Sub(info.ModTime()) > maxAge
+		exceeded := maxFiles > 0 && i >= maxFiles-1
+
+		if !expired && !exceeded {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Line writes the line to the log file and tail, and logs it as an eksctl event.
+func (rl *runLog) Line(line string, tail *[]string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.file != nil {
+		fmt.Fprintln(rl.file, line)
+	}
+
+	*tail = append(*tail, line)
+	if len(*tail) > maxErrorOutputLines {
+		*tail = (*tail)[len(*tail)-maxErrorOutputLines:]
+	}
+
+	e, ok := ParseEvent(line)
+	if !ok {
+		log.Printf("[DEBUG] eksctl: %s", line)
+
+		return
+	}
+
+	switch e.Level {
+	case LevelDebug:
+		log.Printf("[DEBUG] eksctl: %s", e.Message)
+	case LevelWarning:
+		log.Printf("[WARN] eksctl: %s", e.Message)
+	case LevelError:
+		log.Printf("[ERROR] eksctl: %s", e.Message)
+	default:
+		log.Printf("[INFO] eksctl: %s", e.Message)
+	}
+
+	if e.IsFailure() {
+		r := e.FailureReason()
+
+		if e.StackName != "" && e.StackName != e.LogicalResourceID {
+			r = fmt.Sprintf("stack %q: %s", e.StackName, r)
+		}
+
+		if !rl.seen[r] {
+			rl.seen[r] = true
+			rl.failures = append(rl.failures, r)
+		}
+	}
+}
+
+// Summary returns the CloudFormation failure reasons, the path to the log file, and the last lines of stdout and stderr,
+// in this order so that the last line of the summary is the last line of the output.
+func (rl *runLog) Summary() string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var buf strings.Builder
+
+	if len(rl.failures) > 0 {
+		buf.WriteString("CloudFormation failures:\n")
+
+		for _, f := range rl.failures {
+			buf.WriteString("  - " + f + "\n")
+		}
+	}
+
+	if rl.path != "" {
+		buf.WriteString("Complete output: " + rl.path + "\n")
+	}
+
+	buf.WriteString(strings.Join(append(append([]string{}, rl.stdoutTail...), rl.stderrTail...), "\n"))
+
+	return buf.String()
 }
 
-type debugOut struct{}
+func (rl *runLog) Close() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.file != nil {
+		if err := rl.file.Close(); err != nil {
+			log.Printf("[WARN] closing log file %s: %v", rl.path, err)
+		}
 
-func (o debugOut) Output(line string) {
-	logDebug("eksctl", line)
+		rl.file = nil
+	}
 }
 
-type Outputter interface {
-	Output(string)
+// lineWriter calls fn for each line written to it.
+type lineWriter struct {
+	buf []byte
+	fn  func(string)
 }
 
-const KeyOutput = "output"
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.fn(string(w.buf[:i]))
+
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
 
-func logDebug(title string, data interface{}) {
-	var buf bytes.Buffer
+// Flush calls fn for the last line not terminated by a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
 
-	d := json.NewEncoder(&buf)
-	d.SetIndent("", "  ")
+		w.buf = nil
+	}
+}
 
-	if err := d.Encode(data); err != nil {
+func Hash(data interface{}) string {
+	bs, err := json.Marshal(data)
+	if err != nil {
 		panic(err)
 	}
+	first := sha256.New()
+	first.Write(bs)
+	return fmt.Sprintf("%x", first.Sum(nil))
+}
 
-	log.Printf("[DEBUG] %s: %s", title, buf.String())
+func SetOutput(d *schema.ResourceData, v string) {
+	d.Set(KeyOutput, v)
 }
 
+const KeyOutput = "output"
+
 // CommandResult is a wrapper around both the input and output attributes that are relavent for updates
 type CommandResult struct {
 	Output     string
 	ExitStatus int
+
+	// LogFile is the path to the file containing the complete output of the command, or empty when it couldn't be written
+	LogFile string
 }
 
 // NewCommandResult is the constructor for CommandResult
@@ -2,8 +2,13 @@ package sdk
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -34,3 +39,64 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestPruneLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prune-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	// 1.log is the newest, and 5.log is the oldest
+	for i := 1; i <= 5; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("%d.log", i))
+
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := now.Add(-time.Duration(i) * 24 * time.Hour)
+
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := func() []string {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+
+		sort.Strings(names)
+
+		return names
+	}
+
+	if err := pruneLogs(dir, now, 4*24*time.Hour+time.Minute, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := cmp.Diff([]string{"1.log", "2.log", "3.log", "4.log", "other.txt"}, remaining()); d != "" {
+		t.Fatalf("unexpected files after pruning by age:\n%s", d)
+	}
+
+	if err := pruneLogs(dir, now, 0, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := cmp.Diff([]string{"1.log", "2.log", "other.txt"}, remaining()); d != "" {
+		t.Fatalf("unexpected files after pruning by count:\n%s", d)
+	}
+}
@@ -0,0 +1,43 @@
+package tfsdk
+
+import (
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// SyncReadWrite serializes reads and writes to the resource data, which isn't safe for concurrent use,
+// so that it can be shared by goroutines.
+type SyncReadWrite struct {
+	mu sync.Mutex
+
+	D *schema.ResourceData
+}
+
+func (d *SyncReadWrite) Get(k string) interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.D.Get(k)
+}
+
+func (d *SyncReadWrite) GetChange(k string) (interface{}, interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.D.GetChange(k)
+}
+
+func (d *SyncReadWrite) Set(k string, v interface{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.D.Set(k, v)
+}
+
+func (d *SyncReadWrite) Id() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.D.Id()
+}